@@ -0,0 +1,630 @@
+/*
+Copyright 2026 The kcp Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"fmt"
+	"maps"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/util/sets"
+	clientgofeaturegate "k8s.io/client-go/features"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/kcp-dev/logicalcluster/v3"
+)
+
+// ClusterIndexer is a cache.Indexer that is partitioned by logical cluster.
+type ClusterIndexer interface {
+	cache.Indexer
+
+	// DropCluster removes all objects of clusterName at once and returns
+	// them.
+	DropCluster(clusterName logicalcluster.Name) []interface{}
+}
+
+// NewClusterIndexer returns a ClusterIndexer for objects keyed by keyFunc,
+// which must be MetaClusterNamespaceKeyFunc or
+// MetaShardClusterNamespaceKeyFunc, optionally wrapped for tombstones.
+//
+// Objects are kept in one partition per logical cluster, and within it per
+// namespace. Every partition has its own lock, so that writes to one cluster
+// do not block reads of another. The ClusterIndexName and
+// ClusterAndNamespaceIndexName indexes are answered from the partitions and
+// need not be registered; they are always reported by GetIndexers. Other
+// indexes are kept per partition, so a lookup across all clusters visits
+// every partition.
+func NewClusterIndexer(keyFunc cache.KeyFunc, indexers cache.Indexers) ClusterIndexer {
+	i := &clusterIndexer{
+		keyFunc:    keyFunc,
+		indexers:   cache.Indexers{},
+		partitions: map[logicalcluster.Name]*clusterPartition{},
+	}
+	for name, indexFunc := range indexers {
+		if isPartitionIndex(name) {
+			continue
+		}
+		i.indexers[name] = indexFunc
+	}
+	return i
+}
+
+type clusterIndexer struct {
+	keyFunc cache.KeyFunc
+
+	// lock guards the partitions map and the indexers. Partitions are only
+	// created, dropped or replaced with it held for writing. Lock order is
+	// lock, then the partition's lock.
+	lock       sync.RWMutex
+	partitions map[logicalcluster.Name]*clusterPartition
+	indexers   cache.Indexers
+	// rv is the last observed resource version. It is written on every
+	// write, so it is not guarded by lock, which would serialize writes to
+	// different clusters.
+	rv atomic.Pointer[string]
+}
+
+// clusterPartition holds the objects of one logical cluster.
+type clusterPartition struct {
+	lock sync.RWMutex
+	// dropped is set once the partition was removed from the indexer.
+	// Writers that find it set retry with the current partition.
+	dropped bool
+	// namespaces maps namespaces to keys to objects.
+	namespaces map[string]map[string]interface{}
+	count      int
+	// indexers are the indexes kept in indices. AddIndexers replaces them
+	// with the partition locked, so that writers holding the lock update
+	// every index the partition has.
+	indexers cache.Indexers
+	// indices holds the indexes other than the partition indexes, keyed by
+	// index name, indexed value and key.
+	indices map[string]map[string]sets.Set[string]
+}
+
+func newClusterPartition(indexers cache.Indexers) *clusterPartition {
+	return &clusterPartition{
+		namespaces: map[string]map[string]interface{}{},
+		indexers:   indexers,
+		indices:    map[string]map[string]sets.Set[string]{},
+	}
+}
+
+func isPartitionIndex(name string) bool {
+	return name == ClusterIndexName || name == ClusterAndNamespaceIndexName
+}
+
+// splitKey returns the partition and namespace of key. Keys that cannot be
+// split are kept in the partition of the empty cluster name.
+func splitKey(key string) (logicalcluster.Name, string) {
+	_, clusterName, namespace, _, err := SplitMetaShardClusterNamespaceKey(key)
+	if err != nil {
+		return "", ""
+	}
+	return clusterName, namespace
+}
+
+// lockPartition returns the partition of clusterName locked for writing,
+// creating it if needed.
+func (i *clusterIndexer) lockPartition(clusterName logicalcluster.Name) *clusterPartition {
+	for {
+		i.lock.RLock()
+		p, ok := i.partitions[clusterName]
+		i.lock.RUnlock()
+
+		if !ok {
+			i.lock.Lock()
+			p, ok = i.partitions[clusterName]
+			if !ok {
+				p = newClusterPartition(i.indexers)
+				i.partitions[clusterName] = p
+			}
+			i.lock.Unlock()
+		}
+
+		p.lock.Lock()
+		if !p.dropped {
+			return p
+		}
+		p.lock.Unlock()
+	}
+}
+
+// rLockPartition returns the partition of clusterName locked for reading,
+// or nil if there is none.
+func (i *clusterIndexer) rLockPartition(clusterName logicalcluster.Name) *clusterPartition {
+	i.lock.RLock()
+	defer i.lock.RUnlock()
+	p, ok := i.partitions[clusterName]
+	if !ok {
+		return nil
+	}
+	p.lock.RLock()
+	return p
+}
+
+// snapshot returns the current partitions.
+func (i *clusterIndexer) snapshot() []*clusterPartition {
+	i.lock.RLock()
+	defer i.lock.RUnlock()
+	partitions := make([]*clusterPartition, 0, len(i.partitions))
+	for _, p := range i.partitions {
+		partitions = append(partitions, p)
+	}
+	return partitions
+}
+
+func (i *clusterIndexer) Add(obj interface{}) error {
+	return i.Update(obj)
+}
+
+func (i *clusterIndexer) Update(obj interface{}) error {
+	key, err := i.keyFunc(obj)
+	if err != nil {
+		return cache.KeyError{Obj: obj, Err: err}
+	}
+	clusterName, namespace := splitKey(key)
+
+	p := i.lockPartition(clusterName)
+	old := p.set(namespace, key, obj)
+	p.updateIndices(p.indexers, old, obj, key)
+	p.lock.Unlock()
+
+	i.observeResourceVersion(obj)
+	return nil
+}
+
+func (i *clusterIndexer) Delete(obj interface{}) error {
+	key, err := i.keyFunc(obj)
+	if err != nil {
+		return cache.KeyError{Obj: obj, Err: err}
+	}
+	clusterName, namespace := splitKey(key)
+
+	i.lock.RLock()
+	p, ok := i.partitions[clusterName]
+	i.lock.RUnlock()
+	if ok {
+		p.lock.Lock()
+		if old, exists := p.remove(namespace, key); exists {
+			p.updateIndices(p.indexers, old, nil, key)
+		}
+		empty := p.count == 0
+		p.lock.Unlock()
+		if empty {
+			i.dropIfEmpty(clusterName, p)
+		}
+	}
+
+	i.observeResourceVersion(obj)
+	return nil
+}
+
+// dropIfEmpty removes p if it is still the partition of clusterName and
+// holds no objects.
+func (i *clusterIndexer) dropIfEmpty(clusterName logicalcluster.Name, p *clusterPartition) {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	if i.partitions[clusterName] != p {
+		return
+	}
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.count == 0 {
+		p.dropped = true
+		delete(i.partitions, clusterName)
+	}
+}
+
+func (i *clusterIndexer) DropCluster(clusterName logicalcluster.Name) []interface{} {
+	i.lock.Lock()
+	p, ok := i.partitions[clusterName]
+	delete(i.partitions, clusterName)
+	i.lock.Unlock()
+	if !ok {
+		return nil
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.dropped = true
+	return p.list()
+}
+
+func (i *clusterIndexer) List() []interface{} {
+	var list []interface{}
+	for _, p := range i.snapshot() {
+		p.lock.RLock()
+		list = append(list, p.list()...)
+		p.lock.RUnlock()
+	}
+	return list
+}
+
+func (i *clusterIndexer) ListKeys() []string {
+	var keys []string
+	for _, p := range i.snapshot() {
+		p.lock.RLock()
+		for _, items := range p.namespaces {
+			for key := range items {
+				keys = append(keys, key)
+			}
+		}
+		p.lock.RUnlock()
+	}
+	return keys
+}
+
+func (i *clusterIndexer) Get(obj interface{}) (item interface{}, exists bool, err error) {
+	key, err := i.keyFunc(obj)
+	if err != nil {
+		return nil, false, cache.KeyError{Obj: obj, Err: err}
+	}
+	return i.GetByKey(key)
+}
+
+func (i *clusterIndexer) GetByKey(key string) (item interface{}, exists bool, err error) {
+	clusterName, namespace := splitKey(key)
+	p := i.rLockPartition(clusterName)
+	if p == nil {
+		return nil, false, nil
+	}
+	defer p.lock.RUnlock()
+	item, exists = p.namespaces[namespace][key]
+	return item, exists, nil
+}
+
+func (i *clusterIndexer) Replace(list []interface{}, resourceVersion string) error {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	partitions := map[logicalcluster.Name]*clusterPartition{}
+	for _, obj := range list {
+		key, err := i.keyFunc(obj)
+		if err != nil {
+			return cache.KeyError{Obj: obj, Err: err}
+		}
+		clusterName, namespace := splitKey(key)
+		p, ok := partitions[clusterName]
+		if !ok {
+			p = newClusterPartition(i.indexers)
+			partitions[clusterName] = p
+		}
+		old := p.set(namespace, key, obj)
+		p.updateIndices(p.indexers, old, obj, key)
+	}
+
+	for _, p := range i.partitions {
+		p.lock.Lock()
+		p.dropped = true
+		p.lock.Unlock()
+	}
+	i.partitions = partitions
+	i.rv.Store(&resourceVersion)
+	return nil
+}
+
+func (i *clusterIndexer) Resync() error {
+	return nil
+}
+
+func (i *clusterIndexer) LastStoreSyncResourceVersion() string {
+	// Like the client-go store, only report the resource version with the
+	// AtomicFIFO feature.
+	if !clientgofeaturegate.FeatureGates().Enabled(clientgofeaturegate.AtomicFIFO) {
+		return ""
+	}
+	if rv := i.rv.Load(); rv != nil {
+		return *rv
+	}
+	return ""
+}
+
+func (i *clusterIndexer) Bookmark(rv string) {
+	i.rv.Store(&rv)
+}
+
+func (i *clusterIndexer) observeResourceVersion(obj interface{}) {
+	m, err := meta.Accessor(obj)
+	if err != nil {
+		return
+	}
+	rv := m.GetResourceVersion()
+	i.rv.Store(&rv)
+}
+
+func (i *clusterIndexer) Index(indexName string, obj interface{}) ([]interface{}, error) {
+	indexFunc, err := i.indexFunc(indexName)
+	if err != nil {
+		return nil, err
+	}
+	values, err := indexFunc(obj)
+	if err != nil {
+		return nil, err
+	}
+	if len(values) == 1 {
+		return i.ByIndex(indexName, values[0])
+	}
+
+	seen := sets.New[string]()
+	var list []interface{}
+	for _, value := range values {
+		keys, err := i.IndexKeys(indexName, value)
+		if err != nil {
+			return nil, err
+		}
+		for _, key := range keys {
+			if seen.Has(key) {
+				continue
+			}
+			seen.Insert(key)
+			if item, exists, _ := i.GetByKey(key); exists {
+				list = append(list, item)
+			}
+		}
+	}
+	return list, nil
+}
+
+func (i *clusterIndexer) IndexKeys(indexName, indexedValue string) ([]string, error) {
+	var keys []string
+	err := i.byIndex(indexName, indexedValue, func(key string, _ interface{}) {
+		keys = append(keys, key)
+	})
+	return keys, err
+}
+
+func (i *clusterIndexer) ByIndex(indexName, indexedValue string) ([]interface{}, error) {
+	var list []interface{}
+	err := i.byIndex(indexName, indexedValue, func(_ string, obj interface{}) {
+		list = append(list, obj)
+	})
+	return list, err
+}
+
+// byIndex calls fn for every object whose indexed values for indexName
+// include indexedValue.
+func (i *clusterIndexer) byIndex(indexName, indexedValue string, fn func(key string, obj interface{})) error {
+	switch indexName {
+	case ClusterIndexName:
+		p := i.rLockPartition(logicalcluster.Name(indexedValue))
+		if p == nil {
+			return nil
+		}
+		defer p.lock.RUnlock()
+		for _, items := range p.namespaces {
+			for key, obj := range items {
+				fn(key, obj)
+			}
+		}
+		return nil
+
+	case ClusterAndNamespaceIndexName:
+		clusterName, namespace, ok := strings.Cut(indexedValue, "/")
+		if !ok {
+			return nil
+		}
+		p := i.rLockPartition(logicalcluster.Name(clusterName))
+		if p == nil {
+			return nil
+		}
+		defer p.lock.RUnlock()
+		for key, obj := range p.namespaces[namespace] {
+			fn(key, obj)
+		}
+		return nil
+	}
+
+	if _, err := i.indexFunc(indexName); err != nil {
+		return err
+	}
+	for _, p := range i.snapshot() {
+		p.lock.RLock()
+		for key := range p.indices[indexName][indexedValue] {
+			_, namespace := splitKey(key)
+			fn(key, p.namespaces[namespace][key])
+		}
+		p.lock.RUnlock()
+	}
+	return nil
+}
+
+func (i *clusterIndexer) ListIndexFuncValues(indexName string) []string {
+	values := sets.New[string]()
+	for _, p := range i.snapshotWithNames() {
+		p.partition.lock.RLock()
+		switch indexName {
+		case ClusterIndexName:
+			values.Insert(ClusterIndexKey(p.name))
+		case ClusterAndNamespaceIndexName:
+			for namespace := range p.partition.namespaces {
+				values.Insert(ClusterAndNamespaceIndexKey(p.name, namespace))
+			}
+		default:
+			for value, keys := range p.partition.indices[indexName] {
+				if keys.Len() > 0 {
+					values.Insert(value)
+				}
+			}
+		}
+		p.partition.lock.RUnlock()
+	}
+	return sets.List(values)
+}
+
+type namedPartition struct {
+	name      logicalcluster.Name
+	partition *clusterPartition
+}
+
+func (i *clusterIndexer) snapshotWithNames() []namedPartition {
+	i.lock.RLock()
+	defer i.lock.RUnlock()
+	partitions := make([]namedPartition, 0, len(i.partitions))
+	for name, p := range i.partitions {
+		partitions = append(partitions, namedPartition{name: name, partition: p})
+	}
+	return partitions
+}
+
+func (i *clusterIndexer) GetIndexers() cache.Indexers {
+	i.lock.RLock()
+	defer i.lock.RUnlock()
+	indexers := maps.Clone(i.indexers)
+	indexers[ClusterIndexName] = ClusterIndexFunc
+	indexers[ClusterAndNamespaceIndexName] = ClusterAndNamespaceIndexFunc
+	return indexers
+}
+
+func (i *clusterIndexer) AddIndexers(newIndexers cache.Indexers) error {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	added := cache.Indexers{}
+	for name, indexFunc := range newIndexers {
+		if isPartitionIndex(name) {
+			continue
+		}
+		if _, exists := i.indexers[name]; exists {
+			return fmt.Errorf("indexer conflict: %v", name)
+		}
+		added[name] = indexFunc
+	}
+	if len(added) == 0 {
+		return nil
+	}
+
+	indexers := maps.Clone(i.indexers)
+	maps.Copy(indexers, added)
+	for _, p := range i.partitions {
+		p.lock.Lock()
+		for _, items := range p.namespaces {
+			for key, obj := range items {
+				p.updateIndices(added, nil, obj, key)
+			}
+		}
+		p.indexers = indexers
+		p.lock.Unlock()
+	}
+	i.indexers = indexers
+	return nil
+}
+
+func (i *clusterIndexer) indexFunc(indexName string) (cache.IndexFunc, error) {
+	switch indexName {
+	case ClusterIndexName:
+		return ClusterIndexFunc, nil
+	case ClusterAndNamespaceIndexName:
+		return ClusterAndNamespaceIndexFunc, nil
+	}
+	i.lock.RLock()
+	defer i.lock.RUnlock()
+	indexFunc, ok := i.indexers[indexName]
+	if !ok {
+		return nil, fmt.Errorf("Index with name %s does not exist", indexName)
+	}
+	return indexFunc, nil
+}
+
+// set stores obj and returns the object it replaced, if any. Must be called
+// with the lock held.
+func (p *clusterPartition) set(namespace, key string, obj interface{}) interface{} {
+	items, ok := p.namespaces[namespace]
+	if !ok {
+		items = map[string]interface{}{}
+		p.namespaces[namespace] = items
+	}
+	old, exists := items[key]
+	if !exists {
+		p.count++
+	}
+	items[key] = obj
+	return old
+}
+
+// remove deletes the object stored under key. Must be called with the lock
+// held.
+func (p *clusterPartition) remove(namespace, key string) (interface{}, bool) {
+	items := p.namespaces[namespace]
+	old, exists := items[key]
+	if !exists {
+		return nil, false
+	}
+	delete(items, key)
+	if len(items) == 0 {
+		delete(p.namespaces, namespace)
+	}
+	p.count--
+	return old, true
+}
+
+// list returns all objects. Must be called with the lock held.
+func (p *clusterPartition) list() []interface{} {
+	list := make([]interface{}, 0, p.count)
+	for _, items := range p.namespaces {
+		for _, obj := range items {
+			list = append(list, obj)
+		}
+	}
+	return list
+}
+
+// updateIndices updates the indexes of indexers for key changing from
+// oldObj to newObj, either of which may be nil. Must be called with the lock
+// held.
+func (p *clusterPartition) updateIndices(indexers cache.Indexers, oldObj, newObj interface{}, key string) {
+	for name, indexFunc := range indexers {
+		var oldValues, newValues []string
+		var err error
+		if oldObj != nil {
+			oldValues, err = indexFunc(oldObj)
+			if err != nil {
+				panic(fmt.Errorf("unable to calculate an index entry for key %q on index %q: %w", key, name, err))
+			}
+		}
+		if newObj != nil {
+			newValues, err = indexFunc(newObj)
+			if err != nil {
+				panic(fmt.Errorf("unable to calculate an index entry for key %q on index %q: %w", key, name, err))
+			}
+		}
+
+		index := p.indices[name]
+		if index == nil {
+			index = map[string]sets.Set[string]{}
+			p.indices[name] = index
+		}
+		for _, value := range oldValues {
+			keys := index[value]
+			keys.Delete(key)
+			if keys.Len() == 0 {
+				delete(index, value)
+			}
+		}
+		for _, value := range newValues {
+			keys, ok := index[value]
+			if !ok {
+				keys = sets.New[string]()
+				index[value] = keys
+			}
+			keys.Insert(key)
+		}
+	}
+}
@@ -0,0 +1,321 @@
+/*
+Copyright 2026 The kcp Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/kcp-dev/logicalcluster/v3"
+)
+
+func appIndexFunc(obj interface{}) ([]string, error) {
+	m, err := meta.Accessor(obj)
+	if err != nil {
+		return nil, err
+	}
+	if app, ok := m.GetLabels()["app"]; ok {
+		return []string{app}, nil
+	}
+	return nil, nil
+}
+
+func newTestClusterIndexer(t *testing.T) ClusterIndexer {
+	indexer := NewClusterIndexer(MetaClusterNamespaceKeyFunc, cache.Indexers{"app": appIndexFunc})
+	for _, cluster := range []string{"c1", "c2"} {
+		require.NoError(t, indexer.Add(newUnstructured(cluster, "ns1", "n1", map[string]string{"app": "myapp"})))
+		require.NoError(t, indexer.Add(newUnstructured(cluster, "ns2", "n1", map[string]string{"app": "myapp"})))
+		require.NoError(t, indexer.Add(newUnstructured(cluster, "ns2", "n2", nil)))
+		require.NoError(t, indexer.Add(newUnstructured(cluster, "", "cn1", map[string]string{"app": "myapp"})))
+		require.NoError(t, indexer.Add(newUnstructured(cluster, "", "cn2", nil)))
+	}
+	return indexer
+}
+
+func sortedKeys(t *testing.T, objs []interface{}) []string {
+	t.Helper()
+	keys := make([]string, 0, len(objs))
+	for _, obj := range objs {
+		key, err := MetaClusterNamespaceKeyFunc(obj)
+		require.NoError(t, err)
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func TestClusterIndexerIndexes(t *testing.T) {
+	indexer := newTestClusterIndexer(t)
+
+	tests := map[string]struct {
+		indexName    string
+		indexedValue string
+		want         []string
+	}{
+		"cluster": {
+			indexName:    ClusterIndexName,
+			indexedValue: "c1",
+			want:         []string{"c1|cn1", "c1|cn2", "c1|ns1/n1", "c1|ns2/n1", "c1|ns2/n2"},
+		},
+		"unknown cluster": {
+			indexName:    ClusterIndexName,
+			indexedValue: "c3",
+			want:         []string{},
+		},
+		"cluster and namespace": {
+			indexName:    ClusterAndNamespaceIndexName,
+			indexedValue: "c2/ns2",
+			want:         []string{"c2|ns2/n1", "c2|ns2/n2"},
+		},
+		"cluster scoped": {
+			indexName:    ClusterAndNamespaceIndexName,
+			indexedValue: "c2/",
+			want:         []string{"c2|cn1", "c2|cn2"},
+		},
+		"custom": {
+			indexName:    "app",
+			indexedValue: "myapp",
+			want:         []string{"c1|cn1", "c1|ns1/n1", "c1|ns2/n1", "c2|cn1", "c2|ns1/n1", "c2|ns2/n1"},
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			objs, err := indexer.ByIndex(tt.indexName, tt.indexedValue)
+			require.NoError(t, err)
+			require.Equal(t, tt.want, sortedKeys(t, objs))
+
+			keys, err := indexer.IndexKeys(tt.indexName, tt.indexedValue)
+			require.NoError(t, err)
+			require.ElementsMatch(t, tt.want, keys)
+		})
+	}
+
+	_, err := indexer.ByIndex("unknown", "")
+	require.EqualError(t, err, "Index with name unknown does not exist")
+
+	require.Equal(t, []string{"c1", "c2"}, indexer.ListIndexFuncValues(ClusterIndexName))
+	require.Equal(t, []string{"c1/", "c1/ns1", "c1/ns2", "c2/", "c2/ns1", "c2/ns2"}, indexer.ListIndexFuncValues(ClusterAndNamespaceIndexName))
+	require.Equal(t, []string{"myapp"}, indexer.ListIndexFuncValues("app"))
+
+	objs, err := indexer.Index(ClusterAndNamespaceIndexName, newUnstructured("c1", "ns1", "other", nil))
+	require.NoError(t, err)
+	require.Equal(t, []string{"c1|ns1/n1"}, sortedKeys(t, objs))
+
+	require.ElementsMatch(t, []string{ClusterIndexName, ClusterAndNamespaceIndexName, "app"}, func() []string {
+		var names []string
+		for name := range indexer.GetIndexers() {
+			names = append(names, name)
+		}
+		return names
+	}())
+}
+
+func TestClusterIndexerListers(t *testing.T) {
+	l := NewGenericClusterLister(newTestClusterIndexer(t), schema.GroupResource{})
+
+	list, err := l.List(labels.Everything())
+	require.NoError(t, err)
+	require.Len(t, list, 10)
+
+	list, err = l.ByCluster("c1").List(labels.Set{"app": "myapp"}.AsSelector())
+	require.NoError(t, err)
+	require.Len(t, list, 3)
+
+	list, err = l.ByCluster("c2").ByNamespace("ns2").List(labels.Everything())
+	require.NoError(t, err)
+	require.Len(t, list, 2)
+
+	obj, err := l.ByCluster("c2").ByNamespace("ns1").Get("n1")
+	require.NoError(t, err)
+	require.Equal(t, "n1", obj.(*unstructured.Unstructured).GetName())
+}
+
+func TestClusterIndexerUpdateAndDelete(t *testing.T) {
+	indexer := newTestClusterIndexer(t)
+
+	require.NoError(t, indexer.Update(newUnstructured("c1", "ns1", "n1", map[string]string{"app": "other"})))
+	keys, err := indexer.IndexKeys("app", "other")
+	require.NoError(t, err)
+	require.Equal(t, []string{"c1|ns1/n1"}, keys)
+	keys, err = indexer.IndexKeys("app", "myapp")
+	require.NoError(t, err)
+	require.NotContains(t, keys, "c1|ns1/n1")
+
+	for _, obj := range indexer.List() {
+		if logicalcluster.From(obj.(*unstructured.Unstructured)) == "c1" {
+			require.NoError(t, indexer.Delete(obj))
+		}
+	}
+	require.Equal(t, []string{"c2"}, indexer.ListIndexFuncValues(ClusterIndexName))
+	require.Equal(t, []string{"myapp"}, indexer.ListIndexFuncValues("app"))
+	require.Len(t, indexer.ListKeys(), 5)
+
+	_, exists, err := indexer.GetByKey("c1|ns1/n1")
+	require.NoError(t, err)
+	require.False(t, exists)
+	require.NoError(t, indexer.Delete(newUnstructured("c1", "ns1", "n1", nil)), "deleting a missing object is not an error")
+}
+
+func TestClusterIndexerDropCluster(t *testing.T) {
+	indexer := newTestClusterIndexer(t)
+
+	dropped := indexer.DropCluster("c1")
+	require.Equal(t, []string{"c1|cn1", "c1|cn2", "c1|ns1/n1", "c1|ns2/n1", "c1|ns2/n2"}, sortedKeys(t, dropped))
+	require.Empty(t, indexer.DropCluster("c1"))
+
+	require.Len(t, indexer.List(), 5)
+	keys, err := indexer.IndexKeys("app", "myapp")
+	require.NoError(t, err)
+	sort.Strings(keys)
+	require.Equal(t, []string{"c2|cn1", "c2|ns1/n1", "c2|ns2/n1"}, keys)
+
+	require.NoError(t, indexer.Add(newUnstructured("c1", "ns1", "n1", nil)))
+	_, exists, err := indexer.GetByKey("c1|ns1/n1")
+	require.NoError(t, err)
+	require.True(t, exists, "a dropped cluster can be added again")
+}
+
+func TestClusterIndexerReplaceAndAddIndexers(t *testing.T) {
+	indexer := newTestClusterIndexer(t)
+
+	require.NoError(t, indexer.Replace([]interface{}{
+		newUnstructured("c3", "ns1", "n1", map[string]string{"app": "myapp", "tier": "web"}),
+		newUnstructured("c3", "ns1", "n2", map[string]string{"tier": "db"}),
+	}, "10"))
+	require.Equal(t, []string{"c3|ns1/n1", "c3|ns1/n2"}, sortedKeys(t, indexer.List()))
+	require.Equal(t, []string{"myapp"}, indexer.ListIndexFuncValues("app"))
+
+	require.NoError(t, indexer.AddIndexers(cache.Indexers{
+		"tier": func(obj interface{}) ([]string, error) {
+			return []string{obj.(*unstructured.Unstructured).GetLabels()["tier"]}, nil
+		},
+		ClusterIndexName: ClusterIndexFunc,
+	}))
+	keys, err := indexer.IndexKeys("tier", "db")
+	require.NoError(t, err)
+	require.Equal(t, []string{"c3|ns1/n2"}, keys)
+
+	require.EqualError(t, indexer.AddIndexers(cache.Indexers{"app": appIndexFunc}), "indexer conflict: app")
+}
+
+func TestClusterIndexerConcurrentClusters(t *testing.T) {
+	indexer := NewClusterIndexer(MetaClusterNamespaceKeyFunc, cache.Indexers{"app": appIndexFunc})
+
+	var wg sync.WaitGroup
+	for c := range 8 {
+		cluster := fmt.Sprintf("c%d", c)
+		wg.Go(func() {
+			for i := range 100 {
+				obj := newUnstructured(cluster, "ns", fmt.Sprintf("n%d", i), map[string]string{"app": "myapp"})
+				require.NoError(t, indexer.Add(obj))
+				if i%2 == 0 {
+					require.NoError(t, indexer.Delete(obj))
+				}
+				if i%10 == 0 {
+					_, err := indexer.ByIndex(ClusterIndexName, cluster)
+					require.NoError(t, err)
+				}
+			}
+		})
+	}
+	wg.Wait()
+
+	require.Len(t, indexer.List(), 8*50)
+	for c := range 8 {
+		objs, err := indexer.ByIndex(ClusterIndexName, fmt.Sprintf("c%d", c))
+		require.NoError(t, err)
+		require.Len(t, objs, 50)
+	}
+}
+
+func TestClusterIndexerConcurrentAddIndexers(t *testing.T) {
+	indexer := NewClusterIndexer(MetaClusterNamespaceKeyFunc, nil)
+
+	var wg sync.WaitGroup
+	for c := range 8 {
+		cluster := fmt.Sprintf("c%d", c)
+		wg.Go(func() {
+			for i := range 200 {
+				require.NoError(t, indexer.Add(newUnstructured(cluster, "ns", fmt.Sprintf("n%d", i), map[string]string{"app": "myapp"})))
+			}
+		})
+	}
+	wg.Go(func() {
+		require.NoError(t, indexer.AddIndexers(cache.Indexers{"app": appIndexFunc}))
+	})
+	wg.Wait()
+
+	// Writes racing with AddIndexers are indexed all the same.
+	objs, err := indexer.ByIndex("app", "myapp")
+	require.NoError(t, err)
+	require.Len(t, objs, 8*200)
+}
+
+func TestClusterIndexerConcurrentWritersResourceVersion(t *testing.T) {
+	indexer := NewClusterIndexer(MetaClusterNamespaceKeyFunc, nil).(*clusterIndexer)
+	for c := range 8 {
+		require.NoError(t, indexer.Add(newUnstructured(fmt.Sprintf("c%d", c), "ns", "n", nil)))
+	}
+
+	// Writers to existing partitions only need the indexer-wide lock for
+	// reading, so they are not serialized by it.
+	indexer.lock.RLock()
+	var wg sync.WaitGroup
+	written := make(map[string]bool)
+	for c := range 8 {
+		cluster := fmt.Sprintf("c%d", c)
+		for i := range 10 {
+			rv := fmt.Sprintf("%d", c*10+i)
+			written[rv] = true
+			wg.Go(func() {
+				obj := newUnstructured(cluster, "ns", "n", nil)
+				obj.SetResourceVersion(rv)
+				require.NoError(t, indexer.Update(obj))
+			})
+		}
+	}
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(wait.ForeverTestTimeout):
+		t.Fatal("writers are blocked by the indexer-wide lock")
+	}
+	indexer.lock.RUnlock()
+
+	rv := indexer.rv.Load()
+	require.NotNil(t, rv)
+	require.True(t, written[*rv], "unexpected resource version %q", *rv)
+
+	indexer.Bookmark("100")
+	require.Equal(t, "100", *indexer.rv.Load())
+}
@@ -0,0 +1,163 @@
+/*
+Copyright 2026 The kcp Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	"github.com/kcp-dev/logicalcluster/v3"
+)
+
+// ClusterKey identifies an object in a cluster-aware store. Its String form is
+// the key used by MetaClusterNamespaceKeyFunc:
+// <clusterName>|<namespace>/<name>, where the cluster and namespace parts are
+// omitted when empty.
+//
+// The separators '|' and '/', as well as '%', are percent-encoded inside the
+// components (as %7C, %2F and %25), so that every key splits unambiguously.
+// Components that are valid Kubernetes and logical cluster names never contain
+// them, so their keys are not affected by the escaping.
+type ClusterKey struct {
+	Cluster   logicalcluster.Name
+	Namespace string
+	Name      string
+}
+
+// String formats the key. It is the inverse of ParseClusterKey for every key
+// that passes Validate.
+func (k ClusterKey) String() string {
+	var b strings.Builder
+	if k.Cluster != "" {
+		b.WriteString(escapeKeyComponent(k.Cluster.String()))
+		b.WriteByte('|')
+	}
+	if k.Namespace != "" {
+		b.WriteString(escapeKeyComponent(k.Namespace))
+		b.WriteByte('/')
+	}
+	b.WriteString(escapeKeyComponent(k.Name))
+	return b.String()
+}
+
+// Validate checks that the name is set, that the cluster, if set, is a
+// valid logical cluster name, and that the namespace, if set, is a valid
+// namespace name (a DNS-1123 label).
+func (k ClusterKey) Validate() error {
+	if k.Name == "" {
+		return errors.New("name must not be empty")
+	}
+	if k.Cluster != "" && !k.Cluster.IsValid() {
+		return fmt.Errorf("invalid logical cluster name %q", k.Cluster)
+	}
+	if k.Namespace != "" {
+		if errs := validation.IsDNS1123Label(k.Namespace); len(errs) > 0 {
+			return fmt.Errorf("invalid namespace %q: %s", k.Namespace, strings.Join(errs, "; "))
+		}
+	}
+	return nil
+}
+
+// ParseClusterKey parses a key formatted by ClusterKey.String. It rejects keys
+// with empty cluster or namespace parts, with more than one '|' or '/'
+// separator, with escape sequences String would not have written, and keys
+// that do not pass Validate, so that a parsed key always formats back to key.
+func ParseClusterKey(key string) (ClusterKey, error) {
+	var k ClusterKey
+	rest := key
+	if cluster, after, ok := strings.Cut(rest, "|"); ok {
+		if cluster == "" {
+			return ClusterKey{}, fmt.Errorf("invalid cluster key %q: empty cluster", key)
+		}
+		unescaped, err := unescapeKeyComponent(cluster)
+		if err != nil {
+			return ClusterKey{}, fmt.Errorf("invalid cluster key %q: cluster: %w", key, err)
+		}
+		k.Cluster = logicalcluster.Name(unescaped)
+		rest = after
+	}
+	if strings.Contains(rest, "|") {
+		return ClusterKey{}, fmt.Errorf("invalid cluster key %q: more than one cluster separator", key)
+	}
+	if namespace, after, ok := strings.Cut(rest, "/"); ok {
+		if namespace == "" {
+			return ClusterKey{}, fmt.Errorf("invalid cluster key %q: empty namespace", key)
+		}
+		unescaped, err := unescapeKeyComponent(namespace)
+		if err != nil {
+			return ClusterKey{}, fmt.Errorf("invalid cluster key %q: namespace: %w", key, err)
+		}
+		k.Namespace = unescaped
+		rest = after
+	}
+	if strings.Contains(rest, "/") {
+		return ClusterKey{}, fmt.Errorf("invalid cluster key %q: more than one namespace separator", key)
+	}
+	name, err := unescapeKeyComponent(rest)
+	if err != nil {
+		return ClusterKey{}, fmt.Errorf("invalid cluster key %q: name: %w", key, err)
+	}
+	k.Name = name
+
+	if err := k.Validate(); err != nil {
+		return ClusterKey{}, fmt.Errorf("invalid cluster key %q: %w", key, err)
+	}
+	return k, nil
+}
+
+var keyComponentEscaper = strings.NewReplacer("%", "%25", "|", "%7C", "/", "%2F")
+
+func escapeKeyComponent(s string) string {
+	if !strings.ContainsAny(s, "%|/") {
+		return s
+	}
+	return keyComponentEscaper.Replace(s)
+}
+
+// unescapeKeyComponent reverses escapeKeyComponent. Only the escape sequences
+// written by escapeKeyComponent are accepted, so that the result escapes back
+// to s.
+func unescapeKeyComponent(s string) (string, error) {
+	if !strings.Contains(s, "%") {
+		return s, nil
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '%' {
+			b.WriteByte(s[i])
+			continue
+		}
+		if i+3 > len(s) {
+			return "", fmt.Errorf("truncated escape sequence %q", s[i:])
+		}
+		switch s[i : i+3] {
+		case "%25":
+			b.WriteByte('%')
+		case "%7C":
+			b.WriteByte('|')
+		case "%2F":
+			b.WriteByte('/')
+		default:
+			return "", fmt.Errorf("invalid escape sequence %q", s[i:i+3])
+		}
+		i += 2
+	}
+	return b.String(), nil
+}
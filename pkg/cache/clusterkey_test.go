@@ -0,0 +1,244 @@
+/*
+Copyright 2026 The kcp Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/kcp-dev/logicalcluster/v3"
+)
+
+func TestClusterKeyString(t *testing.T) {
+	var testCases = []struct {
+		name     string
+		key      ClusterKey
+		expected string
+	}{
+		{
+			name:     "fully populated key",
+			key:      ClusterKey{Cluster: "cluster", Namespace: "namespace", Name: "name"},
+			expected: "cluster|namespace/name",
+		},
+		{
+			name:     "cluster-scoped object",
+			key:      ClusterKey{Cluster: "cluster", Name: "name"},
+			expected: "cluster|name",
+		},
+		{
+			name:     "single-cluster, namespaced context",
+			key:      ClusterKey{Namespace: "namespace", Name: "name"},
+			expected: "namespace/name",
+		},
+		{
+			name:     "separators are escaped",
+			key:      ClusterKey{Cluster: "a|b", Namespace: "c/d", Name: "e%f"},
+			expected: "a%7Cb|c%2Fd/e%25f",
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			if diff := cmp.Diff(testCase.key.String(), testCase.expected); diff != "" {
+				t.Errorf("%s: invalid key: %v", testCase.name, diff)
+			}
+		})
+	}
+}
+
+func TestParseClusterKey(t *testing.T) {
+	var testCases = []struct {
+		name        string
+		key         string
+		expected    ClusterKey
+		expectedErr error
+	}{
+		{
+			name:     "fully populated key",
+			key:      "cluster|namespace/name",
+			expected: ClusterKey{Cluster: "cluster", Namespace: "namespace", Name: "name"},
+		},
+		{
+			name:     "cluster-scoped object",
+			key:      "cluster|name",
+			expected: ClusterKey{Cluster: "cluster", Name: "name"},
+		},
+		{
+			name:     "single-cluster, cluster-scoped context",
+			key:      "name",
+			expected: ClusterKey{Name: "name"},
+		},
+		{
+			name:     "system cluster",
+			key:      "system:admin|name",
+			expected: ClusterKey{Cluster: "system:admin", Name: "name"},
+		},
+		{
+			name:     "escaped name",
+			key:      "cluster|namespace/e%25f%2Fg%7C",
+			expected: ClusterKey{Cluster: "cluster", Namespace: "namespace", Name: "e%f/g|"},
+		},
+		{
+			name:        "too many cluster separators",
+			key:         "cluster|extra|name",
+			expectedErr: errors.New(`invalid cluster key "cluster|extra|name": more than one cluster separator`),
+		},
+		{
+			name:        "too many namespace separators",
+			key:         "cluster|a/b/c",
+			expectedErr: errors.New(`invalid cluster key "cluster|a/b/c": more than one namespace separator`),
+		},
+		{
+			name:        "empty cluster",
+			key:         "|name",
+			expectedErr: errors.New(`invalid cluster key "|name": empty cluster`),
+		},
+		{
+			name:        "empty namespace",
+			key:         "/name",
+			expectedErr: errors.New(`invalid cluster key "/name": empty namespace`),
+		},
+		{
+			name:        "empty name",
+			key:         "cluster|namespace/",
+			expectedErr: errors.New(`invalid cluster key "cluster|namespace/": name must not be empty`),
+		},
+		{
+			name:        "invalid cluster name",
+			key:         "root:something|name",
+			expectedErr: errors.New(`invalid cluster key "root:something|name": invalid logical cluster name "root:something"`),
+		},
+		{
+			name:        "escaped separator in namespace",
+			key:         "cluster|c%2Fd/name",
+			expectedErr: errors.New(`invalid cluster key "cluster|c%2Fd/name": invalid namespace "c/d": a lowercase RFC 1123 label must consist of lower case alphanumeric characters or '-', and must start and end with an alphanumeric character (e.g. 'my-name',  or '123-abc', regex used for validation is '[a-z0-9]([-a-z0-9]*[a-z0-9])?')`),
+		},
+		{
+			name:        "invalid namespace",
+			key:         "cluster|Namespace/name",
+			expectedErr: errors.New(`invalid cluster key "cluster|Namespace/name": invalid namespace "Namespace": a lowercase RFC 1123 label must consist of lower case alphanumeric characters or '-', and must start and end with an alphanumeric character (e.g. 'my-name',  or '123-abc', regex used for validation is '[a-z0-9]([-a-z0-9]*[a-z0-9])?')`),
+		},
+		{
+			name:        "escaped separator in cluster",
+			key:         "a%7Cb|name",
+			expectedErr: errors.New(`invalid cluster key "a%7Cb|name": invalid logical cluster name "a|b"`),
+		},
+		{
+			name:        "unknown escape sequence",
+			key:         "cluster|na%41me",
+			expectedErr: errors.New(`invalid cluster key "cluster|na%41me": name: invalid escape sequence "%41"`),
+		},
+		{
+			name:        "truncated escape sequence",
+			key:         "cluster|name%7",
+			expectedErr: errors.New(`invalid cluster key "cluster|name%7": name: truncated escape sequence "%7"`),
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			actual, actualErr := ParseClusterKey(testCase.key)
+			if diff := cmp.Diff(actualErr, testCase.expectedErr, equateErrorMessage); diff != "" {
+				t.Errorf("%s: invalid error: %v", testCase.name, diff)
+				return
+			}
+			if diff := cmp.Diff(actual, testCase.expected); diff != "" {
+				t.Errorf("%s: invalid key: %v", testCase.name, diff)
+			}
+			if actualErr == nil {
+				if diff := cmp.Diff(actual.String(), testCase.key); diff != "" {
+					t.Errorf("%s: key does not round-trip: %v", testCase.name, diff)
+				}
+			}
+		})
+	}
+}
+
+// FuzzClusterKey checks that keys formatted by ToClusterAwareKey split back
+// into their components, and that they are unchanged from the unescaped
+// format for components without separators.
+func FuzzClusterKey(f *testing.F) {
+	f.Add("cluster", "namespace", "name")
+	f.Add("", "namespace", "name")
+	f.Add("cluster", "", "name")
+	f.Add("system:admin", "", "name")
+	f.Add("a|b", "c/d", "e%f")
+	f.Add("%7C", "%2F", "%25")
+	f.Fuzz(func(t *testing.T, cluster, namespace, name string) {
+		key := ToClusterAwareKey(cluster, namespace, name)
+		k := ClusterKey{Cluster: logicalcluster.Name(cluster), Namespace: namespace, Name: name}
+		if got := k.String(); got != key {
+			t.Fatalf("ClusterKey.String() = %q, ToClusterAwareKey() = %q", got, key)
+		}
+
+		if !strings.ContainsAny(cluster+namespace+name, "%|/") {
+			var unescaped string
+			if cluster != "" {
+				unescaped += cluster + "|"
+			}
+			if namespace != "" {
+				unescaped += namespace + "/"
+			}
+			unescaped += name
+			if key != unescaped {
+				t.Fatalf("key %q differs from unescaped key %q", key, unescaped)
+			}
+		}
+
+		if name != "" {
+			gotCluster, gotNamespace, gotName, err := SplitMetaClusterNamespaceKey(key)
+			if err != nil {
+				t.Fatalf("failed to split %q: %v", key, err)
+			}
+			if gotCluster.String() != cluster || gotNamespace != namespace || gotName != name {
+				t.Fatalf("split %q into %q, %q, %q, expected %q, %q, %q", key, gotCluster, gotNamespace, gotName, cluster, namespace, name)
+			}
+		}
+
+		if k.Validate() != nil {
+			return
+		}
+		parsed, err := ParseClusterKey(key)
+		if err != nil {
+			t.Fatalf("failed to parse %q: %v", key, err)
+		}
+		if parsed != k {
+			t.Fatalf("parsed %q into %#v, expected %#v", key, parsed, k)
+		}
+	})
+}
+
+// FuzzParseClusterKey checks that every key accepted by ParseClusterKey
+// formats back to itself.
+func FuzzParseClusterKey(f *testing.F) {
+	f.Add("cluster|namespace/name")
+	f.Add("namespace/name")
+	f.Add("name")
+	f.Add("cluster|namespace/e%25f%7C")
+	f.Add("cluster|na%41me")
+	f.Add("|||")
+	f.Fuzz(func(t *testing.T, key string) {
+		parsed, err := ParseClusterKey(key)
+		if err != nil {
+			return
+		}
+		if got := parsed.String(); got != key {
+			t.Fatalf("parsed %q into %#v, which formats to %q", key, parsed, got)
+		}
+	})
+}
@@ -0,0 +1,208 @@
+/*
+Copyright 2026 The kcp Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"fmt"
+	"sync"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/kcp-dev/logicalcluster/v3"
+)
+
+// ClusterSet is a set of logical clusters whose membership can change at
+// runtime. It is safe for concurrent use.
+type ClusterSet struct {
+	lock     sync.RWMutex
+	names    sets.Set[logicalcluster.Name]
+	handlers map[*func(added, removed []logicalcluster.Name)]struct{}
+}
+
+// NewClusterSet creates a set of the given clusters.
+func NewClusterSet(names ...logicalcluster.Name) *ClusterSet {
+	return &ClusterSet{
+		names:    sets.New(names...),
+		handlers: map[*func(added, removed []logicalcluster.Name)]struct{}{},
+	}
+}
+
+// Add adds clusters to the set. The change handlers are called with the
+// clusters that were not in the set yet.
+func (s *ClusterSet) Add(names ...logicalcluster.Name) {
+	s.lock.Lock()
+	var added []logicalcluster.Name
+	for _, name := range names {
+		if !s.names.Has(name) {
+			s.names.Insert(name)
+			added = append(added, name)
+		}
+	}
+	handlers := s.changeHandlers(len(added))
+	s.lock.Unlock()
+
+	for _, h := range handlers {
+		h(added, nil)
+	}
+}
+
+// Remove removes clusters from the set. The change handlers are called with
+// the clusters that were in the set.
+func (s *ClusterSet) Remove(names ...logicalcluster.Name) {
+	s.lock.Lock()
+	var removed []logicalcluster.Name
+	for _, name := range names {
+		if s.names.Has(name) {
+			s.names.Delete(name)
+			removed = append(removed, name)
+		}
+	}
+	handlers := s.changeHandlers(len(removed))
+	s.lock.Unlock()
+
+	for _, h := range handlers {
+		h(nil, removed)
+	}
+}
+
+// changeHandlers returns the change handlers if there are changes. Must be
+// called with the lock held.
+func (s *ClusterSet) changeHandlers(changes int) []func(added, removed []logicalcluster.Name) {
+	if changes == 0 {
+		return nil
+	}
+	handlers := make([]func(added, removed []logicalcluster.Name), 0, len(s.handlers))
+	for h := range s.handlers {
+		handlers = append(handlers, *h)
+	}
+	return handlers
+}
+
+// Has returns whether name is in the set.
+func (s *ClusterSet) Has(name logicalcluster.Name) bool {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.names.Has(name)
+}
+
+// List returns the clusters in the set, sorted.
+func (s *ClusterSet) List() []logicalcluster.Name {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return sets.List(s.names)
+}
+
+// AddChangeHandler registers fn to be called after clusters were added to or
+// removed from the set. Calls for concurrent changes are not ordered. The
+// returned func removes the handler again.
+func (s *ClusterSet) AddChangeHandler(fn func(added, removed []logicalcluster.Name)) (remove func()) {
+	h := &fn
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.handlers[h] = struct{}{}
+	return func() {
+		s.lock.Lock()
+		defer s.lock.Unlock()
+		delete(s.handlers, h)
+	}
+}
+
+// ByClusterSet returns a cache.GenericLister for the logical clusters in set.
+// The members are read on every call. Get fails if the name exists in more
+// than one of the clusters.
+func (s *ClusterLister) ByClusterSet(set *ClusterSet) cache.GenericLister {
+	return &multiClusterLister{
+		clusterLister: s,
+		clusters:      set.List,
+		description:   "cluster set",
+	}
+}
+
+// multiClusterLister lists the objects of several logical clusters.
+type multiClusterLister struct {
+	clusterLister *ClusterLister
+	// clusters returns the names of the clusters, sorted.
+	clusters    func() []logicalcluster.Name
+	description string
+}
+
+func (s *multiClusterLister) List(selector labels.Selector) (ret []runtime.Object, err error) {
+	return s.list("", selector)
+}
+
+func (s *multiClusterLister) list(namespace string, selector labels.Selector) (ret []runtime.Object, err error) {
+	for _, name := range s.clusters() {
+		list, err := s.clusterLister.ByCluster(name).ByNamespace(namespace).List(selector)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, list...)
+	}
+	return ret, nil
+}
+
+func (s *multiClusterLister) Get(name string) (runtime.Object, error) {
+	return s.get("", name)
+}
+
+// get returns the object called name in namespace from the one cluster that
+// has it.
+func (s *multiClusterLister) get(namespace, name string) (runtime.Object, error) {
+	var found runtime.Object
+	var foundIn logicalcluster.Name
+	for _, clusterName := range s.clusters() {
+		obj, err := s.clusterLister.ByCluster(clusterName).ByNamespace(namespace).Get(name)
+		if apierrors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if found != nil {
+			return nil, fmt.Errorf("%s %q exists in logical clusters %s and %s of %s", s.clusterLister.resource, name, foundIn, clusterName, s.description)
+		}
+		found, foundIn = obj, clusterName
+	}
+	if found == nil {
+		return nil, apierrors.NewNotFound(s.clusterLister.resource, name)
+	}
+	return found, nil
+}
+
+func (s *multiClusterLister) ByNamespace(namespace string) cache.GenericNamespaceLister {
+	return &multiClusterNamespaceLister{
+		multiClusterLister: s,
+		namespace:          namespace,
+	}
+}
+
+type multiClusterNamespaceLister struct {
+	multiClusterLister *multiClusterLister
+	namespace          string
+}
+
+func (s *multiClusterNamespaceLister) List(selector labels.Selector) (ret []runtime.Object, err error) {
+	return s.multiClusterLister.list(s.namespace, selector)
+}
+
+func (s *multiClusterNamespaceLister) Get(name string) (runtime.Object, error) {
+	return s.multiClusterLister.get(s.namespace, name)
+}
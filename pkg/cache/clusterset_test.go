@@ -0,0 +1,86 @@
+/*
+Copyright 2026 The kcp Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/kcp-dev/logicalcluster/v3"
+)
+
+func TestClusterSet(t *testing.T) {
+	set := NewClusterSet("c2", "c1")
+	require.Equal(t, []logicalcluster.Name{"c1", "c2"}, set.List())
+
+	type change struct {
+		added, removed []logicalcluster.Name
+	}
+	var changes []change
+	remove := set.AddChangeHandler(func(added, removed []logicalcluster.Name) {
+		changes = append(changes, change{added: added, removed: removed})
+	})
+
+	set.Add("c1", "c3")
+	set.Remove("c2", "c4")
+	set.Add("c1")
+	require.Equal(t, []change{
+		{added: []logicalcluster.Name{"c3"}},
+		{removed: []logicalcluster.Name{"c2"}},
+	}, changes)
+	require.True(t, set.Has("c3"))
+	require.False(t, set.Has("c2"))
+
+	remove()
+	set.Add("c5")
+	require.Len(t, changes, 2)
+}
+
+func TestByClusterSet(t *testing.T) {
+	indexer := newTestIndexer(t)
+	require.NoError(t, indexer.Add(newUnstructured("c3", "ns1", "n3", nil)))
+	set := NewClusterSet("c1")
+	l := NewGenericClusterLister(indexer, schema.GroupResource{}).ByClusterSet(set)
+
+	list, err := l.List(labels.Everything())
+	require.NoError(t, err)
+	require.Len(t, list, 5)
+
+	set.Add("c3")
+	list, err = l.ByNamespace("ns1").List(labels.Everything())
+	require.NoError(t, err)
+	require.Len(t, list, 2)
+
+	obj, err := l.ByNamespace("ns1").Get("n3")
+	require.NoError(t, err)
+	require.Equal(t, logicalcluster.Name("c3"), logicalcluster.From(obj.(*unstructured.Unstructured)))
+
+	set.Add("c2")
+	_, err = l.ByNamespace("ns1").Get("n1")
+	require.Error(t, err, "n1 exists in two clusters of the set")
+	require.False(t, apierrors.IsNotFound(err))
+
+	set.Remove("c1", "c2", "c3")
+	_, err = l.Get("cn1")
+	require.True(t, apierrors.IsNotFound(err))
+}
@@ -29,6 +29,11 @@ const (
 	ClusterIndexName = "cluster"
 	// ClusterAndNamespaceIndexName is the name of index that allows you to filter by cluster and namespace.
 	ClusterAndNamespaceIndexName = "cluster-and-namespace"
+	// ShardIndexName is the name of the index that allows you to filter by shard.
+	ShardIndexName = "shard"
+	// ClusterKeyIndexName is the name of the index that allows you to look up
+	// objects keyed with a shard by their key without the shard.
+	ClusterKeyIndexName = "cluster-key"
 )
 
 // ClusterIndexFunc indexes by cluster name.
@@ -58,3 +63,28 @@ func ClusterAndNamespaceIndexFunc(obj interface{}) ([]string, error) {
 func ClusterAndNamespaceIndexKey(clusterName logicalcluster.Name, namespace string) string {
 	return clusterName.String() + "/" + namespace
 }
+
+// ShardIndexFunc indexes by the shard in the ShardAnnotationKey annotation.
+// Objects without the annotation are not indexed.
+func ShardIndexFunc(obj interface{}) ([]string, error) {
+	meta, err := meta.Accessor(obj)
+	if err != nil {
+		return []string{}, fmt.Errorf("object has no meta: %v", err)
+	}
+	shard, ok := meta.GetAnnotations()[ShardAnnotationKey]
+	if !ok {
+		return []string{}, nil
+	}
+	return []string{shard}, nil
+}
+
+// ClusterKeyIndexFunc indexes by the key of MetaClusterNamespaceKeyFunc, so
+// that objects keyed with MetaShardClusterNamespaceKeyFunc can be found
+// without knowing their shard.
+func ClusterKeyIndexFunc(obj interface{}) ([]string, error) {
+	key, err := MetaClusterNamespaceKeyFunc(obj)
+	if err != nil {
+		return []string{}, err
+	}
+	return []string{key}, nil
+}
@@ -0,0 +1,198 @@
+/*
+Copyright 2026 The kcp Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"fmt"
+	"maps"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/selection"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/kcp-dev/logicalcluster/v3"
+)
+
+// FieldExtractor returns the selectable fields of an object in addition to
+// metadata.name and metadata.namespace, e.g. spec.nodeName of a Pod.
+type FieldExtractor func(obj runtime.Object) (fields.Set, error)
+
+var fieldExtractors = struct {
+	lock       sync.RWMutex
+	extractors map[schema.GroupVersionKind]FieldExtractor
+	typer      runtime.ObjectTyper
+}{
+	extractors: map[schema.GroupVersionKind]FieldExtractor{},
+}
+
+// RegisterFieldExtractor registers the field extractor of the objects of
+// kind gvk. Field selectors of the listers and iterators of this package are
+// evaluated against the fields it returns. The kind of an object is read
+// from its type meta, or else from the typer of SetFieldExtractorTyper.
+func RegisterFieldExtractor(gvk schema.GroupVersionKind, extractor FieldExtractor) {
+	fieldExtractors.lock.Lock()
+	defer fieldExtractors.lock.Unlock()
+	fieldExtractors.extractors[gvk] = extractor
+}
+
+// SetFieldExtractorTyper sets the typer used to find the field extractor of
+// objects without type meta, like the typed objects of most informers. It is
+// usually the scheme the objects were decoded with.
+func SetFieldExtractorTyper(typer runtime.ObjectTyper) {
+	fieldExtractors.lock.Lock()
+	defer fieldExtractors.lock.Unlock()
+	fieldExtractors.typer = typer
+}
+
+// fieldExtractorFor returns the field extractor of obj, or nil.
+func fieldExtractorFor(obj runtime.Object) FieldExtractor {
+	fieldExtractors.lock.RLock()
+	defer fieldExtractors.lock.RUnlock()
+	if len(fieldExtractors.extractors) == 0 {
+		return nil
+	}
+
+	if gvk := obj.GetObjectKind().GroupVersionKind(); !gvk.Empty() {
+		return fieldExtractors.extractors[gvk]
+	}
+	if fieldExtractors.typer == nil {
+		return nil
+	}
+	gvks, _, err := fieldExtractors.typer.ObjectKinds(obj)
+	if err != nil {
+		return nil
+	}
+	for _, gvk := range gvks {
+		if extractor, ok := fieldExtractors.extractors[gvk]; ok {
+			return extractor
+		}
+	}
+	return nil
+}
+
+// ObjectFields returns the selectable fields of obj: metadata.name,
+// metadata.namespace, and the fields of its registered field extractor.
+func ObjectFields(obj interface{}) (fields.Set, error) {
+	metadata, err := meta.Accessor(obj)
+	if err != nil {
+		return nil, fmt.Errorf("object has no meta: %v", err)
+	}
+	set := objectMetaFields(metadata)
+	if runtimeObj, ok := obj.(runtime.Object); ok {
+		if extractor := fieldExtractorFor(runtimeObj); extractor != nil {
+			extracted, err := extractor(runtimeObj)
+			if err != nil {
+				return nil, err
+			}
+			set = maps.Clone(set)
+			maps.Copy(set, extracted)
+		}
+	}
+	return set, nil
+}
+
+// objectMetaFields returns the selectable fields of the object metadata.
+func objectMetaFields(metadata metav1.Object) fields.Set {
+	return fields.Set{
+		"metadata.name":      metadata.GetName(),
+		"metadata.namespace": metadata.GetNamespace(),
+	}
+}
+
+// FieldIndexName returns the name of the index of field, see
+// ClusterFieldIndexFunc.
+func FieldIndexName(field string) string {
+	return "field:" + field
+}
+
+// ClusterFieldIndexFunc returns an index func that indexes objects by cluster
+// and the value of field, see ClusterFieldIndexKey. Register it under
+// FieldIndexName(field) for the listers to use it for field selectors
+// requiring field to equal a value.
+func ClusterFieldIndexFunc(field string) cache.IndexFunc {
+	return func(obj interface{}) ([]string, error) {
+		set, err := ObjectFields(obj)
+		if err != nil {
+			return []string{}, err
+		}
+		if !set.Has(field) {
+			return []string{}, nil
+		}
+		metadata, err := meta.Accessor(obj)
+		if err != nil {
+			return []string{}, fmt.Errorf("object has no meta: %v", err)
+		}
+		return []string{ClusterFieldIndexKey(logicalcluster.From(metadata), set.Get(field))}, nil
+	}
+}
+
+// ClusterFieldIndexKey formats the index key for a cluster name and field
+// value.
+func ClusterFieldIndexKey(clusterName logicalcluster.Name, value string) string {
+	return clusterName.String() + "|" + value
+}
+
+// matchesFields returns whether obj matches fieldSelector, which must not be
+// empty. Objects whose fields cannot be determined do not match.
+func matchesFields(obj interface{}, fieldSelector fields.Selector) bool {
+	set, err := ObjectFields(obj)
+	if err != nil {
+		return false
+	}
+	return fieldSelector.Matches(set)
+}
+
+// byFieldIndex returns the objects of clusterName that a registered field
+// index finds for one of the equality requirements of fieldSelector. ok is
+// false if no such index is registered.
+func byFieldIndex(indexer cache.Indexer, clusterName logicalcluster.Name, fieldSelector fields.Selector) (items []interface{}, ok bool) {
+	indexName, indexKey, ok := fieldIndexFor(indexer, clusterName, fieldSelector)
+	if !ok {
+		return nil, false
+	}
+	items, err := indexer.ByIndex(indexName, indexKey)
+	if err != nil {
+		return nil, false
+	}
+	return items, true
+}
+
+// fieldIndexFor returns the name and key of the registered field index for
+// the first equality requirement of fieldSelector that has one. ok is false
+// if there is none.
+func fieldIndexFor(indexer cache.Indexer, clusterName logicalcluster.Name, fieldSelector fields.Selector) (indexName, indexKey string, ok bool) {
+	if fieldSelector == nil || fieldSelector.Empty() {
+		return "", "", false
+	}
+	indexers := indexer.GetIndexers()
+	for _, requirement := range fieldSelector.Requirements() {
+		if requirement.Operator != selection.Equals && requirement.Operator != selection.DoubleEquals {
+			continue
+		}
+		indexName := FieldIndexName(requirement.Field)
+		if _, registered := indexers[indexName]; !registered {
+			continue
+		}
+		return indexName, ClusterFieldIndexKey(clusterName, requirement.Value), true
+	}
+	return "", "", false
+}
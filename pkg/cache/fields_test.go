@@ -0,0 +1,130 @@
+/*
+Copyright 2026 The kcp Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/kcp-dev/logicalcluster/v3"
+)
+
+func newPod(cluster, namespace, name, nodeName string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:   namespace,
+			Name:        name,
+			Annotations: map[string]string{logicalcluster.AnnotationKey: cluster},
+		},
+		Spec: corev1.PodSpec{NodeName: nodeName},
+	}
+}
+
+func podNames(list []runtime.Object) []string {
+	names := []string{}
+	for _, obj := range list {
+		names = append(names, obj.(*corev1.Pod).Name)
+	}
+	return names
+}
+
+// countingIndexer counts the lookups of field indexes.
+type countingIndexer struct {
+	cache.Indexer
+	fieldLookups int
+}
+
+func (i *countingIndexer) ByIndex(indexName, indexedValue string) ([]interface{}, error) {
+	if indexName == FieldIndexName("spec.nodeName") {
+		i.fieldLookups++
+	}
+	return i.Indexer.ByIndex(indexName, indexedValue)
+}
+
+func TestFieldSelectorListers(t *testing.T) {
+	RegisterFieldExtractor(corev1.SchemeGroupVersion.WithKind("Pod"), func(obj runtime.Object) (fields.Set, error) {
+		return fields.Set{"spec.nodeName": obj.(*corev1.Pod).Spec.NodeName}, nil
+	})
+	SetFieldExtractorTyper(scheme.Scheme)
+
+	for _, indexed := range []bool{false, true} {
+		indexers := cache.Indexers{
+			ClusterIndexName:             ClusterIndexFunc,
+			ClusterAndNamespaceIndexName: ClusterAndNamespaceIndexFunc,
+		}
+		if indexed {
+			indexers[FieldIndexName("spec.nodeName")] = ClusterFieldIndexFunc("spec.nodeName")
+		}
+		indexer := &countingIndexer{Indexer: cache.NewIndexer(MetaClusterNamespaceKeyFunc, indexers)}
+		for _, pod := range []*corev1.Pod{
+			newPod("c1", "ns1", "a", "node-1"),
+			newPod("c1", "ns1", "b", "node-2"),
+			newPod("c1", "ns2", "c", "node-1"),
+			newPod("c2", "ns1", "d", "node-1"),
+		} {
+			require.NoError(t, indexer.Add(pod))
+		}
+
+		l := NewGenericClusterLister(indexer, corev1.Resource("pods"))
+		onNode1 := fields.OneTermEqualSelector("spec.nodeName", "node-1")
+
+		list, err := l.ListWithFields(labels.Everything(), onNode1)
+		require.NoError(t, err)
+		require.ElementsMatch(t, []string{"a", "c", "d"}, podNames(list))
+
+		list, err = l.ByCluster("c1").(FieldSelectorLister).ListWithFields(nil, onNode1)
+		require.NoError(t, err)
+		require.ElementsMatch(t, []string{"a", "c"}, podNames(list))
+
+		list, err = l.ByCluster("c1").ByNamespace("ns1").(FieldSelectorLister).ListWithFields(nil, onNode1)
+		require.NoError(t, err)
+		require.ElementsMatch(t, []string{"a"}, podNames(list))
+
+		list, err = l.ByCluster("c1").(FieldSelectorLister).ListWithFields(nil, fields.AndSelectors(onNode1, fields.OneTermNotEqualSelector("metadata.namespace", "ns2")))
+		require.NoError(t, err)
+		require.ElementsMatch(t, []string{"a"}, podNames(list))
+
+		list, err = l.ByCluster("c1").(FieldSelectorLister).ListWithFields(nil, fields.OneTermNotEqualSelector("spec.nodeName", "node-1"))
+		require.NoError(t, err)
+		require.ElementsMatch(t, []string{"b"}, podNames(list))
+
+		if indexed {
+			require.Equal(t, 3, indexer.fieldLookups)
+		} else {
+			require.Zero(t, indexer.fieldLookups)
+		}
+	}
+}
+
+func TestObjectFields(t *testing.T) {
+	u := newUnstructured("c1", "ns1", "n1", nil)
+	set, err := ObjectFields(u)
+	require.NoError(t, err)
+	require.Equal(t, fields.Set{"metadata.name": "n1", "metadata.namespace": "ns1"}, set)
+
+	_, err = ObjectFields("not an object")
+	require.Error(t, err)
+}
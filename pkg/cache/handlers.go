@@ -0,0 +1,141 @@
+/*
+Copyright 2026 The kcp Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/kcp-dev/logicalcluster/v3"
+)
+
+// ClusterEventHandler is a typed cache.ResourceEventHandler that is told the
+// logical cluster of every object. Deletions are always passed the last
+// known state of the object; tombstones are unwrapped. Use
+// NewResourceEventHandler to register it with an informer.
+type ClusterEventHandler[T metav1.Object] interface {
+	OnAdd(clusterName logicalcluster.Name, obj T, isInInitialList bool)
+	OnUpdate(clusterName logicalcluster.Name, oldObj, newObj T)
+	OnDelete(clusterName logicalcluster.Name, obj T)
+}
+
+// ClusterEventHandlerFuncs is an adaptor to let you easily specify as many or
+// as few of the notification functions as you want while still implementing
+// ClusterEventHandler.
+type ClusterEventHandlerFuncs[T metav1.Object] struct {
+	AddFunc    func(clusterName logicalcluster.Name, obj T, isInInitialList bool)
+	UpdateFunc func(clusterName logicalcluster.Name, oldObj, newObj T)
+	DeleteFunc func(clusterName logicalcluster.Name, obj T)
+}
+
+// OnAdd calls AddFunc if it's not nil.
+func (f ClusterEventHandlerFuncs[T]) OnAdd(clusterName logicalcluster.Name, obj T, isInInitialList bool) {
+	if f.AddFunc != nil {
+		f.AddFunc(clusterName, obj, isInInitialList)
+	}
+}
+
+// OnUpdate calls UpdateFunc if it's not nil.
+func (f ClusterEventHandlerFuncs[T]) OnUpdate(clusterName logicalcluster.Name, oldObj, newObj T) {
+	if f.UpdateFunc != nil {
+		f.UpdateFunc(clusterName, oldObj, newObj)
+	}
+}
+
+// OnDelete calls DeleteFunc if it's not nil.
+func (f ClusterEventHandlerFuncs[T]) OnDelete(clusterName logicalcluster.Name, obj T) {
+	if f.DeleteFunc != nil {
+		f.DeleteFunc(clusterName, obj)
+	}
+}
+
+// NewResourceEventHandler adapts handler to a cache.ResourceEventHandler.
+// Objects that are not a T, and tombstones without an object, are reported
+// with utilruntime.HandleError and dropped. The cluster of a tombstone is read from its key, see
+// SplitMetaClusterNamespaceKey.
+func NewResourceEventHandler[T metav1.Object](handler ClusterEventHandler[T]) cache.ResourceEventHandler {
+	return &clusterEventHandler[T]{handler: handler}
+}
+
+type clusterEventHandler[T metav1.Object] struct {
+	handler ClusterEventHandler[T]
+}
+
+// Unwrap returns the typed handler, so that informers can name the handler
+// after it rather than after the adapter.
+func (h *clusterEventHandler[T]) Unwrap() any {
+	return h.handler
+}
+
+func (h *clusterEventHandler[T]) OnAdd(obj interface{}, isInInitialList bool) {
+	typed, ok := obj.(T)
+	if !ok {
+		utilruntime.HandleError(unexpectedObjectError[T](obj))
+		return
+	}
+	h.handler.OnAdd(logicalcluster.From(typed), typed, isInInitialList)
+}
+
+func (h *clusterEventHandler[T]) OnUpdate(oldObj, newObj interface{}) {
+	oldTyped, ok := oldObj.(T)
+	if !ok {
+		utilruntime.HandleError(unexpectedObjectError[T](oldObj))
+		return
+	}
+	newTyped, ok := newObj.(T)
+	if !ok {
+		utilruntime.HandleError(unexpectedObjectError[T](newObj))
+		return
+	}
+	h.handler.OnUpdate(logicalcluster.From(newTyped), oldTyped, newTyped)
+}
+
+func (h *clusterEventHandler[T]) OnDelete(obj interface{}) {
+	tombstone, ok := obj.(cache.DeletedFinalStateUnknown)
+	if !ok {
+		typed, ok := obj.(T)
+		if !ok {
+			utilruntime.HandleError(unexpectedObjectError[T](obj))
+			return
+		}
+		h.handler.OnDelete(logicalcluster.From(typed), typed)
+		return
+	}
+
+	if tombstone.Obj == nil {
+		utilruntime.HandleError(fmt.Errorf("tombstone for %q has no object", tombstone.Key))
+		return
+	}
+	typed, ok := tombstone.Obj.(T)
+	if !ok {
+		utilruntime.HandleError(unexpectedObjectError[T](tombstone.Obj))
+		return
+	}
+	_, clusterName, _, _, err := SplitMetaShardClusterNamespaceKey(tombstone.Key)
+	if err != nil || clusterName.Empty() {
+		clusterName = logicalcluster.From(typed)
+	}
+	h.handler.OnDelete(clusterName, typed)
+}
+
+func unexpectedObjectError[T any](obj interface{}) error {
+	var want T
+	return fmt.Errorf("unexpected object type %T, expected %T", obj, want)
+}
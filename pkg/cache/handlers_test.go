@@ -0,0 +1,86 @@
+/*
+Copyright 2026 The kcp Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/kcp-dev/logicalcluster/v3"
+)
+
+func TestNewResourceEventHandler(t *testing.T) {
+	type event struct {
+		kind    string
+		cluster logicalcluster.Name
+		name    string
+		initial bool
+	}
+	var events []event
+	handler := NewResourceEventHandler[*unstructured.Unstructured](ClusterEventHandlerFuncs[*unstructured.Unstructured]{
+		AddFunc: func(clusterName logicalcluster.Name, obj *unstructured.Unstructured, isInInitialList bool) {
+			events = append(events, event{kind: "add", cluster: clusterName, name: obj.GetName(), initial: isInInitialList})
+		},
+		UpdateFunc: func(clusterName logicalcluster.Name, oldObj, newObj *unstructured.Unstructured) {
+			events = append(events, event{kind: "update", cluster: clusterName, name: newObj.GetName()})
+		},
+		DeleteFunc: func(clusterName logicalcluster.Name, obj *unstructured.Unstructured) {
+			events = append(events, event{kind: "delete", cluster: clusterName, name: obj.GetName()})
+		},
+	})
+
+	handler.OnAdd(newUnstructured("c1", "ns1", "n1", nil), true)
+	handler.OnUpdate(newUnstructured("c1", "ns1", "n1", nil), newUnstructured("c1", "ns1", "n1", nil))
+	handler.OnDelete(newUnstructured("c1", "ns1", "n1", nil))
+	handler.OnDelete(cache.DeletedFinalStateUnknown{
+		Key: ToClusterAwareKey("c2", "ns1", "n2"),
+		Obj: newUnstructured("", "ns1", "n2", nil),
+	})
+	handler.OnDelete(cache.DeletedFinalStateUnknown{
+		Key: ToShardClusterAwareKey("shard", "c3", "ns1", "n3"),
+		Obj: newUnstructured("c3", "ns1", "n3", nil),
+	})
+
+	// Objects of the wrong type, and tombstones without an object, are
+	// reported and dropped.
+	var errs []error
+	handlers := utilruntime.ErrorHandlers
+	utilruntime.ErrorHandlers = []utilruntime.ErrorHandler{func(_ context.Context, err error, _ string, _ ...interface{}) {
+		errs = append(errs, err)
+	}}
+	t.Cleanup(func() { utilruntime.ErrorHandlers = handlers })
+	handler.OnAdd(&corev1.Pod{}, false)
+	handler.OnDelete(cache.DeletedFinalStateUnknown{Key: ToClusterAwareKey("c4", "ns1", "n4")})
+	require.Len(t, errs, 2)
+	require.EqualError(t, errs[0], "unexpected object type *v1.Pod, expected *unstructured.Unstructured")
+	require.EqualError(t, errs[1], `tombstone for "c4|ns1/n4" has no object`)
+
+	require.Equal(t, []event{
+		{kind: "add", cluster: "c1", name: "n1", initial: true},
+		{kind: "update", cluster: "c1", name: "n1"},
+		{kind: "delete", cluster: "c1", name: "n1"},
+		{kind: "delete", cluster: "c2", name: "n2"},
+		{kind: "delete", cluster: "c3", name: "n3"},
+	}, events)
+}
@@ -31,3 +31,108 @@ type ScopeableSharedIndexInformer interface {
 	ClusterWithContext(ctx context.Context, clusterName logicalcluster.Name) cache.SharedIndexInformer
 	cache.SharedIndexInformer
 }
+
+// SubtreeScoper is implemented by ScopeableSharedIndexInformers that can be
+// scoped down to a workspace subtree. Callers type-assert for it.
+type SubtreeScoper interface {
+	// Subtree scopes the informer down to the logical clusters at path and
+	// below it.
+	Subtree(path logicalcluster.Path) cache.SharedIndexInformer
+}
+
+// NamespaceScoper is implemented by the informers returned by
+// ScopeableSharedIndexInformer.Cluster and ClusterWithContext that can be
+// scoped further down to a namespace. Callers type-assert for it.
+type NamespaceScoper interface {
+	// Namespace scopes the informer down to namespace, like the namespace
+	// restricted informers of client-go. Its store and indexer only hold the
+	// objects of the namespace, see NewScopedIndexer. It returns an error if
+	// the informer spans several logical clusters, like the informers of
+	// ClusterSetScoper and SubtreeScoper.
+	Namespace(namespace string) (cache.SharedIndexInformer, error)
+}
+
+// ClusterSetScoper is implemented by ScopeableSharedIndexInformers that can
+// be scoped down to a set of logical clusters. Callers type-assert for it.
+type ClusterSetScoper interface {
+	// ClusterSet scopes the informer down to a set of logical clusters that
+	// can change at runtime.
+	ClusterSet(clusterNames ...logicalcluster.Name) ClusterSetInformer
+}
+
+// ClusterSetInformer is an informer scoped to a set of logical clusters.
+// Handlers get adds for the objects of clusters that are added to the set,
+// and deletes for the objects of clusters that are removed from it.
+type ClusterSetInformer interface {
+	cache.SharedIndexInformer
+	// Add adds clusters to the scope.
+	Add(clusterNames ...logicalcluster.Name)
+	// Remove removes clusters from the scope.
+	Remove(clusterNames ...logicalcluster.Name)
+	// Clusters returns the set of clusters in scope, e.g. for
+	// ClusterLister.ByClusterSet.
+	Clusters() *ClusterSet
+}
+
+// ClusterPurger is implemented by informers that can drop every object of a
+// logical cluster from their cache at once, e.g. after the cluster has been
+// deleted.
+type ClusterPurger interface {
+	PurgeCluster(clusterName logicalcluster.Name) error
+}
+
+// ClusterLifecycleHandler is notified when the first object of a logical
+// cluster appears in an informer's cache, and when the last one leaves it.
+type ClusterLifecycleHandler interface {
+	OnClusterAdded(clusterName logicalcluster.Name)
+	OnClusterRemoved(clusterName logicalcluster.Name)
+}
+
+// ClusterLifecycleHandlerFuncs is an adaptor to let you easily specify as many or
+// as few of the notification functions as you want while still implementing
+// ClusterLifecycleHandler.
+type ClusterLifecycleHandlerFuncs struct {
+	AddFunc    func(clusterName logicalcluster.Name)
+	RemoveFunc func(clusterName logicalcluster.Name)
+}
+
+// OnClusterAdded calls AddFunc if it's not nil.
+func (f ClusterLifecycleHandlerFuncs) OnClusterAdded(clusterName logicalcluster.Name) {
+	if f.AddFunc != nil {
+		f.AddFunc(clusterName)
+	}
+}
+
+// OnClusterRemoved calls RemoveFunc if it's not nil.
+func (f ClusterLifecycleHandlerFuncs) OnClusterRemoved(clusterName logicalcluster.Name) {
+	if f.RemoveFunc != nil {
+		f.RemoveFunc(clusterName)
+	}
+}
+
+// ClusterLifecycleNotifier is implemented by informers that can derive
+// logical cluster lifecycle events from the objects in their cache.
+type ClusterLifecycleNotifier interface {
+	// AddClusterLifecycleHandler registers handler. If the informer already
+	// holds objects, handler is first sent OnClusterAdded for every cluster
+	// currently present. The returned func removes the handler again.
+	AddClusterLifecycleHandler(handler ClusterLifecycleHandler) (remove func(), err error)
+}
+
+// MultiShardInformer is a ScopeableSharedIndexInformer that fans in the same
+// resource from several shards into one cache. Every shard has its own
+// reflector and resourceVersion space. Objects carry their shard in the
+// ShardAnnotationKey annotation, are indexed under ShardIndexName, and are
+// keyed with MetaShardClusterNamespaceKeyFunc. The indexer also resolves the
+// keys of MetaClusterNamespaceKeyFunc, see NewShardIndexer.
+type MultiShardInformer interface {
+	ScopeableSharedIndexInformer
+	// Shards returns the names of the shards, sorted.
+	Shards() []string
+	// ShardHasSynced reports whether the initial list of shard has been
+	// processed.
+	ShardHasSynced(shard string) bool
+	// ShardLastSyncResourceVersion returns the resource version last observed
+	// from shard.
+	ShardLastSyncResourceVersion(shard string) string
+}
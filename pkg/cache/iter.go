@@ -0,0 +1,133 @@
+/*
+Copyright 2026 The kcp Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"fmt"
+	"iter"
+	"slices"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/kcp-dev/logicalcluster/v3"
+)
+
+// All yields the objects of the indexer across all logical clusters that
+// match selector and fieldSelector, either of which may be nil to match
+// everything. Field selectors can select metadata.name, metadata.namespace
+// and the fields of the object's registered field extractor, see
+// RegisterFieldExtractor. Objects are asserted to T.
+//
+// Unlike cache.ListAll, no slice of the matching objects is built: only the
+// keys are read up front, and every object is looked up when it is yielded,
+// so iteration can be stopped early. Objects deleted in the meantime are
+// skipped. The iterator reads the indexer anew every time it is ranged over.
+func All[T any](indexer cache.Indexer, selector labels.Selector, fieldSelector fields.Selector) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		yieldMatching(indexer, indexer.ListKeys(), metav1.NamespaceAll, selector, fieldSelector, yield)
+	}
+}
+
+// AllByCluster is like All, but only yields the objects of clusterName. Like
+// ListAllByCluster, it falls back to scanning all objects if ClusterIndexName
+// is not registered. Registered field indexes are used for fieldSelector, see
+// ClusterFieldIndexFunc.
+func AllByCluster[T any](indexer cache.Indexer, clusterName logicalcluster.Name, selector labels.Selector, fieldSelector fields.Selector) iter.Seq[T] {
+	return allInCluster[T](indexer, clusterName, metav1.NamespaceAll, selector, fieldSelector)
+}
+
+// AllByClusterAndNamespace is like All, but only yields the objects of
+// clusterName in namespace. Like ListAllByClusterAndNamespace, it falls back
+// to scanning all objects if the index is not registered.
+func AllByClusterAndNamespace[T any](indexer cache.Indexer, clusterName logicalcluster.Name, namespace string, selector labels.Selector, fieldSelector fields.Selector) iter.Seq[T] {
+	return allInCluster[T](indexer, clusterName, namespace, selector, fieldSelector)
+}
+
+func allInCluster[T any](indexer cache.Indexer, clusterName logicalcluster.Name, namespace string, selector labels.Selector, fieldSelector fields.Selector) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		if indexName, indexKey, ok := fieldIndexFor(indexer, clusterName, fieldSelector); ok {
+			if keys, err := indexer.IndexKeys(indexName, indexKey); err == nil {
+				// The field index spans all namespaces of the cluster.
+				yieldMatching(indexer, keys, namespace, selector, fieldSelector, yield)
+				return
+			}
+		}
+
+		indexName, indexKey, indexFunc := ClusterIndexName, ClusterIndexKey(clusterName), ClusterIndexFunc
+		if namespace != metav1.NamespaceAll {
+			indexName, indexKey, indexFunc = ClusterAndNamespaceIndexName, ClusterAndNamespaceIndexKey(clusterName, namespace), ClusterAndNamespaceIndexFunc
+		}
+		keys, err := indexer.IndexKeys(indexName, indexKey)
+		if err == nil {
+			yieldMatching(indexer, keys, metav1.NamespaceAll, selector, fieldSelector, yield)
+			return
+		}
+
+		indexFallbackMetric(indexName).Inc()
+		yieldMatching(indexer, indexer.ListKeys(), metav1.NamespaceAll, selector, fieldSelector, func(item T) bool {
+			indexKeys, err := indexFunc(item)
+			if err != nil {
+				// The iterator cannot return the error, and the object
+				// cannot be matched without its index keys.
+				utilruntime.HandleError(fmt.Errorf("failed to compute %s of %T: %w", indexName, item, err))
+				return true
+			}
+			if !slices.Contains(indexKeys, indexKey) {
+				return true
+			}
+			return yield(item)
+		})
+	}
+}
+
+// yieldMatching looks up the objects of keys and yields those in namespace,
+// unless it is empty, that match selector and fieldSelector until yield
+// returns false. Objects without object metadata only match if namespace
+// and both selectors are empty.
+func yieldMatching[T any](indexer cache.Indexer, keys []string, namespace string, selector labels.Selector, fieldSelector fields.Selector, yield func(T) bool) {
+	selectAll := selector == nil || selector.Empty()
+	selectAllFields := fieldSelector == nil || fieldSelector.Empty()
+	for _, key := range keys {
+		item, exists, err := indexer.GetByKey(key)
+		if err != nil || !exists {
+			continue
+		}
+		if !selectAll || !selectAllFields || namespace != metav1.NamespaceAll {
+			metadata, err := meta.Accessor(item)
+			if err != nil {
+				continue
+			}
+			if namespace != metav1.NamespaceAll && metadata.GetNamespace() != namespace {
+				continue
+			}
+			if !selectAll && !selector.Matches(labels.Set(metadata.GetLabels())) {
+				continue
+			}
+			if !selectAllFields && !matchesFields(item, fieldSelector) {
+				continue
+			}
+		}
+		if !yield(item.(T)) {
+			return
+		}
+	}
+}
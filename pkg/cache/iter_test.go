@@ -0,0 +1,199 @@
+/*
+Copyright 2026 The kcp Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"context"
+	"iter"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/tools/cache"
+)
+
+func keysOf(t *testing.T, seq iter.Seq[*unstructured.Unstructured]) []string {
+	t.Helper()
+	keys := []string{}
+	for obj := range seq {
+		key, err := MetaClusterNamespaceKeyFunc(obj)
+		require.NoError(t, err)
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+func TestAll(t *testing.T) {
+	indexer := newTestIndexer(t)
+	unindexed := cache.NewIndexer(MetaClusterNamespaceKeyFunc, cache.Indexers{})
+	for _, obj := range indexer.List() {
+		require.NoError(t, unindexed.Add(obj))
+	}
+
+	tests := map[string]struct {
+		seq  func(indexer cache.Indexer) iter.Seq[*unstructured.Unstructured]
+		want []string
+	}{
+		"all": {
+			seq: func(indexer cache.Indexer) iter.Seq[*unstructured.Unstructured] {
+				return All[*unstructured.Unstructured](indexer, nil, nil)
+			},
+			want: []string{"c1|cn1", "c1|cn2", "c1|ns1/n1", "c1|ns2/n1", "c1|ns2/n2", "c2|cn1", "c2|cn2", "c2|ns1/n1", "c2|ns2/n1", "c2|ns2/n2"},
+		},
+		"all by name": {
+			seq: func(indexer cache.Indexer) iter.Seq[*unstructured.Unstructured] {
+				return All[*unstructured.Unstructured](indexer, labels.Everything(), fields.OneTermEqualSelector("metadata.name", "n1"))
+			},
+			want: []string{"c1|ns1/n1", "c1|ns2/n1", "c2|ns1/n1", "c2|ns2/n1"},
+		},
+		"cluster with labels": {
+			seq: func(indexer cache.Indexer) iter.Seq[*unstructured.Unstructured] {
+				return AllByCluster[*unstructured.Unstructured](indexer, "c1", labels.Set{"app": "myapp"}.AsSelector(), nil)
+			},
+			want: []string{"c1|cn1", "c1|ns1/n1", "c1|ns2/n1"},
+		},
+		"cluster by namespace field": {
+			seq: func(indexer cache.Indexer) iter.Seq[*unstructured.Unstructured] {
+				return AllByCluster[*unstructured.Unstructured](indexer, "c2", nil, fields.OneTermEqualSelector("metadata.namespace", "ns2"))
+			},
+			want: []string{"c2|ns2/n1", "c2|ns2/n2"},
+		},
+		"cluster and namespace": {
+			seq: func(indexer cache.Indexer) iter.Seq[*unstructured.Unstructured] {
+				return AllByClusterAndNamespace[*unstructured.Unstructured](indexer, "c2", "ns2", nil, fields.OneTermNotEqualSelector("metadata.name", "n1"))
+			},
+			want: []string{"c2|ns2/n2"},
+		},
+		"cluster and all namespaces": {
+			seq: func(indexer cache.Indexer) iter.Seq[*unstructured.Unstructured] {
+				return AllByClusterAndNamespace[*unstructured.Unstructured](indexer, "c1", "", labels.Set{"app": "myapp"}.AsSelector(), nil)
+			},
+			want: []string{"c1|cn1", "c1|ns1/n1", "c1|ns2/n1"},
+		},
+		"unknown cluster": {
+			seq: func(indexer cache.Indexer) iter.Seq[*unstructured.Unstructured] {
+				return AllByCluster[*unstructured.Unstructured](indexer, "c3", nil, nil)
+			},
+			want: []string{},
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			require.ElementsMatch(t, tt.want, keysOf(t, tt.seq(indexer)))
+			require.ElementsMatch(t, tt.want, keysOf(t, tt.seq(unindexed)), "without indexes")
+		})
+	}
+}
+
+func TestAllStopsEarly(t *testing.T) {
+	indexer := newTestIndexer(t)
+
+	var seen int
+	for range AllByCluster[runtime.Object](indexer, "c1", nil, nil) {
+		seen++
+		if seen == 2 {
+			break
+		}
+	}
+	require.Equal(t, 2, seen)
+}
+
+// readCountingIndexer counts the objects read from an indexer.
+type readCountingIndexer struct {
+	cache.Indexer
+	gets, lists int
+}
+
+func (i *readCountingIndexer) GetByKey(key string) (interface{}, bool, error) {
+	i.gets++
+	return i.Indexer.GetByKey(key)
+}
+
+func (i *readCountingIndexer) List() []interface{} {
+	i.lists++
+	return i.Indexer.List()
+}
+
+func (i *readCountingIndexer) ByIndex(indexName, indexedValue string) ([]interface{}, error) {
+	i.lists++
+	return i.Indexer.ByIndex(indexName, indexedValue)
+}
+
+func TestAllReadsObjectsLazily(t *testing.T) {
+	unindexed := cache.NewIndexer(MetaClusterNamespaceKeyFunc, cache.Indexers{})
+	for _, obj := range newTestIndexer(t).List() {
+		require.NoError(t, unindexed.Add(obj))
+	}
+
+	for name, indexer := range map[string]cache.Indexer{"indexed": newTestIndexer(t), "unindexed": unindexed} {
+		for seqName, seq := range map[string]func(cache.Indexer) iter.Seq[runtime.Object]{
+			"all": func(indexer cache.Indexer) iter.Seq[runtime.Object] {
+				return All[runtime.Object](indexer, nil, nil)
+			},
+			"cluster": func(indexer cache.Indexer) iter.Seq[runtime.Object] {
+				return AllByCluster[runtime.Object](indexer, "c1", nil, nil)
+			},
+		} {
+			t.Run(name+"/"+seqName, func(t *testing.T) {
+				counting := &readCountingIndexer{Indexer: indexer}
+				var seen int
+				for range seq(counting) {
+					seen++
+					if seen == 2 {
+						break
+					}
+				}
+				require.Equal(t, 2, seen)
+				require.Zero(t, counting.lists, "no slice of objects is built")
+				if name == "indexed" {
+					require.Equal(t, 2, counting.gets, "objects after the break are not read")
+				}
+			})
+		}
+	}
+}
+
+func TestAllByClusterReportsIndexErrors(t *testing.T) {
+	indexer := cache.NewIndexer(func(obj interface{}) (string, error) {
+		if s, ok := obj.(string); ok {
+			return s, nil
+		}
+		return MetaClusterNamespaceKeyFunc(obj)
+	}, cache.Indexers{})
+	require.NoError(t, indexer.Add(newUnstructured("c1", "ns1", "n1", nil)))
+	require.NoError(t, indexer.Add("no metadata"))
+
+	var errs []error
+	handlers := utilruntime.ErrorHandlers
+	utilruntime.ErrorHandlers = []utilruntime.ErrorHandler{func(_ context.Context, err error, _ string, _ ...interface{}) {
+		errs = append(errs, err)
+	}}
+	t.Cleanup(func() { utilruntime.ErrorHandlers = handlers })
+
+	var keys []string
+	for obj := range AllByCluster[any](indexer, "c1", nil, nil) {
+		keys = append(keys, obj.(*unstructured.Unstructured).GetName())
+	}
+	require.Equal(t, []string{"n1"}, keys)
+	require.Len(t, errs, 1)
+	require.ErrorContains(t, errs[0], ClusterIndexName)
+}
@@ -28,10 +28,15 @@ import (
 
 // DeletionHandlingMetaClusterNamespaceKeyFunc checks for
 // DeletedFinalStateUnknown objects before calling
-// MetaClusterNamespaceKeyFunc.
+// MetaClusterNamespaceKeyFunc. The shard of tombstones keyed with
+// MetaShardClusterNamespaceKeyFunc is dropped from the key.
 func DeletionHandlingMetaClusterNamespaceKeyFunc(obj interface{}) (string, error) {
 	if d, ok := obj.(cache.DeletedFinalStateUnknown); ok {
-		return d.Key, nil
+		shard, clusterName, namespace, name, err := SplitMetaShardClusterNamespaceKey(d.Key)
+		if err != nil || shard == "" {
+			return d.Key, nil
+		}
+		return ToClusterAwareKey(clusterName.String(), namespace, name), nil
 	}
 	return MetaClusterNamespaceKeyFunc(obj)
 }
@@ -40,7 +45,7 @@ func DeletionHandlingMetaClusterNamespaceKeyFunc(obj interface{}) (string, error
 // keys for API objects which implement meta.Interface.
 // The key uses the format <clusterName>|<namespace>/<name> unless <namespace> is empty, then
 // it's just <clusterName>|<name>, and if running in a single-cluster context where no explicit
-// cluster name is given, it's just <name>.
+// cluster name is given, it's just <name>. See ClusterKey for how the components are escaped.
 func MetaClusterNamespaceKeyFunc(obj interface{}) (string, error) {
 	if key, ok := obj.(cache.ExplicitKey); ok {
 		return string(key), nil
@@ -49,41 +54,119 @@ func MetaClusterNamespaceKeyFunc(obj interface{}) (string, error) {
 	if err != nil {
 		return "", fmt.Errorf("object has no meta: %v", err)
 	}
-	return ToClusterAwareKey(logicalcluster.From(meta).String(), meta.GetNamespace(), meta.GetName()), nil
+	return ClusterKey{Cluster: logicalcluster.From(meta), Namespace: meta.GetNamespace(), Name: meta.GetName()}.String(), nil
 }
 
 // ToClusterAwareKey formats a cluster, namespace, and name as a key.
 func ToClusterAwareKey(cluster, namespace, name string) string {
-	var key string
-	if cluster != "" {
-		key += cluster + "|"
-	}
-	if namespace != "" {
-		key += namespace + "/"
-	}
-	key += name
-	return key
+	return ClusterKey{Cluster: logicalcluster.Name(cluster), Namespace: namespace, Name: name}.String()
 }
 
 // SplitMetaClusterNamespaceKey returns the namespace and name that
-// MetaClusterNamespaceKeyFunc encoded into key.
+// MetaClusterNamespaceKeyFunc encoded into key. Unlike ParseClusterKey, it
+// does not validate the components.
 func SplitMetaClusterNamespaceKey(key string) (clusterName logicalcluster.Name, namespace, name string, err error) {
 	invalidKey := fmt.Errorf("unexpected key format: %q", key)
 	outerParts := strings.Split(key, "|")
 	switch len(outerParts) {
 	case 1:
-		namespace, name, err := cache.SplitMetaNamespaceKey(outerParts[0])
+		namespace, name, err := splitEscapedNamespaceKey(outerParts[0])
 		if err != nil {
 			err = invalidKey
 		}
 		return "", namespace, name, err
 	case 2:
-		namespace, name, err := cache.SplitMetaNamespaceKey(outerParts[1])
+		cluster, cerr := unescapeKeyComponent(outerParts[0])
+		if cerr != nil {
+			return "", "", "", invalidKey
+		}
+		namespace, name, err := splitEscapedNamespaceKey(outerParts[1])
 		if err != nil {
 			err = invalidKey
 		}
-		return logicalcluster.Name(outerParts[0]), namespace, name, err
+		return logicalcluster.Name(cluster), namespace, name, err
 	default:
 		return "", "", "", invalidKey
 	}
 }
+
+// splitEscapedNamespaceKey splits a <namespace>/<name> key like
+// cache.SplitMetaNamespaceKey and unescapes both parts.
+func splitEscapedNamespaceKey(key string) (namespace, name string, err error) {
+	namespace, name, err = cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		return "", "", err
+	}
+	if namespace, err = unescapeKeyComponent(namespace); err != nil {
+		return "", "", err
+	}
+	if name, err = unescapeKeyComponent(name); err != nil {
+		return "", "", err
+	}
+	return namespace, name, nil
+}
+
+// ShardAnnotationKey is the annotation holding the name of the shard an object
+// was read from. It is set on every object by multi-shard informers.
+const ShardAnnotationKey = "kcp.io/shard"
+
+// DeletionHandlingMetaShardClusterNamespaceKeyFunc checks for
+// DeletedFinalStateUnknown objects before calling
+// MetaShardClusterNamespaceKeyFunc.
+func DeletionHandlingMetaShardClusterNamespaceKeyFunc(obj interface{}) (string, error) {
+	if d, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		return d.Key, nil
+	}
+	return MetaShardClusterNamespaceKeyFunc(obj)
+}
+
+// MetaShardClusterNamespaceKeyFunc is like MetaClusterNamespaceKeyFunc, but
+// qualifies the key with the shard from the ShardAnnotationKey annotation.
+// The key uses the format <shard>|<clusterName>|<namespace>/<name>. Objects
+// without the annotation get the same key as from MetaClusterNamespaceKeyFunc.
+func MetaShardClusterNamespaceKeyFunc(obj interface{}) (string, error) {
+	if key, ok := obj.(cache.ExplicitKey); ok {
+		return string(key), nil
+	}
+	meta, err := meta.Accessor(obj)
+	if err != nil {
+		return "", fmt.Errorf("object has no meta: %v", err)
+	}
+	return ToShardClusterAwareKey(meta.GetAnnotations()[ShardAnnotationKey], logicalcluster.From(meta).String(), meta.GetNamespace(), meta.GetName()), nil
+}
+
+// ToShardClusterAwareKey formats a shard, cluster, namespace, and name as a key.
+// The shard is only included together with a cluster, and an empty shard
+// gives the same key as ToClusterAwareKey. The shard is escaped like the
+// components of a ClusterKey.
+func ToShardClusterAwareKey(shard, cluster, namespace, name string) string {
+	key := ToClusterAwareKey(cluster, namespace, name)
+	if shard != "" && cluster != "" {
+		key = escapeKeyComponent(shard) + "|" + key
+	}
+	return key
+}
+
+// SplitMetaShardClusterNamespaceKey returns the shard, cluster, namespace and
+// name that MetaShardClusterNamespaceKeyFunc encoded into key. Keys without a
+// shard are split like SplitMetaClusterNamespaceKey does, with an empty shard.
+func SplitMetaShardClusterNamespaceKey(key string) (shard string, clusterName logicalcluster.Name, namespace, name string, err error) {
+	parts := strings.SplitN(key, "|", 3)
+	if len(parts) < 3 {
+		clusterName, namespace, name, err = SplitMetaClusterNamespaceKey(key)
+		return "", clusterName, namespace, name, err
+	}
+	if parts[0] == "" || parts[1] == "" || strings.Contains(parts[2], "|") {
+		return "", "", "", "", fmt.Errorf("unexpected key format: %q", key)
+	}
+	shard, serr := unescapeKeyComponent(parts[0])
+	cluster, cerr := unescapeKeyComponent(parts[1])
+	if serr != nil || cerr != nil {
+		return "", "", "", "", fmt.Errorf("unexpected key format: %q", key)
+	}
+	namespace, name, err = splitEscapedNamespaceKey(parts[2])
+	if err != nil {
+		return shard, logicalcluster.Name(cluster), "", "", fmt.Errorf("unexpected key format: %q", key)
+	}
+	return shard, logicalcluster.Name(cluster), namespace, name, nil
+}
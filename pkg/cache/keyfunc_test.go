@@ -59,6 +59,13 @@ func TestDeletionHandlingMetaClusterNamespaceKeyFunc(t *testing.T) {
 			},
 			expected: "whatever",
 		},
+		{
+			name: "tombstone with shard",
+			obj: cache.DeletedFinalStateUnknown{
+				Key: "shard|cluster|namespace/name",
+			},
+			expected: "cluster|namespace/name",
+		},
 	}
 	for _, testCase := range testCases {
 		t.Run(testCase.name, func(t *testing.T) {
@@ -227,3 +234,157 @@ var (
 		return xe.Error() == ye.Error()
 	}))
 )
+
+func TestMetaShardClusterNamespaceKeyFunc(t *testing.T) {
+	var testCases = []struct {
+		name        string
+		obj         interface{}
+		expected    string
+		expectedErr error
+	}{
+		{
+			name: "normal object",
+			obj: &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace:   "namespace",
+					Name:        "name",
+					Annotations: map[string]string{logicalcluster.AnnotationKey: "cluster", ShardAnnotationKey: "shard"},
+				},
+			},
+			expected: "shard|cluster|namespace/name",
+		},
+		{
+			name: "object without namespace",
+			obj: &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "name",
+					Annotations: map[string]string{logicalcluster.AnnotationKey: "cluster", ShardAnnotationKey: "shard"},
+				},
+			},
+			expected: "shard|cluster|name",
+		},
+		{
+			name: "object without shard",
+			obj: &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace:   "namespace",
+					Name:        "name",
+					Annotations: map[string]string{logicalcluster.AnnotationKey: "cluster"},
+				},
+			},
+			expected: "cluster|namespace/name",
+		},
+		{
+			name: "object without cluster",
+			obj: &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace:   "namespace",
+					Name:        "name",
+					Annotations: map[string]string{ShardAnnotationKey: "shard"},
+				},
+			},
+			expected: "namespace/name",
+		},
+		{
+			name:        "invalid object",
+			obj:         "invalid",
+			expectedErr: errors.New("object has no meta: object does not implement the Object interfaces"),
+		},
+		{
+			name: "tombstone",
+			obj: cache.DeletedFinalStateUnknown{
+				Key: "whatever",
+			},
+			expected: "whatever",
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			actual, actualErr := DeletionHandlingMetaShardClusterNamespaceKeyFunc(testCase.obj)
+			if diff := cmp.Diff(actualErr, testCase.expectedErr, equateErrorMessage); diff != "" {
+				t.Errorf("%s: invalid error: %v", testCase.name, diff)
+				return
+			}
+			if diff := cmp.Diff(actual, testCase.expected); diff != "" {
+				t.Errorf("%s: invalid key: %v", testCase.name, diff)
+			}
+		})
+	}
+}
+
+func TestSplitMetaShardClusterNamespaceKey(t *testing.T) {
+	var testCases = []struct {
+		name              string
+		key               string
+		expectedShard     string
+		expectedCluster   logicalcluster.Name
+		expectedNamespace string
+		expectedName      string
+		expectedErr       error
+	}{
+		{
+			name:              "fully populated key",
+			key:               "shard|clusterName|namespace/name",
+			expectedShard:     "shard",
+			expectedCluster:   logicalcluster.Name("clusterName"),
+			expectedNamespace: "namespace",
+			expectedName:      "name",
+		},
+		{
+			name:            "cluster-scoped resource",
+			key:             "shard|clusterName|name",
+			expectedShard:   "shard",
+			expectedCluster: logicalcluster.Name("clusterName"),
+			expectedName:    "name",
+		},
+		{
+			name:              "key without shard",
+			key:               "clusterName|namespace/name",
+			expectedCluster:   logicalcluster.Name("clusterName"),
+			expectedNamespace: "namespace",
+			expectedName:      "name",
+		},
+		{
+			name:         "single-cluster, cluster-scoped context",
+			key:          "name",
+			expectedName: "name",
+		},
+		{
+			name:        "too many separators",
+			key:         "shard|cluster|extra|name",
+			expectedErr: errors.New(`unexpected key format: "shard|cluster|extra|name"`),
+		},
+		{
+			name:        "empty shard",
+			key:         "|cluster|name",
+			expectedErr: errors.New(`unexpected key format: "|cluster|name"`),
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			actualShard, actualCluster, actualNamespace, actualName, actualErr := SplitMetaShardClusterNamespaceKey(testCase.key)
+			if diff := cmp.Diff(actualErr, testCase.expectedErr, equateErrorMessage); diff != "" {
+				t.Errorf("%s: invalid error: %v", testCase.name, diff)
+				return
+			}
+			if diff := cmp.Diff(actualShard, testCase.expectedShard); diff != "" {
+				t.Errorf("%s: invalid shard: %v", testCase.name, diff)
+			}
+			if diff := cmp.Diff(actualCluster, testCase.expectedCluster); diff != "" {
+				t.Errorf("%s: invalid cluster: %v", testCase.name, diff)
+			}
+			if diff := cmp.Diff(actualNamespace, testCase.expectedNamespace); diff != "" {
+				t.Errorf("%s: invalid namespace: %v", testCase.name, diff)
+			}
+			if diff := cmp.Diff(actualName, testCase.expectedName); diff != "" {
+				t.Errorf("%s: invalid name: %v", testCase.name, diff)
+			}
+			if testCase.expectedErr == nil {
+				roundTrip := ToShardClusterAwareKey(actualShard, actualCluster.String(), actualNamespace, actualName)
+				if diff := cmp.Diff(roundTrip, testCase.key); diff != "" {
+					t.Errorf("%s: key does not round-trip: %v", testCase.name, diff)
+				}
+			}
+		})
+	}
+}
@@ -17,69 +17,150 @@ limitations under the License.
 package cache
 
 import (
-	"k8s.io/apimachinery/pkg/api/errors"
+	"errors"
+	"fmt"
+	"slices"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
-	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/client-go/tools/cache"
-	"k8s.io/klog/v2"
 
 	"github.com/kcp-dev/logicalcluster/v3"
 )
 
+// ErrIndexNotRegistered is returned by the strict list functions and listers
+// if the index they need is not registered on the indexer.
+var ErrIndexNotRegistered = errors.New("index is not registered")
+
 // ListAllByCluster used to list items belongs to a cluster from Indexer.
+//
+// If ClusterIndexName is not registered, all items of the indexer are
+// scanned, which is counted by the metric of SetListerMetricsProvider.
 func ListAllByCluster(indexer cache.Indexer, clusterName logicalcluster.Name, selector labels.Selector, appendFn cache.AppendFunc) error {
-	return listAllByIndexWithBackup(indexer, ClusterIndexName, ClusterIndexKey(clusterName), ClusterIndexFunc, selector, appendFn)
+	return listAllInCluster(indexer, clusterName, metav1.NamespaceAll, false, selector, nil, appendFn)
+}
+
+// ListAllByClusterStrict is like ListAllByCluster, but returns
+// ErrIndexNotRegistered instead of scanning all items if ClusterIndexName is
+// not registered.
+func ListAllByClusterStrict(indexer cache.Indexer, clusterName logicalcluster.Name, selector labels.Selector, appendFn cache.AppendFunc) error {
+	return listAllInCluster(indexer, clusterName, metav1.NamespaceAll, true, selector, nil, appendFn)
 }
 
 // ListAllByClusterAndNamespace used to list items belongs to a cluster and namespace from Indexer.
+//
+// If ClusterAndNamespaceIndexName is not registered, all items of the
+// indexer are scanned, which is counted by the metric of
+// SetListerMetricsProvider.
 func ListAllByClusterAndNamespace(indexer cache.Indexer, clusterName logicalcluster.Name, namespace string, selector labels.Selector, appendFn cache.AppendFunc) error {
-	if namespace == metav1.NamespaceAll {
-		return ListAllByCluster(indexer, clusterName, selector, appendFn)
-	}
-	return listAllByIndexWithBackup(indexer, ClusterAndNamespaceIndexName, ClusterAndNamespaceIndexKey(clusterName, namespace), ClusterAndNamespaceIndexFunc, selector, appendFn)
+	return listAllByClusterAndNamespace(indexer, clusterName, namespace, false, selector, appendFn)
+}
+
+// ListAllByClusterAndNamespaceStrict is like ListAllByClusterAndNamespace,
+// but returns ErrIndexNotRegistered instead of scanning all items if the
+// index is not registered.
+func ListAllByClusterAndNamespaceStrict(indexer cache.Indexer, clusterName logicalcluster.Name, namespace string, selector labels.Selector, appendFn cache.AppendFunc) error {
+	return listAllByClusterAndNamespace(indexer, clusterName, namespace, true, selector, appendFn)
 }
 
-// listAllByIndexWithBackup used to list items from the Indexer using an index, or falling back to a func if no index is registered.
-func listAllByIndexWithBackup(indexer cache.Indexer, indexName, indexKey string, indexFunc cache.IndexFunc, selector labels.Selector, appendFn cache.AppendFunc) error {
-	var items []interface{}
-	var err error
-	items, err = indexer.ByIndex(indexName, indexKey)
+func listAllByClusterAndNamespace(indexer cache.Indexer, clusterName logicalcluster.Name, namespace string, strict bool, selector labels.Selector, appendFn cache.AppendFunc) error {
+	return listAllInCluster(indexer, clusterName, namespace, strict, selector, nil, appendFn)
+}
+
+// listAllInCluster used to list items of a cluster and namespace, or all
+// namespaces if namespace is empty, that match selector and fieldSelector.
+func listAllInCluster(indexer cache.Indexer, clusterName logicalcluster.Name, namespace string, strict bool, selector labels.Selector, fieldSelector fields.Selector, appendFn cache.AppendFunc) error {
+	items, err := clusterItems(indexer, clusterName, namespace, fieldSelector, strict)
 	if err != nil {
-		// Ignore error; do slow search without index.
-		klog.Warningf("can not retrieve list of objects using index : %v", err)
-		for _, item := range indexer.List() {
-			keys, err := indexFunc(item)
-			if err != nil {
-				return err
-			}
-			if sets.NewString(keys...).Has(indexKey) {
-				items = append(items, item)
+		return err
+	}
+	return appendMatchingObjects(items, selector, fieldSelector, appendFn)
+}
+
+// clusterItems returns the items of a cluster and namespace, or all
+// namespaces if namespace is empty. If a field index is registered for an
+// equality requirement of fieldSelector, only the items it finds are
+// returned; the selector must still be matched against them.
+func clusterItems(indexer cache.Indexer, clusterName logicalcluster.Name, namespace string, fieldSelector fields.Selector, strict bool) ([]interface{}, error) {
+	if items, ok := byFieldIndex(indexer, clusterName, fieldSelector); ok {
+		if namespace == metav1.NamespaceAll {
+			return items, nil
+		}
+		inNamespace := items[:0:0]
+		for _, item := range items {
+			if metadata, err := meta.Accessor(item); err == nil && metadata.GetNamespace() == namespace {
+				inNamespace = append(inNamespace, item)
 			}
 		}
+		return inNamespace, nil
 	}
-	return appendMatchingObjects(items, selector, appendFn)
+
+	if namespace == metav1.NamespaceAll {
+		return byIndexWithBackup(indexer, ClusterIndexName, ClusterIndexKey(clusterName), ClusterIndexFunc, strict)
+	}
+	return byIndexWithBackup(indexer, ClusterAndNamespaceIndexName, ClusterAndNamespaceIndexKey(clusterName, namespace), ClusterAndNamespaceIndexFunc, strict)
+}
+
+// byIndexWithBackup returns the items with indexKey in index indexName. If
+// the index is not registered, it fails if strict is set, or else falls back
+// to scanning all items with indexFunc.
+func byIndexWithBackup(indexer cache.Indexer, indexName, indexKey string, indexFunc cache.IndexFunc, strict bool) ([]interface{}, error) {
+	items, err := indexer.ByIndex(indexName, indexKey)
+	if err == nil {
+		return items, nil
+	}
+	if strict {
+		return nil, fmt.Errorf("%w: %s", ErrIndexNotRegistered, indexName)
+	}
+
+	indexFallbackMetric(indexName).Inc()
+	items = nil
+	for _, item := range indexer.List() {
+		keys, err := indexFunc(item)
+		if err != nil {
+			return nil, err
+		}
+		if slices.Contains(keys, indexKey) {
+			items = append(items, item)
+		}
+	}
+	return items, nil
 }
 
-func appendMatchingObjects(items []interface{}, selector labels.Selector, appendFn cache.AppendFunc) error {
+func appendMatchingObjects(items []interface{}, selector labels.Selector, fieldSelector fields.Selector, appendFn cache.AppendFunc) error {
 	selectAll := selector == nil || selector.Empty()
+	selectAllFields := fieldSelector == nil || fieldSelector.Empty()
 	for _, item := range items {
-		if selectAll {
+		if selectAll && selectAllFields {
 			// Avoid computing labels of the objects to speed up common flows
 			// of listing all objects.
 			appendFn(item)
 			continue
 		}
-		metadata, err := meta.Accessor(item)
-		if err != nil {
-			return err
+		if !selectAll {
+			metadata, err := meta.Accessor(item)
+			if err != nil {
+				return err
+			}
+			if !selector.Matches(labels.Set(metadata.GetLabels())) {
+				continue
+			}
 		}
-		if selector.Matches(labels.Set(metadata.GetLabels())) {
-			appendFn(item)
+		if !selectAllFields {
+			set, err := ObjectFields(item)
+			if err != nil {
+				return err
+			}
+			if !fieldSelector.Matches(set) {
+				continue
+			}
 		}
+		appendFn(item)
 	}
 
 	return nil
@@ -93,6 +174,18 @@ func NewGenericClusterLister(indexer cache.Indexer, resource schema.GroupResourc
 	}
 }
 
+// NewStrictGenericClusterLister is like NewGenericClusterLister, but the
+// listers it returns fail with ErrIndexNotRegistered instead of scanning the
+// whole indexer if ClusterIndexName or ClusterAndNamespaceIndexName is not
+// registered.
+func NewStrictGenericClusterLister(indexer cache.Indexer, resource schema.GroupResource) *ClusterLister {
+	return &ClusterLister{
+		indexer:  indexer,
+		resource: resource,
+		strict:   true,
+	}
+}
+
 // GenericClusterLister is a lister that can either list all objects across all logical clusters, or
 // scope down to a lister for one logical cluster only.
 type GenericClusterLister interface {
@@ -102,12 +195,43 @@ type GenericClusterLister interface {
 	ByCluster(clusterName logicalcluster.Name) cache.GenericLister
 }
 
+// ClusterScopeLister is implemented by the GenericClusterListers of this
+// package, which can additionally be scoped by workspace path and to sets of
+// logical clusters.
+type ClusterScopeLister interface {
+	// ByClusterPath will give you a cache.GenericLister for the logical cluster at a path
+	ByClusterPath(path logicalcluster.Path) cache.GenericLister
+	// BySubtree will give you a cache.GenericLister for the logical clusters at a path and below it
+	BySubtree(path logicalcluster.Path) cache.GenericLister
+	// ByClusterSet will give you a cache.GenericLister for the logical clusters in a set
+	ByClusterSet(set *ClusterSet) cache.GenericLister
+}
+
+// FieldSelectorLister is implemented by the listers of this package, which
+// can additionally filter by field selectors. Field selectors can select
+// metadata.name, metadata.namespace and the fields of the object's
+// registered field extractor, see RegisterFieldExtractor.
+type FieldSelectorLister interface {
+	// ListWithFields will return the objects that match selector and
+	// fieldSelector, either of which may be nil to match everything.
+	ListWithFields(selector labels.Selector, fieldSelector fields.Selector) (ret []runtime.Object, err error)
+}
+
 // ClusterLister is a lister that supports multiple logical clusters. It can list the entire contents of the backing store, and return individual cache.GenericListers that are scoped to individual logical clusters.
+//
+// Listers read the indexer as it is. To stop serving the objects of evicted
+// logical clusters, create the informer with PurgeOnClusterDeletion, which
+// removes them from the indexer when kcpclient.EvictCluster is called.
 type ClusterLister struct {
-	indexer  cache.Indexer
-	resource schema.GroupResource
+	indexer      cache.Indexer
+	resource     schema.GroupResource
+	strict       bool
+	pathResolver ClusterPathResolver
 }
 
+var _ GenericClusterLister = &ClusterLister{}
+var _ ClusterScopeLister = &ClusterLister{}
+
 func (s *ClusterLister) List(selector labels.Selector) (ret []runtime.Object, err error) {
 	if selector == nil {
 		selector = labels.NewSelector()
@@ -118,11 +242,21 @@ func (s *ClusterLister) List(selector labels.Selector) (ret []runtime.Object, er
 	return ret, err
 }
 
+// ListWithFields lists the objects across logical clusters and namespaces
+// that match selector and fieldSelector.
+func (s *ClusterLister) ListWithFields(selector labels.Selector, fieldSelector fields.Selector) (ret []runtime.Object, err error) {
+	err = appendMatchingObjects(s.indexer.List(), selector, fieldSelector, func(m interface{}) {
+		ret = append(ret, m.(runtime.Object))
+	})
+	return ret, err
+}
+
 func (s *ClusterLister) ByCluster(clusterName logicalcluster.Name) cache.GenericLister {
 	return &genericLister{
 		indexer:     s.indexer,
 		resource:    s.resource,
 		clusterName: clusterName,
+		strict:      s.strict,
 	}
 }
 
@@ -130,10 +264,20 @@ type genericLister struct {
 	indexer     cache.Indexer
 	clusterName logicalcluster.Name
 	resource    schema.GroupResource
+	strict      bool
 }
 
 func (s *genericLister) List(selector labels.Selector) (ret []runtime.Object, err error) {
-	err = ListAllByCluster(s.indexer, s.clusterName, selector, func(i interface{}) {
+	err = listAllInCluster(s.indexer, s.clusterName, metav1.NamespaceAll, s.strict, selector, nil, func(i interface{}) {
+		ret = append(ret, i.(runtime.Object))
+	})
+	return ret, err
+}
+
+// ListWithFields lists the objects of the cluster that match selector and
+// fieldSelector, using a field index where registered.
+func (s *genericLister) ListWithFields(selector labels.Selector, fieldSelector fields.Selector) (ret []runtime.Object, err error) {
+	err = listAllInCluster(s.indexer, s.clusterName, metav1.NamespaceAll, s.strict, selector, fieldSelector, func(i interface{}) {
 		ret = append(ret, i.(runtime.Object))
 	})
 	return ret, err
@@ -146,7 +290,7 @@ func (s *genericLister) Get(name string) (runtime.Object, error) {
 		return nil, err
 	}
 	if !exists {
-		return nil, errors.NewNotFound(s.resource, name)
+		return nil, apierrors.NewNotFound(s.resource, name)
 	}
 	return obj.(runtime.Object), nil
 }
@@ -157,6 +301,7 @@ func (s *genericLister) ByNamespace(namespace string) cache.GenericNamespaceList
 		namespace: namespace,
 		resource:  s.resource,
 		cluster:   s.clusterName,
+		strict:    s.strict,
 	}
 }
 
@@ -165,10 +310,20 @@ type genericNamespaceLister struct {
 	cluster   logicalcluster.Name
 	namespace string
 	resource  schema.GroupResource
+	strict    bool
 }
 
 func (s *genericNamespaceLister) List(selector labels.Selector) (ret []runtime.Object, err error) {
-	err = ListAllByClusterAndNamespace(s.indexer, s.cluster, s.namespace, selector, func(i interface{}) {
+	err = listAllByClusterAndNamespace(s.indexer, s.cluster, s.namespace, s.strict, selector, func(i interface{}) {
+		ret = append(ret, i.(runtime.Object))
+	})
+	return ret, err
+}
+
+// ListWithFields lists the objects of the namespace that match selector and
+// fieldSelector, using a field index where registered.
+func (s *genericNamespaceLister) ListWithFields(selector labels.Selector, fieldSelector fields.Selector) (ret []runtime.Object, err error) {
+	err = listAllInCluster(s.indexer, s.cluster, s.namespace, s.strict, selector, fieldSelector, func(i interface{}) {
 		ret = append(ret, i.(runtime.Object))
 	})
 	return ret, err
@@ -181,7 +336,7 @@ func (s *genericNamespaceLister) Get(name string) (runtime.Object, error) {
 		return nil, err
 	}
 	if !exists {
-		return nil, errors.NewNotFound(s.resource, name)
+		return nil, apierrors.NewNotFound(s.resource, name)
 	}
 	return obj.(runtime.Object), nil
 }
@@ -17,6 +17,7 @@ limitations under the License.
 package cache
 
 import (
+	"sync/atomic"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -214,3 +215,58 @@ func TestGenericNamespaceLister(t *testing.T) {
 		})
 	}
 }
+
+type testCounter struct {
+	count atomic.Int32
+}
+
+func (c *testCounter) Inc() {
+	c.count.Add(1)
+}
+
+type testListerMetricsProvider map[string]*testCounter
+
+func (p testListerMetricsProvider) NewIndexFallbackMetric(indexName string) cache.CounterMetric {
+	return p[indexName]
+}
+
+// testListerMetrics is installed once, as SetListerMetricsProvider only
+// takes the first provider; tests reset its counters.
+var testListerMetrics = testListerMetricsProvider{
+	ClusterIndexName:             &testCounter{},
+	ClusterAndNamespaceIndexName: &testCounter{},
+}
+
+func TestListerIndexFallback(t *testing.T) {
+	metrics := testListerMetrics
+	SetListerMetricsProvider(metrics)
+	for _, counter := range metrics {
+		counter.count.Store(0)
+	}
+
+	indexer := cache.NewIndexer(MetaClusterNamespaceKeyFunc, cache.Indexers{})
+	require.NoError(t, indexer.Add(newUnstructured("c1", "ns1", "n1", nil)))
+	require.NoError(t, indexer.Add(newUnstructured("c2", "ns1", "n1", nil)))
+
+	list, err := NewGenericClusterLister(indexer, schema.GroupResource{}).ByCluster("c1").List(labels.Everything())
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	list, err = NewGenericClusterLister(indexer, schema.GroupResource{}).ByCluster("c1").ByNamespace("ns1").List(labels.Everything())
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	require.Equal(t, int32(1), metrics[ClusterIndexName].count.Load())
+	require.Equal(t, int32(1), metrics[ClusterAndNamespaceIndexName].count.Load())
+
+	strict := NewStrictGenericClusterLister(indexer, schema.GroupResource{}).ByCluster("c1")
+	_, err = strict.List(labels.Everything())
+	require.ErrorIs(t, err, ErrIndexNotRegistered)
+	_, err = strict.ByNamespace("ns1").List(labels.Everything())
+	require.ErrorIs(t, err, ErrIndexNotRegistered)
+	_, err = strict.ByNamespace("ns1").Get("n1")
+	require.NoError(t, err, "getting by key needs no index")
+	require.Equal(t, int32(1), metrics[ClusterIndexName].count.Load())
+
+	list, err = NewStrictGenericClusterLister(newTestIndexer(t), schema.GroupResource{}).ByCluster("c1").List(labels.Everything())
+	require.NoError(t, err)
+	require.Len(t, list, 5)
+}
@@ -0,0 +1,107 @@
+/*
+Copyright 2026 The kcp Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"k8s.io/client-go/tools/cache"
+)
+
+// ListerMetricsProvider generates the metrics of the cluster-aware listers.
+type ListerMetricsProvider interface {
+	// NewIndexFallbackMetric returns a counter of the lists that scanned the
+	// whole indexer because the index indexName is not registered.
+	NewIndexFallbackMetric(indexName string) cache.CounterMetric
+}
+
+type noopCounterMetric struct{}
+
+func (noopCounterMetric) Inc() {}
+
+type listerMetrics struct {
+	clusterIndexFallbacks             cache.CounterMetric
+	clusterAndNamespaceIndexFallbacks cache.CounterMetric
+}
+
+var (
+	setListerMetricsProvider sync.Once
+	globalListerMetrics      atomic.Pointer[listerMetrics]
+)
+
+func init() {
+	globalListerMetrics.Store(&listerMetrics{
+		clusterIndexFallbacks:             noopCounterMetric{},
+		clusterAndNamespaceIndexFallbacks: noopCounterMetric{},
+	})
+}
+
+// SetListerMetricsProvider sets the metrics provider of the cluster-aware
+// listers. Only the first call has an effect.
+func SetListerMetricsProvider(provider ListerMetricsProvider) {
+	setListerMetricsProvider.Do(func() {
+		globalListerMetrics.Store(&listerMetrics{
+			clusterIndexFallbacks:             provider.NewIndexFallbackMetric(ClusterIndexName),
+			clusterAndNamespaceIndexFallbacks: provider.NewIndexFallbackMetric(ClusterAndNamespaceIndexName),
+		})
+	})
+}
+
+// indexFallbackMetric returns the fallback counter of indexName.
+func indexFallbackMetric(indexName string) cache.CounterMetric {
+	m := globalListerMetrics.Load()
+	switch indexName {
+	case ClusterIndexName:
+		return m.clusterIndexFallbacks
+	case ClusterAndNamespaceIndexName:
+		return m.clusterAndNamespaceIndexFallbacks
+	}
+	return noopCounterMetric{}
+}
+
+// HandlerMetricsProvider generates the metrics of the event handlers of an
+// informer, see the HandlerMetricsProvider option of the informers. The
+// metrics are labelled with the name of the handler, which is derived from
+// its type or functions, and the identifier of the informer. Handlers with
+// the same name share their metrics. Like with cache.InformerMetricsProvider,
+// the returned metrics should check id.Reserved() before updating.
+type HandlerMetricsProvider interface {
+	// NewPendingNotificationsMetric returns a gauge of the notifications
+	// queued for the handler and not yet delivered. It is called once per
+	// handler name and informer, and the gauge reports the total of all
+	// handlers with that name.
+	NewPendingNotificationsMetric(id cache.InformerNameAndResource, handlerName string) cache.GaugeMetric
+	// NewDeliveryLatencyMetric returns a histogram of the seconds between a
+	// notification being queued for the handler and the handler being
+	// called with it.
+	NewDeliveryLatencyMetric(id cache.InformerNameAndResource, handlerName string) cache.HistogramMetric
+	// NewHandlerDurationMetric returns a histogram of the seconds the
+	// handler took to process a notification.
+	NewHandlerDurationMetric(id cache.InformerNameAndResource, handlerName string) cache.HistogramMetric
+	// NewRemovedHandlersMetric returns a counter of the registrations of the
+	// handler that were removed from the informer, e.g. by
+	// RemoveEventHandler or when their logical cluster was deleted.
+	NewRemovedHandlersMetric(id cache.InformerNameAndResource, handlerName string) cache.CounterMetric
+	// NewDroppedNotificationsMetric returns a counter of the notifications
+	// that were dropped instead of being delivered to the handler because
+	// its buffer overflowed, see the OverflowPolicy handler option.
+	NewDroppedNotificationsMetric(id cache.InformerNameAndResource, handlerName string) cache.CounterMetric
+	// NewSlowNotificationsMetric returns a counter of the notifications the
+	// handler took longer than its SlowHandlerThreshold option to process.
+	NewSlowNotificationsMetric(id cache.InformerNameAndResource, handlerName string) cache.CounterMetric
+}
@@ -0,0 +1,441 @@
+/*
+Copyright 2026 The kcp Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/kcp-dev/logicalcluster/v3"
+)
+
+const (
+	// ClusterPathAnnotationKey is the annotation holding the path of the
+	// logical cluster an object lives in, e.g. root:org:team. kcp sets it on
+	// LogicalCluster objects, among others.
+	ClusterPathAnnotationKey = "kcp.io/path"
+
+	// ClusterPathIndexName is the name of the index that allows you to filter
+	// by logical cluster path.
+	ClusterPathIndexName = "cluster-path"
+
+	// ClusterPathPrefixIndexName is the name of the index that allows you to
+	// filter by logical cluster path and its ancestors.
+	ClusterPathPrefixIndexName = "cluster-path-prefix"
+)
+
+// ClusterPathIndexFunc indexes by the logical cluster path in the
+// ClusterPathAnnotationKey annotation. Objects without the annotation are
+// indexed by their cluster name, which is a valid path too.
+func ClusterPathIndexFunc(obj interface{}) ([]string, error) {
+	meta, err := meta.Accessor(obj)
+	if err != nil {
+		return []string{}, fmt.Errorf("object has no meta: %v", err)
+	}
+	if path, ok := meta.GetAnnotations()[ClusterPathAnnotationKey]; ok && path != "" {
+		return []string{ClusterPathIndexKey(logicalcluster.NewPath(path))}, nil
+	}
+	return []string{ClusterPathIndexKey(logicalcluster.From(meta).Path())}, nil
+}
+
+// ClusterPathIndexKey formats the index key for a logical cluster path.
+func ClusterPathIndexKey(path logicalcluster.Path) string {
+	return path.String()
+}
+
+// ClusterPathPrefixIndexFunc indexes by the logical cluster path in the
+// ClusterPathAnnotationKey annotation and all of its ancestors, so that
+// ByIndex with a path returns the objects in the workspace subtree below it.
+// Objects without the annotation are indexed by their cluster name.
+func ClusterPathPrefixIndexFunc(obj interface{}) ([]string, error) {
+	meta, err := meta.Accessor(obj)
+	if err != nil {
+		return []string{}, fmt.Errorf("object has no meta: %v", err)
+	}
+	path := logicalcluster.NewPath(meta.GetAnnotations()[ClusterPathAnnotationKey])
+	if path.Empty() {
+		return []string{ClusterPathIndexKey(logicalcluster.From(meta).Path())}, nil
+	}
+	var keys []string
+	for {
+		keys = append(keys, ClusterPathIndexKey(path))
+		parent, ok := path.Parent()
+		if !ok {
+			return keys, nil
+		}
+		path = parent
+	}
+}
+
+// ClusterPathResolver resolves logical cluster paths to names.
+type ClusterPathResolver interface {
+	// ResolveClusterPath returns the name of the logical cluster at path.
+	ResolveClusterPath(path logicalcluster.Path) (logicalcluster.Name, bool)
+}
+
+// ClusterPathLookup looks up the paths of logical clusters.
+type ClusterPathLookup interface {
+	// ClusterPath returns the path of the logical cluster called name.
+	ClusterPath(name logicalcluster.Name) (logicalcluster.Path, bool)
+}
+
+// ClusterSubtreeResolver resolves workspace subtrees to the logical clusters
+// in them.
+type ClusterSubtreeResolver interface {
+	// ClustersInSubtree returns the names of the logical clusters at path
+	// and below it.
+	ClustersInSubtree(path logicalcluster.Path) []logicalcluster.Name
+}
+
+// ObjectInSubtree returns whether obj lives in a logical cluster at subtree
+// or below it. The path of the object's cluster is read from the
+// ClusterPathAnnotationKey annotation, or else looked up in paths, which may
+// be nil. obj can be a cache.DeletedFinalStateUnknown tombstone.
+func ObjectInSubtree(obj interface{}, subtree logicalcluster.Path, paths ClusterPathLookup) bool {
+	var clusterName logicalcluster.Name
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		if tombstone.Obj == nil {
+			_, name, _, _, err := SplitMetaShardClusterNamespaceKey(tombstone.Key)
+			if err != nil {
+				return false
+			}
+			clusterName = name
+		} else {
+			obj = tombstone.Obj
+		}
+	}
+	if clusterName == "" {
+		metadata, err := meta.Accessor(obj)
+		if err != nil {
+			return false
+		}
+		if path, ok := metadata.GetAnnotations()[ClusterPathAnnotationKey]; ok && path != "" {
+			return logicalcluster.NewPath(path).HasPrefix(subtree)
+		}
+		clusterName = logicalcluster.From(metadata)
+	}
+	if clusterName == "" {
+		return false
+	}
+	if paths != nil {
+		if path, ok := paths.ClusterPath(clusterName); ok {
+			return path.HasPrefix(subtree)
+		}
+	}
+	return clusterName.Path().HasPrefix(subtree)
+}
+
+// ClusterPathCache is a ClusterPathResolver fed by LogicalCluster objects.
+// Register it as the event handler of a LogicalCluster informer: every
+// LogicalCluster carries its path in the ClusterPathAnnotationKey annotation
+// and lives in the logical cluster it describes.
+type ClusterPathCache struct {
+	lock     sync.RWMutex
+	names    map[logicalcluster.Path]logicalcluster.Name
+	paths    map[logicalcluster.Name]logicalcluster.Path
+	handlers map[*func(logicalcluster.Name, logicalcluster.Path)]struct{}
+}
+
+var _ cache.ResourceEventHandler = &ClusterPathCache{}
+var _ ClusterPathResolver = &ClusterPathCache{}
+var _ ClusterPathLookup = &ClusterPathCache{}
+var _ ClusterSubtreeResolver = &ClusterPathCache{}
+
+// NewClusterPathCache creates an empty ClusterPathCache.
+func NewClusterPathCache() *ClusterPathCache {
+	return &ClusterPathCache{
+		names:    map[logicalcluster.Path]logicalcluster.Name{},
+		paths:    map[logicalcluster.Name]logicalcluster.Path{},
+		handlers: map[*func(logicalcluster.Name, logicalcluster.Path)]struct{}{},
+	}
+}
+
+// AddChangeHandler registers fn to be called whenever a logical cluster
+// gets a new path, and with an empty path when the cluster is deleted. The
+// returned func removes the handler again.
+func (c *ClusterPathCache) AddChangeHandler(fn func(name logicalcluster.Name, path logicalcluster.Path)) (remove func()) {
+	h := &fn
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.handlers[h] = struct{}{}
+	return func() {
+		c.lock.Lock()
+		defer c.lock.Unlock()
+		delete(c.handlers, h)
+	}
+}
+
+// ClustersInSubtree returns the names of the known logical clusters at path
+// and below it, sorted.
+func (c *ClusterPathCache) ClustersInSubtree(path logicalcluster.Path) []logicalcluster.Name {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	var names []logicalcluster.Name
+	for p, name := range c.names {
+		if p.HasPrefix(path) {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+// ResolveClusterPath returns the name of the logical cluster at path. Paths
+// of a single segment are names already.
+func (c *ClusterPathCache) ResolveClusterPath(path logicalcluster.Path) (logicalcluster.Name, bool) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	if name, ok := c.names[path]; ok {
+		return name, true
+	}
+	return path.Name()
+}
+
+// ClusterPath returns the path of the logical cluster called name, if known.
+func (c *ClusterPathCache) ClusterPath(name logicalcluster.Name) (logicalcluster.Path, bool) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	path, ok := c.paths[name]
+	return path, ok
+}
+
+func (c *ClusterPathCache) OnAdd(obj interface{}, _ bool) {
+	c.set(obj)
+}
+
+func (c *ClusterPathCache) OnUpdate(_, newObj interface{}) {
+	c.set(newObj)
+}
+
+func (c *ClusterPathCache) OnDelete(obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	metadata, err := meta.Accessor(obj)
+	if err != nil {
+		return
+	}
+	name := logicalcluster.From(metadata)
+
+	c.lock.Lock()
+	if _, ok := c.paths[name]; !ok {
+		c.lock.Unlock()
+		return
+	}
+	c.remove(name)
+	handlers := c.changeHandlers()
+	c.lock.Unlock()
+
+	for _, h := range handlers {
+		h(name, logicalcluster.Path{})
+	}
+}
+
+func (c *ClusterPathCache) set(obj interface{}) {
+	metadata, err := meta.Accessor(obj)
+	if err != nil {
+		return
+	}
+	name := logicalcluster.From(metadata)
+	path := logicalcluster.NewPath(metadata.GetAnnotations()[ClusterPathAnnotationKey])
+
+	c.lock.Lock()
+	if old, ok := c.paths[name]; ok && old == path {
+		c.lock.Unlock()
+		return
+	}
+	c.remove(name)
+	if name == "" || path.Empty() {
+		c.lock.Unlock()
+		return
+	}
+	c.names[path] = name
+	c.paths[name] = path
+	handlers := c.changeHandlers()
+	c.lock.Unlock()
+
+	for _, h := range handlers {
+		h(name, path)
+	}
+}
+
+// changeHandlers returns the registered change handlers. Must be called with
+// the lock held.
+func (c *ClusterPathCache) changeHandlers() []func(logicalcluster.Name, logicalcluster.Path) {
+	handlers := make([]func(logicalcluster.Name, logicalcluster.Path), 0, len(c.handlers))
+	for h := range c.handlers {
+		handlers = append(handlers, *h)
+	}
+	return handlers
+}
+
+// remove forgets the path of name. Must be called with the lock held.
+func (c *ClusterPathCache) remove(name logicalcluster.Name) {
+	path, ok := c.paths[name]
+	if !ok {
+		return
+	}
+	delete(c.paths, name)
+	if c.names[path] == name {
+		delete(c.names, path)
+	}
+}
+
+// WithClusterPathResolver returns a copy of the lister that resolves the
+// paths passed to ByClusterPath with resolver.
+func (s *ClusterLister) WithClusterPathResolver(resolver ClusterPathResolver) *ClusterLister {
+	lister := *s
+	lister.pathResolver = resolver
+	return &lister
+}
+
+// ByClusterPath returns a cache.GenericLister for the logical cluster at
+// path. The path is resolved on every call, by the resolver of
+// WithClusterPathResolver if set, else through ClusterPathIndexName if it is
+// registered, from the objects carrying the ClusterPathAnnotationKey
+// annotation. Paths of a single segment are names already. A lister for an
+// unresolvable path lists nothing.
+func (s *ClusterLister) ByClusterPath(path logicalcluster.Path) cache.GenericLister {
+	return &pathLister{
+		clusterLister: s,
+		path:          path,
+	}
+}
+
+// resolveClusterPath returns the name of the logical cluster at path.
+func (s *ClusterLister) resolveClusterPath(path logicalcluster.Path) (logicalcluster.Name, bool) {
+	if s.pathResolver != nil {
+		if name, ok := s.pathResolver.ResolveClusterPath(path); ok {
+			return name, true
+		}
+	}
+	if name, ok := path.Name(); ok {
+		return name, true
+	}
+	items, err := s.indexer.ByIndex(ClusterPathIndexName, ClusterPathIndexKey(path))
+	if err != nil {
+		return "", false
+	}
+	for _, item := range items {
+		if metadata, err := meta.Accessor(item); err == nil && logicalcluster.From(metadata) != "" {
+			return logicalcluster.From(metadata), true
+		}
+	}
+	return "", false
+}
+
+type pathLister struct {
+	clusterLister *ClusterLister
+	path          logicalcluster.Path
+}
+
+func (s *pathLister) lister() (cache.GenericLister, bool) {
+	name, ok := s.clusterLister.resolveClusterPath(s.path)
+	if !ok {
+		return nil, false
+	}
+	return s.clusterLister.ByCluster(name), true
+}
+
+func (s *pathLister) List(selector labels.Selector) ([]runtime.Object, error) {
+	lister, ok := s.lister()
+	if !ok {
+		return nil, nil
+	}
+	return lister.List(selector)
+}
+
+func (s *pathLister) Get(name string) (runtime.Object, error) {
+	lister, ok := s.lister()
+	if !ok {
+		return nil, apierrors.NewNotFound(s.clusterLister.resource, name)
+	}
+	return lister.Get(name)
+}
+
+func (s *pathLister) ByNamespace(namespace string) cache.GenericNamespaceLister {
+	return &pathNamespaceLister{
+		pathLister: s,
+		namespace:  namespace,
+	}
+}
+
+type pathNamespaceLister struct {
+	pathLister *pathLister
+	namespace  string
+}
+
+func (s *pathNamespaceLister) List(selector labels.Selector) ([]runtime.Object, error) {
+	lister, ok := s.pathLister.lister()
+	if !ok {
+		return nil, nil
+	}
+	return lister.ByNamespace(s.namespace).List(selector)
+}
+
+func (s *pathNamespaceLister) Get(name string) (runtime.Object, error) {
+	lister, ok := s.pathLister.lister()
+	if !ok {
+		return nil, apierrors.NewNotFound(s.pathLister.clusterLister.resource, name)
+	}
+	return lister.ByNamespace(s.namespace).Get(name)
+}
+
+// BySubtree returns a cache.GenericLister for the logical clusters at path
+// and below it. The clusters are determined on every call: those the
+// resolver of WithClusterPathResolver knows, if it is a
+// ClusterSubtreeResolver, those of the objects found through
+// ClusterPathPrefixIndexName, if it is registered, and the cluster named
+// like path, if path is a single segment. Get fails if the name exists in
+// more than one of the clusters.
+func (s *ClusterLister) BySubtree(path logicalcluster.Path) cache.GenericLister {
+	return &multiClusterLister{
+		clusterLister: s,
+		clusters: func() []logicalcluster.Name {
+			return s.subtreeClusters(path)
+		},
+		description: "subtree " + path.String(),
+	}
+}
+
+// subtreeClusters returns the names of the logical clusters at path and below
+// it, sorted.
+func (s *ClusterLister) subtreeClusters(path logicalcluster.Path) []logicalcluster.Name {
+	names := sets.New[logicalcluster.Name]()
+	if resolver, ok := s.pathResolver.(ClusterSubtreeResolver); ok {
+		names.Insert(resolver.ClustersInSubtree(path)...)
+	}
+	if items, err := s.indexer.ByIndex(ClusterPathPrefixIndexName, ClusterPathIndexKey(path)); err == nil {
+		for _, item := range items {
+			if metadata, err := meta.Accessor(item); err == nil && logicalcluster.From(metadata) != "" {
+				names.Insert(logicalcluster.From(metadata))
+			}
+		}
+	}
+	if name, ok := path.Name(); ok {
+		names.Insert(name)
+	}
+	return sets.List(names)
+}
@@ -0,0 +1,218 @@
+/*
+Copyright 2026 The kcp Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/kcp-dev/logicalcluster/v3"
+)
+
+func newLogicalCluster(cluster, path string) *unstructured.Unstructured {
+	u := newUnstructured(cluster, "", "cluster", nil)
+	if path != "" {
+		u.SetAnnotations(map[string]string{
+			logicalcluster.AnnotationKey: cluster,
+			ClusterPathAnnotationKey:     path,
+		})
+	}
+	return u
+}
+
+func TestClusterPathIndexFunc(t *testing.T) {
+	values, err := ClusterPathIndexFunc(newLogicalCluster("abc", "root:org"))
+	require.NoError(t, err)
+	require.Equal(t, []string{"root:org"}, values)
+
+	values, err = ClusterPathIndexFunc(newUnstructured("abc", "ns1", "n1", nil))
+	require.NoError(t, err)
+	require.Equal(t, []string{"abc"}, values)
+}
+
+func TestClusterPathCache(t *testing.T) {
+	c := NewClusterPathCache()
+	c.OnAdd(newLogicalCluster("abc", "root:org"), true)
+	c.OnAdd(newLogicalCluster("def", "root:org:team"), true)
+
+	name, ok := c.ResolveClusterPath(logicalcluster.NewPath("root:org:team"))
+	require.True(t, ok)
+	require.Equal(t, logicalcluster.Name("def"), name)
+	path, ok := c.ClusterPath("abc")
+	require.True(t, ok)
+	require.Equal(t, logicalcluster.NewPath("root:org"), path)
+
+	name, ok = c.ResolveClusterPath(logicalcluster.NewPath("xyz"))
+	require.True(t, ok, "single segment paths are names")
+	require.Equal(t, logicalcluster.Name("xyz"), name)
+	_, ok = c.ResolveClusterPath(logicalcluster.NewPath("root:other"))
+	require.False(t, ok)
+
+	c.OnUpdate(newLogicalCluster("abc", "root:org"), newLogicalCluster("abc", "root:renamed"))
+	_, ok = c.ResolveClusterPath(logicalcluster.NewPath("root:org"))
+	require.False(t, ok)
+	name, ok = c.ResolveClusterPath(logicalcluster.NewPath("root:renamed"))
+	require.True(t, ok)
+	require.Equal(t, logicalcluster.Name("abc"), name)
+
+	c.OnDelete(cache.DeletedFinalStateUnknown{Obj: newLogicalCluster("def", "root:org:team")})
+	_, ok = c.ResolveClusterPath(logicalcluster.NewPath("root:org:team"))
+	require.False(t, ok)
+}
+
+func TestByClusterPath(t *testing.T) {
+	indexer := newTestIndexer(t)
+	require.NoError(t, indexer.AddIndexers(cache.Indexers{ClusterPathIndexName: ClusterPathIndexFunc}))
+	require.NoError(t, indexer.Add(newLogicalCluster("c1", "root:org")))
+
+	l := NewGenericClusterLister(indexer, schema.GroupResource{})
+
+	tests := map[string]struct {
+		lister  *ClusterLister
+		path    string
+		wantLen int
+	}{
+		"by index":         {lister: l, path: "root:org", wantLen: 6},
+		"by name":          {lister: l, path: "c2", wantLen: 5},
+		"unknown path":     {lister: l, path: "root:other", wantLen: 0},
+		"by resolver":      {lister: l.WithClusterPathResolver(resolverFunc{"root:team": "c2"}), path: "root:team", wantLen: 5},
+		"resolver misses":  {lister: l.WithClusterPathResolver(resolverFunc{}), path: "root:org", wantLen: 6},
+		"unknown resolved": {lister: l.WithClusterPathResolver(resolverFunc{"root:gone": "c3"}), path: "root:gone", wantLen: 0},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			list, err := tt.lister.ByClusterPath(logicalcluster.NewPath(tt.path)).List(labels.Everything())
+			require.NoError(t, err)
+			require.Len(t, list, tt.wantLen)
+		})
+	}
+
+	obj, err := l.ByClusterPath(logicalcluster.NewPath("root:org")).ByNamespace("ns2").Get("n2")
+	require.NoError(t, err)
+	require.Equal(t, logicalcluster.Name("c1"), logicalcluster.From(obj.(*unstructured.Unstructured)))
+
+	_, err = l.ByClusterPath(logicalcluster.NewPath("root:other")).Get("cn1")
+	require.True(t, apierrors.IsNotFound(err))
+}
+
+type resolverFunc map[string]logicalcluster.Name
+
+func (r resolverFunc) ResolveClusterPath(path logicalcluster.Path) (logicalcluster.Name, bool) {
+	name, ok := r[path.String()]
+	return name, ok
+}
+
+func TestClusterPathPrefixIndexFunc(t *testing.T) {
+	values, err := ClusterPathPrefixIndexFunc(newLogicalCluster("abc", "root:org:team"))
+	require.NoError(t, err)
+	require.Equal(t, []string{"root:org:team", "root:org", "root"}, values)
+
+	values, err = ClusterPathPrefixIndexFunc(newUnstructured("abc", "ns1", "n1", nil))
+	require.NoError(t, err)
+	require.Equal(t, []string{"abc"}, values)
+}
+
+func TestObjectInSubtree(t *testing.T) {
+	paths := NewClusterPathCache()
+	paths.OnAdd(newLogicalCluster("abc", "root:org:team"), true)
+	subtree := logicalcluster.NewPath("root:org")
+
+	tests := map[string]struct {
+		obj   interface{}
+		paths ClusterPathLookup
+		want  bool
+	}{
+		"annotated":             {obj: newLogicalCluster("abc", "root:org:team"), want: true},
+		"annotated elsewhere":   {obj: newLogicalCluster("abc", "root:organization"), want: false},
+		"looked up":             {obj: newUnstructured("abc", "ns1", "n1", nil), paths: paths, want: true},
+		"unknown":               {obj: newUnstructured("def", "ns1", "n1", nil), paths: paths, want: false},
+		"without lookup":        {obj: newUnstructured("abc", "ns1", "n1", nil), want: false},
+		"tombstone":             {obj: cache.DeletedFinalStateUnknown{Obj: newUnstructured("abc", "ns1", "n1", nil)}, paths: paths, want: true},
+		"tombstone without obj": {obj: cache.DeletedFinalStateUnknown{Key: "abc|ns1/n1"}, paths: paths, want: true},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			require.Equal(t, tt.want, ObjectInSubtree(tt.obj, subtree, tt.paths))
+		})
+	}
+
+	require.True(t, ObjectInSubtree(newUnstructured("root", "", "n1", nil), logicalcluster.NewPath("root"), nil))
+}
+
+func TestClusterPathCacheSubtree(t *testing.T) {
+	c := NewClusterPathCache()
+	var changes []string
+	remove := c.AddChangeHandler(func(name logicalcluster.Name, path logicalcluster.Path) {
+		changes = append(changes, name.String()+"="+path.String())
+	})
+	c.OnAdd(newLogicalCluster("abc", "root:org:team"), true)
+	c.OnAdd(newLogicalCluster("def", "root:org"), true)
+	c.OnAdd(newLogicalCluster("ghi", "root:other"), true)
+	c.OnUpdate(newLogicalCluster("ghi", "root:other"), newLogicalCluster("ghi", "root:other"))
+
+	require.Equal(t, []logicalcluster.Name{"abc", "def"}, c.ClustersInSubtree(logicalcluster.NewPath("root:org")))
+	require.Equal(t, []logicalcluster.Name{"abc", "def", "ghi"}, c.ClustersInSubtree(logicalcluster.NewPath("root")))
+	require.Equal(t, []string{"abc=root:org:team", "def=root:org", "ghi=root:other"}, changes, "unchanged paths are not reported")
+
+	remove()
+	c.OnAdd(newLogicalCluster("jkl", "root:org:new"), true)
+	require.Len(t, changes, 3)
+}
+
+func TestBySubtree(t *testing.T) {
+	indexer := newTestIndexer(t)
+	require.NoError(t, indexer.Add(newUnstructured("c3", "ns1", "n1", nil)))
+
+	paths := NewClusterPathCache()
+	paths.OnAdd(newLogicalCluster("c1", "root:org:a"), true)
+	paths.OnAdd(newLogicalCluster("c2", "root:org:b"), true)
+	paths.OnAdd(newLogicalCluster("c3", "root:other"), true)
+	l := NewGenericClusterLister(indexer, schema.GroupResource{}).WithClusterPathResolver(paths)
+
+	list, err := l.BySubtree(logicalcluster.NewPath("root:org")).List(labels.Everything())
+	require.NoError(t, err)
+	require.Len(t, list, 10)
+
+	list, err = l.BySubtree(logicalcluster.NewPath("root:org:b")).ByNamespace("ns2").List(labels.Everything())
+	require.NoError(t, err)
+	require.Len(t, list, 2)
+
+	obj, err := l.BySubtree(logicalcluster.NewPath("root:other")).ByNamespace("ns1").Get("n1")
+	require.NoError(t, err)
+	require.Equal(t, logicalcluster.Name("c3"), logicalcluster.From(obj.(*unstructured.Unstructured)))
+
+	_, err = l.BySubtree(logicalcluster.NewPath("root:org")).ByNamespace("ns1").Get("n1")
+	require.Error(t, err, "n1 exists in two clusters of the subtree")
+	require.False(t, apierrors.IsNotFound(err))
+
+	_, err = l.BySubtree(logicalcluster.NewPath("root:org")).Get("missing")
+	require.True(t, apierrors.IsNotFound(err))
+
+	// Without a resolver, the clusters are found through the prefix index.
+	require.NoError(t, indexer.AddIndexers(cache.Indexers{ClusterPathPrefixIndexName: ClusterPathPrefixIndexFunc}))
+	require.NoError(t, indexer.Add(newLogicalCluster("c2", "root:org:b")))
+	list, err = NewGenericClusterLister(indexer, schema.GroupResource{}).BySubtree(logicalcluster.NewPath("root:org")).List(labels.Everything())
+	require.NoError(t, err)
+	require.Len(t, list, 6)
+}
@@ -0,0 +1,177 @@
+/*
+Copyright 2026 The kcp Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"errors"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/kcp-dev/logicalcluster/v3"
+)
+
+// ErrScopedIndexerReadOnly is returned by the mutating methods of the indexer
+// of NewScopedIndexer.
+var ErrScopedIndexerReadOnly = errors.New("scoped indexer is read-only")
+
+// NewScopedIndexer returns a read-only view of indexer that only holds the
+// objects of clusterName in namespace, or in all namespaces if namespace is
+// metav1.NamespaceAll. Lists are served from ClusterAndNamespaceIndexName or
+// ClusterIndexName.
+//
+// The keys of the view are those of cache.MetaNamespaceKeyFunc, without the
+// cluster, so that client-go listers like cache.NewGenericLister work on top
+// of it. ListIndexFuncValues and GetIndexers are not scoped.
+func NewScopedIndexer(indexer cache.Indexer, clusterName logicalcluster.Name, namespace string) cache.Indexer {
+	return &scopedIndexer{
+		indexer:     indexer,
+		clusterName: clusterName,
+		namespace:   namespace,
+	}
+}
+
+type scopedIndexer struct {
+	indexer     cache.Indexer
+	clusterName logicalcluster.Name
+	namespace   string
+}
+
+func (s *scopedIndexer) Add(obj interface{}) error {
+	return ErrScopedIndexerReadOnly
+}
+
+func (s *scopedIndexer) Update(obj interface{}) error {
+	return ErrScopedIndexerReadOnly
+}
+
+func (s *scopedIndexer) Delete(obj interface{}) error {
+	return ErrScopedIndexerReadOnly
+}
+
+func (s *scopedIndexer) Replace(list []interface{}, resourceVersion string) error {
+	return ErrScopedIndexerReadOnly
+}
+
+func (s *scopedIndexer) Resync() error {
+	return nil
+}
+
+func (s *scopedIndexer) Bookmark(resourceVersion string) {}
+
+func (s *scopedIndexer) LastStoreSyncResourceVersion() string {
+	return s.indexer.LastStoreSyncResourceVersion()
+}
+
+func (s *scopedIndexer) List() []interface{} {
+	// The items are scoped by the index or, without one, by the filtering
+	// fallback, so the error is only about the index func and is dropped.
+	items, _ := clusterItems(s.indexer, s.clusterName, s.namespace, nil, false)
+	return items
+}
+
+func (s *scopedIndexer) ListKeys() []string {
+	items := s.List()
+	keys := make([]string, 0, len(items))
+	for _, item := range items {
+		if key, err := cache.MetaNamespaceKeyFunc(item); err == nil {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+func (s *scopedIndexer) Get(obj interface{}) (item interface{}, exists bool, err error) {
+	key, err := cache.MetaNamespaceKeyFunc(obj)
+	if err != nil {
+		return nil, false, cache.KeyError{Obj: obj, Err: err}
+	}
+	return s.GetByKey(key)
+}
+
+func (s *scopedIndexer) GetByKey(key string) (item interface{}, exists bool, err error) {
+	namespace, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		return nil, false, err
+	}
+	if !s.inNamespace(namespace) {
+		return nil, false, nil
+	}
+	return s.indexer.GetByKey(ToClusterAwareKey(s.clusterName.String(), namespace, name))
+}
+
+func (s *scopedIndexer) Index(indexName string, obj interface{}) ([]interface{}, error) {
+	items, err := s.indexer.Index(indexName, obj)
+	if err != nil {
+		return nil, err
+	}
+	return s.filter(items), nil
+}
+
+func (s *scopedIndexer) IndexKeys(indexName, indexedValue string) ([]string, error) {
+	items, err := s.ByIndex(indexName, indexedValue)
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]string, 0, len(items))
+	for _, item := range items {
+		if key, err := cache.MetaNamespaceKeyFunc(item); err == nil {
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+func (s *scopedIndexer) ListIndexFuncValues(indexName string) []string {
+	return s.indexer.ListIndexFuncValues(indexName)
+}
+
+func (s *scopedIndexer) ByIndex(indexName, indexedValue string) ([]interface{}, error) {
+	items, err := s.indexer.ByIndex(indexName, indexedValue)
+	if err != nil {
+		return nil, err
+	}
+	return s.filter(items), nil
+}
+
+func (s *scopedIndexer) GetIndexers() cache.Indexers {
+	return s.indexer.GetIndexers()
+}
+
+func (s *scopedIndexer) AddIndexers(newIndexers cache.Indexers) error {
+	return s.indexer.AddIndexers(newIndexers)
+}
+
+// filter returns the items in the scope of s.
+func (s *scopedIndexer) filter(items []interface{}) []interface{} {
+	ret := make([]interface{}, 0, len(items))
+	for _, item := range items {
+		metadata, err := meta.Accessor(item)
+		if err != nil {
+			continue
+		}
+		if logicalcluster.From(metadata) == s.clusterName && s.inNamespace(metadata.GetNamespace()) {
+			ret = append(ret, item)
+		}
+	}
+	return ret
+}
+
+func (s *scopedIndexer) inNamespace(namespace string) bool {
+	return s.namespace == metav1.NamespaceAll || s.namespace == namespace
+}
@@ -0,0 +1,95 @@
+/*
+Copyright 2026 The kcp Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/kcp-dev/logicalcluster/v3"
+)
+
+func TestScopedIndexer(t *testing.T) {
+	tests := map[string]struct {
+		namespace string
+		wantKeys  []string
+	}{
+		"namespace": {
+			namespace: "ns2",
+			wantKeys:  []string{"ns2/n1", "ns2/n2"},
+		},
+		"cluster-scoped objects": {
+			namespace: "",
+			wantKeys:  []string{"ns1/n1", "ns2/n1", "ns2/n2", "cn1", "cn2"},
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			indexer := NewScopedIndexer(newTestIndexer(t), "c1", tt.namespace)
+			require.ElementsMatch(t, tt.wantKeys, indexer.ListKeys())
+			require.Len(t, indexer.List(), len(tt.wantKeys))
+			for _, item := range indexer.List() {
+				require.Equal(t, logicalcluster.Name("c1"), logicalcluster.From(item.(*unstructured.Unstructured)))
+			}
+		})
+	}
+}
+
+func TestScopedIndexerGet(t *testing.T) {
+	indexer := NewScopedIndexer(newTestIndexer(t), "c2", "ns2")
+
+	obj, exists, err := indexer.GetByKey("ns2/n2")
+	require.NoError(t, err)
+	require.True(t, exists)
+	require.Equal(t, logicalcluster.Name("c2"), logicalcluster.From(obj.(*unstructured.Unstructured)))
+
+	_, exists, err = indexer.GetByKey("ns1/n1")
+	require.NoError(t, err)
+	require.False(t, exists, "ns1 is out of scope")
+
+	_, exists, err = indexer.Get(newUnstructured("c1", "ns2", "n1", nil))
+	require.NoError(t, err)
+	require.True(t, exists, "objects are looked up in the cluster of the view")
+
+	items, err := indexer.ByIndex(ClusterIndexName, "c2")
+	require.NoError(t, err)
+	require.Len(t, items, 2)
+
+	require.ErrorIs(t, indexer.Add(newUnstructured("c2", "ns2", "n3", nil)), ErrScopedIndexerReadOnly)
+}
+
+func TestScopedIndexerClientGoLister(t *testing.T) {
+	lister := cache.NewGenericLister(NewScopedIndexer(newTestIndexer(t), "c1", metav1.NamespaceAll), schema.GroupResource{})
+
+	list, err := lister.ByNamespace("ns2").List(labels.Everything())
+	require.NoError(t, err)
+	require.Len(t, list, 2)
+
+	_, err = lister.ByNamespace("ns1").Get("n1")
+	require.NoError(t, err)
+
+	_, err = lister.ByNamespace("ns1").Get("n2")
+	require.True(t, apierrors.IsNotFound(err))
+}
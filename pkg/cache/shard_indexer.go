@@ -0,0 +1,98 @@
+/*
+Copyright 2026 The kcp Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"cmp"
+	"slices"
+
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/kcp-dev/logicalcluster/v3"
+)
+
+// NewShardIndexer wraps indexer, which must key objects with
+// MetaShardClusterNamespaceKeyFunc, so that it can also be read with the keys
+// of MetaClusterNamespaceKeyFunc. This lets listers, scoped indexers and work
+// queues, which only know the cluster of an object, read a cache that holds
+// the objects of several shards.
+//
+// Get and GetByKey look up keys without a shard through ClusterKeyIndexName,
+// or by scanning all items if it is not registered. If several shards hold
+// an object with the key, which happens while a logical cluster moves between
+// shards, the one of the first shard in lexical order is returned. All of
+// them are listed, and can be told apart with ShardIndexName.
+//
+// If indexer is a ClusterIndexer, so is the returned indexer.
+func NewShardIndexer(indexer cache.Indexer) cache.Indexer {
+	if clusterIndexer, ok := indexer.(ClusterIndexer); ok {
+		return &shardClusterIndexer{
+			shardIndexer:   shardIndexer{Indexer: indexer},
+			clusterIndexer: clusterIndexer,
+		}
+	}
+	return &shardIndexer{Indexer: indexer}
+}
+
+type shardIndexer struct {
+	cache.Indexer
+}
+
+func (s *shardIndexer) Get(obj interface{}) (item interface{}, exists bool, err error) {
+	key, err := MetaShardClusterNamespaceKeyFunc(obj)
+	if err != nil {
+		return nil, false, cache.KeyError{Obj: obj, Err: err}
+	}
+	return s.GetByKey(key)
+}
+
+func (s *shardIndexer) GetByKey(key string) (item interface{}, exists bool, err error) {
+	item, exists, err = s.Indexer.GetByKey(key)
+	if err != nil || exists {
+		return item, exists, err
+	}
+	shard, _, _, _, err := SplitMetaShardClusterNamespaceKey(key)
+	if err != nil || shard != "" {
+		return nil, false, nil
+	}
+
+	items, err := byIndexWithBackup(s.Indexer, ClusterKeyIndexName, key, ClusterKeyIndexFunc, false)
+	if err != nil || len(items) == 0 {
+		return nil, false, err
+	}
+	return slices.MinFunc(items, func(a, b interface{}) int {
+		return cmp.Compare(shardOf(a), shardOf(b))
+	}), true, nil
+}
+
+// shardOf returns the shard in the ShardAnnotationKey annotation of obj.
+func shardOf(obj interface{}) string {
+	shards, _ := ShardIndexFunc(obj)
+	if len(shards) == 0 {
+		return ""
+	}
+	return shards[0]
+}
+
+type shardClusterIndexer struct {
+	shardIndexer
+	clusterIndexer ClusterIndexer
+}
+
+func (s *shardClusterIndexer) DropCluster(clusterName logicalcluster.Name) []interface{} {
+	return s.clusterIndexer.DropCluster(clusterName)
+}
@@ -0,0 +1,104 @@
+/*
+Copyright 2026 The kcp Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/tools/cache"
+)
+
+func newShardUnstructured(shard, cluster, namespace, name string) *unstructured.Unstructured {
+	u := newUnstructured(cluster, namespace, name, nil)
+	annotations := u.GetAnnotations()
+	annotations[ShardAnnotationKey] = shard
+	u.SetAnnotations(annotations)
+	return u
+}
+
+func TestShardIndexer(t *testing.T) {
+	for name, indexers := range map[string]cache.Indexers{
+		"indexed": {
+			ClusterIndexName:    ClusterIndexFunc,
+			ShardIndexName:      ShardIndexFunc,
+			ClusterKeyIndexName: ClusterKeyIndexFunc,
+		},
+		"not indexed": {
+			ClusterIndexName: ClusterIndexFunc,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			indexer := NewShardIndexer(cache.NewIndexer(MetaShardClusterNamespaceKeyFunc, indexers))
+			require.NoError(t, indexer.Add(newShardUnstructured("s2", "c1", "ns", "moving")))
+			require.NoError(t, indexer.Add(newShardUnstructured("s1", "c1", "ns", "moving")))
+			require.NoError(t, indexer.Add(newShardUnstructured("s2", "c2", "", "other")))
+			require.ElementsMatch(t, []string{"s1|c1|ns/moving", "s2|c1|ns/moving", "s2|c2|other"}, indexer.ListKeys())
+
+			// Keys with a shard find the copy of that shard.
+			obj, exists, err := indexer.GetByKey("s2|c1|ns/moving")
+			require.NoError(t, err)
+			require.True(t, exists)
+			require.Equal(t, "s2", shardOf(obj))
+
+			// Keys without a shard find the copy of the first shard.
+			obj, exists, err = indexer.GetByKey("c1|ns/moving")
+			require.NoError(t, err)
+			require.True(t, exists)
+			require.Equal(t, "s1", shardOf(obj))
+			obj, exists, err = indexer.Get(newUnstructured("c2", "", "other", nil))
+			require.NoError(t, err)
+			require.True(t, exists)
+			require.Equal(t, "s2", shardOf(obj))
+
+			_, exists, err = indexer.GetByKey("s3|c1|ns/moving")
+			require.NoError(t, err)
+			require.False(t, exists, "keys with a shard are not resolved in other shards")
+			_, exists, err = indexer.GetByKey("c3|ns/moving")
+			require.NoError(t, err)
+			require.False(t, exists)
+
+			// Listers look objects up by cluster.
+			lister := NewGenericClusterLister(indexer, schema.GroupResource{Resource: "things"})
+			obj2, err := lister.ByCluster("c1").ByNamespace("ns").Get("moving")
+			require.NoError(t, err)
+			require.Equal(t, "s1", shardOf(obj2))
+			items, err := lister.ByCluster("c1").List(nil)
+			require.NoError(t, err)
+			require.Len(t, items, 2)
+		})
+	}
+}
+
+func TestShardIndexerDropCluster(t *testing.T) {
+	indexer := NewShardIndexer(NewClusterIndexer(MetaShardClusterNamespaceKeyFunc, cache.Indexers{
+		ClusterKeyIndexName: ClusterKeyIndexFunc,
+	}))
+	require.NoError(t, indexer.Add(newShardUnstructured("s1", "c1", "ns", "a")))
+	require.NoError(t, indexer.Add(newShardUnstructured("s2", "c1", "ns", "a")))
+
+	clusterIndexer, ok := indexer.(ClusterIndexer)
+	require.True(t, ok)
+	_, exists, err := indexer.GetByKey("c1|ns/a")
+	require.NoError(t, err)
+	require.True(t, exists)
+	require.Len(t, clusterIndexer.DropCluster("c1"), 2)
+	require.Empty(t, indexer.ListKeys())
+}
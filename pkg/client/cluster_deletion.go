@@ -0,0 +1,144 @@
+/*
+Copyright 2026 The kcp Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"sync"
+	"weak"
+
+	"github.com/kcp-dev/logicalcluster/v3"
+)
+
+// ClusterDeletedFunc is called when a logical cluster has been deleted. The
+// path is whatever was passed to EvictCluster; for a deleted LogicalCluster
+// this is usually its single-segment name, i.e. logicalcluster.Name.Path().
+type ClusterDeletedFunc func(clusterPath logicalcluster.Path)
+
+// clusterDeletedSubscriber is the value the registry points at. Strong
+// subscriptions pin it directly; weak subscriptions only hold a
+// weak.Pointer to it, so it stays alive exactly as long as the
+// unsubscribe func handed back to the subscriber does.
+type clusterDeletedSubscriber struct {
+	fn ClusterDeletedFunc
+}
+
+// clusterDeletedSubscription is one entry in the registry. Exactly one of
+// strong and weak is set.
+type clusterDeletedSubscription struct {
+	strong *clusterDeletedSubscriber
+	weak   weak.Pointer[clusterDeletedSubscriber]
+}
+
+func (s clusterDeletedSubscription) subscriber() *clusterDeletedSubscriber {
+	if s.strong != nil {
+		return s.strong
+	}
+	return s.weak.Value()
+}
+
+var (
+	subscriptionsMu sync.Mutex
+	subscriptions   []clusterDeletedSubscription
+)
+
+// OnClusterDeleted registers fn to be called on every EvictCluster. The
+// registry holds fn strongly: it stays registered until unsubscribe is
+// called. Use this for process-lifetime components (RESTMappers, rate
+// limiters, work queues) that drop their per-cluster state on deletion.
+//
+// fn is called synchronously from EvictCluster, outside of the registry
+// lock, and must not block.
+func OnClusterDeleted(fn ClusterDeletedFunc) (unsubscribe func()) {
+	sub := &clusterDeletedSubscriber{fn: fn}
+
+	subscriptionsMu.Lock()
+	defer subscriptionsMu.Unlock()
+	subscriptions = append(subscriptions, clusterDeletedSubscription{strong: sub})
+
+	return func() {
+		removeSubscriber(sub)
+	}
+}
+
+// OnClusterDeletedWeak is like OnClusterDeleted, but the registry holds fn
+// weakly. The returned unsubscribe func is what keeps the subscription
+// alive: the subscriber must retain it (typically as a struct field) for as
+// long as it wants to be notified. Once it becomes unreachable, the
+// subscription is dropped and pruned lazily on the next EvictCluster.
+//
+// This lets short-lived owners such as client caches and informers
+// subscribe without the registry pinning them in memory. fn may capture the
+// subscriber, and even the unsubscribe func: the registry does not keep
+// either alive, and the cycle between them is collected like any other.
+func OnClusterDeletedWeak(fn ClusterDeletedFunc) (unsubscribe func()) {
+	sub := &clusterDeletedSubscriber{fn: fn}
+
+	subscriptionsMu.Lock()
+	defer subscriptionsMu.Unlock()
+	subscriptions = append(subscriptions, clusterDeletedSubscription{weak: weak.Make(sub)})
+
+	return func() {
+		removeSubscriber(sub)
+	}
+}
+
+func removeSubscriber(sub *clusterDeletedSubscriber) {
+	subscriptionsMu.Lock()
+	defer subscriptionsMu.Unlock()
+
+	live := subscriptions[:0]
+	for _, s := range subscriptions {
+		if s.subscriber() == sub {
+			continue
+		}
+		live = append(live, s)
+	}
+	clear(subscriptions[len(live):])
+	subscriptions = live
+}
+
+// EvictCluster notifies every subscriber registered with OnClusterDeleted
+// or OnClusterDeletedWeak that clusterPath has been deleted. In particular,
+// every cache created by NewCache drops its cached client (and everything
+// that client transitively pins — REST client, codec factory, JSON decoder
+// state, OpenAPI schemas). Wire this to a LogicalCluster delete handler to
+// bound retained memory per workspace lifetime. See
+// https://github.com/kcp-dev/kcp/issues/4071.
+//
+// Dead weak entries (subscribers whose only remaining reference was the
+// weak entry in the registry) are pruned in-place during the iteration.
+// Subscribers are notified in registration order.
+func EvictCluster(clusterPath logicalcluster.Path) {
+	subscriptionsMu.Lock()
+	live := subscriptions[:0]
+	alive := make([]*clusterDeletedSubscriber, 0, len(subscriptions))
+	for _, s := range subscriptions {
+		sub := s.subscriber()
+		if sub == nil {
+			continue
+		}
+		live = append(live, s)
+		alive = append(alive, sub)
+	}
+	clear(subscriptions[len(live):])
+	subscriptions = live
+	subscriptionsMu.Unlock()
+
+	for _, sub := range alive {
+		sub.fn(clusterPath)
+	}
+}
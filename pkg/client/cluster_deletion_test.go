@@ -0,0 +1,114 @@
+/*
+Copyright 2026 The kcp Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/kcp-dev/logicalcluster/v3"
+)
+
+func TestOnClusterDeleted_NotifiesUntilUnsubscribed(t *testing.T) {
+	var got []logicalcluster.Path
+	unsubscribe := OnClusterDeleted(func(clusterPath logicalcluster.Path) {
+		got = append(got, clusterPath)
+	})
+
+	EvictCluster(logicalcluster.NewPath("ws-a"))
+	unsubscribe()
+	EvictCluster(logicalcluster.NewPath("ws-b"))
+
+	if len(got) != 1 || got[0] != logicalcluster.NewPath("ws-a") {
+		t.Fatalf("expected exactly one notification for ws-a, got %v", got)
+	}
+
+	// Unsubscribing twice must be harmless.
+	unsubscribe()
+}
+
+func TestOnClusterDeletedWeak_DroppedWithAnchor(t *testing.T) {
+	flushRegistry()
+	before := registrySize()
+
+	var calls int
+	func() {
+		_ = OnClusterDeletedWeak(func(logicalcluster.Path) { calls++ })
+	}()
+
+	if got := registrySize(); got != before+1 {
+		t.Fatalf("expected %d registry entries pre-GC, got %d", before+1, got)
+	}
+
+	for range 50 {
+		runtime.GC()
+		runtime.GC()
+		EvictCluster(logicalcluster.NewPath("ws-weak"))
+		if registrySize() == before {
+			return
+		}
+		time.Sleep(2 * time.Millisecond)
+	}
+	t.Fatalf("weak subscription was not pruned: registry has %d entries, expected %d", registrySize(), before)
+}
+
+func TestOnClusterDeletedWeak_DroppedWithCycle(t *testing.T) {
+	flushRegistry()
+	before := registrySize()
+
+	// The subscriber holds its unsubscribe func, and fn captures the
+	// subscriber.
+	type subscriber struct {
+		evicted     []logicalcluster.Path
+		unsubscribe func()
+	}
+	func() {
+		s := &subscriber{}
+		s.unsubscribe = OnClusterDeletedWeak(func(clusterPath logicalcluster.Path) {
+			s.evicted = append(s.evicted, clusterPath)
+		})
+	}()
+
+	for range 50 {
+		runtime.GC()
+		runtime.GC()
+		EvictCluster(logicalcluster.NewPath("ws-cycle"))
+		if registrySize() == before {
+			return
+		}
+		time.Sleep(2 * time.Millisecond)
+	}
+	t.Fatalf("weak subscription was not pruned: registry has %d entries, expected %d", registrySize(), before)
+}
+
+func TestOnClusterDeletedWeak_NotifiesWhileAnchored(t *testing.T) {
+	var got []logicalcluster.Path
+	unsubscribe := OnClusterDeletedWeak(func(clusterPath logicalcluster.Path) {
+		got = append(got, clusterPath)
+	})
+
+	runtime.GC()
+	runtime.GC()
+	EvictCluster(logicalcluster.NewPath("ws-anchored"))
+	unsubscribe()
+	EvictCluster(logicalcluster.NewPath("ws-after"))
+
+	if len(got) != 1 || got[0] != logicalcluster.NewPath("ws-anchored") {
+		t.Fatalf("expected exactly one notification for ws-anchored, got %v", got)
+	}
+}
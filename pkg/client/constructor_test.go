@@ -158,9 +158,9 @@ func TestRegistry_PrunesGCdCaches(t *testing.T) {
 }
 
 func registrySize() int {
-	evictorsMu.Lock()
-	defer evictorsMu.Unlock()
-	return len(evictors)
+	subscriptionsMu.Lock()
+	defer subscriptionsMu.Unlock()
+	return len(subscriptions)
 }
 
 // flushRegistry forces the registry to compact, dropping any entries whose
@@ -0,0 +1,263 @@
+/*
+Copyright 2026 The kcp Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package controller provides a small runtime for controllers that reconcile
+// objects across logical clusters: it feeds the events of a cluster-aware
+// informer into a cluster-aware work queue and calls a Reconciler for every
+// key, with retries, bounded concurrency and graceful shutdown.
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/klog/v2"
+
+	kcpcache "github.com/kcp-dev/apimachinery/v2/pkg/cache"
+	kcpclient "github.com/kcp-dev/apimachinery/v2/pkg/client"
+	"github.com/kcp-dev/apimachinery/v2/pkg/partition"
+	kcpworkqueue "github.com/kcp-dev/apimachinery/v2/pkg/workqueue"
+	"github.com/kcp-dev/logicalcluster/v3"
+)
+
+// Reconciler reconciles one object of a logical cluster.
+type Reconciler interface {
+	// Reconcile brings the object identified by key in cluster to its
+	// desired state. The object may have been deleted. If Reconcile returns
+	// an error, the key is retried with backoff. ctx carries a logger with
+	// the cluster and key, and is cancelled when the controller stops.
+	Reconcile(ctx context.Context, cluster logicalcluster.Name, key cache.ObjectName) error
+}
+
+// ReconcilerFunc is an adaptor to use a function as a Reconciler.
+type ReconcilerFunc func(ctx context.Context, cluster logicalcluster.Name, key cache.ObjectName) error
+
+// Reconcile calls f.
+func (f ReconcilerFunc) Reconcile(ctx context.Context, cluster logicalcluster.Name, key cache.ObjectName) error {
+	return f(ctx, cluster, key)
+}
+
+// Options configures a Controller.
+type Options[R any] struct {
+	// Name of the controller. It is used for logging and as the name of the
+	// default queue.
+	Name string
+
+	// Informer is the informer whose objects are reconciled. Every add,
+	// update and delete enqueues the object's key. The controller does not
+	// run the informer.
+	Informer kcpcache.ScopeableSharedIndexInformer
+
+	// Reconciler is called for every key.
+	Reconciler Reconciler
+
+	// Queue optionally allows specifying the queue. Defaults to a
+	// kcpworkqueue.FairQueue named Name. Queues implementing
+	// ForgetCluster(logicalcluster.Name) forget a logical cluster once it is
+	// reported deleted through kcpclient.EvictCluster.
+	Queue workqueue.TypedRateLimitingInterface[kcpworkqueue.ClusterQueueKey]
+
+	// Clients optionally allows specifying a client cache. If set, the
+	// client of the cluster being reconciled is available from the context
+	// passed to Reconcile, see ClientFrom.
+	Clients kcpclient.Cache[R]
+
+	// Partitioner optionally restricts the controller to the logical clusters
+	// owned by this replica. Events and keys of other clusters are skipped,
+	// and when the owned clusters change, all objects of owned clusters are
+	// enqueued again.
+	Partitioner *partition.ClusterPartitioner
+
+	// MaxConcurrentReconciles is the number of keys reconciled at the same
+	// time. Defaults to 1. A key is never reconciled concurrently with
+	// itself.
+	MaxConcurrentReconciles int
+}
+
+// Controller reconciles the objects of an informer across logical clusters.
+type Controller[R any] struct {
+	name        string
+	informer    kcpcache.ScopeableSharedIndexInformer
+	reconciler  Reconciler
+	queue       workqueue.TypedRateLimitingInterface[kcpworkqueue.ClusterQueueKey]
+	clients     kcpclient.Cache[R]
+	partitioner *partition.ClusterPartitioner
+	workers     int
+
+	registration cache.ResourceEventHandlerRegistration
+	startedLock  sync.Mutex
+	started      bool
+}
+
+// New creates a controller and registers its event handler with the
+// informer. Start it with Run.
+func New[R any](options Options[R]) (*Controller[R], error) {
+	if options.Informer == nil {
+		return nil, errors.New("informer must be set")
+	}
+	if options.Reconciler == nil {
+		return nil, errors.New("reconciler must be set")
+	}
+	if options.Queue == nil {
+		options.Queue = kcpworkqueue.NewFairQueue(kcpworkqueue.FairQueueConfig{Name: options.Name})
+	}
+	if options.Partitioner != nil {
+		options.Queue = partition.NewPartitionedQueue(options.Queue, options.Partitioner)
+	}
+	if options.MaxConcurrentReconciles < 1 {
+		options.MaxConcurrentReconciles = 1
+	}
+
+	c := &Controller[R]{
+		name:        options.Name,
+		informer:    options.Informer,
+		reconciler:  options.Reconciler,
+		queue:       options.Queue,
+		clients:     options.Clients,
+		partitioner: options.Partitioner,
+		workers:     options.MaxConcurrentReconciles,
+	}
+	var handler cache.ResourceEventHandler = kcpworkqueue.NewEnqueueHandler(options.Queue)
+	if options.Partitioner != nil {
+		handler = options.Partitioner.EventHandler(handler)
+	}
+	registration, err := options.Informer.AddEventHandler(handler)
+	if err != nil {
+		return nil, fmt.Errorf("failed to add event handler: %w", err)
+	}
+	c.registration = registration
+	return c, nil
+}
+
+// Queue returns the queue of the controller, e.g. to enqueue keys from the
+// event handlers of other informers.
+func (c *Controller[R]) Queue() workqueue.TypedRateLimitingInterface[kcpworkqueue.ClusterQueueKey] {
+	return c.queue
+}
+
+// Run waits for the informer to sync and reconciles keys until ctx is done.
+// It then shuts down the queue, waits for the reconciles in progress to
+// finish and removes the event handler from the informer. Keys still queued
+// are dropped. A controller can only be run once.
+func (c *Controller[R]) Run(ctx context.Context) error {
+	c.startedLock.Lock()
+	if c.started {
+		c.startedLock.Unlock()
+		return errors.New("controller was already started")
+	}
+	c.started = true
+	c.startedLock.Unlock()
+
+	logger := klog.FromContext(ctx).WithValues("controller", c.name)
+	ctx = klog.NewContext(ctx, logger)
+
+	defer func() {
+		if err := c.informer.RemoveEventHandler(c.registration); err != nil {
+			utilruntime.HandleErrorWithContext(ctx, err, "Failed to remove event handler")
+		}
+	}()
+
+	if forgetter, ok := c.queue.(interface {
+		ForgetCluster(cluster logicalcluster.Name)
+	}); ok {
+		unsubscribe := kcpclient.OnClusterDeleted(func(clusterPath logicalcluster.Path) {
+			if name, ok := clusterPath.Name(); ok {
+				forgetter.ForgetCluster(name)
+			}
+		})
+		defer unsubscribe()
+	}
+
+	if c.partitioner != nil {
+		remove := c.partitioner.AddChangeHandler(func() {
+			c.partitioner.EnqueueOwned(c.informer.GetStore(), c.queue)
+		})
+		defer remove()
+	}
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	defer c.queue.ShutDown()
+
+	logger.Info("Starting controller")
+	defer logger.Info("Shutting down controller")
+
+	if !cache.WaitForNamedCacheSyncWithContext(ctx, c.registration.HasSynced) {
+		return ctx.Err()
+	}
+
+	for range c.workers {
+		wg.Go(func() {
+			wait.UntilWithContext(ctx, c.runWorker, 0)
+		})
+	}
+	<-ctx.Done()
+	return nil
+}
+
+func (c *Controller[R]) runWorker(ctx context.Context) {
+	for c.processNextItem(ctx) {
+	}
+}
+
+func (c *Controller[R]) processNextItem(ctx context.Context) bool {
+	key, shutdown := c.queue.Get()
+	if shutdown {
+		return false
+	}
+	defer c.queue.Done(key)
+	if ctx.Err() != nil {
+		// The controller is stopping, leave the remaining keys alone.
+		return false
+	}
+
+	logger := klog.FromContext(ctx).WithValues("cluster", key.Cluster, "namespace", key.Namespace, "name", key.Name)
+	ctx = klog.NewContext(ctx, logger)
+
+	if err := c.reconcile(ctx, key); err != nil {
+		utilruntime.HandleErrorWithContext(ctx, err, "Reconcile failed, requeuing", "retries", c.queue.NumRequeues(key))
+		c.queue.AddRateLimited(key)
+		return true
+	}
+	c.queue.Forget(key)
+	return true
+}
+
+func (c *Controller[R]) reconcile(ctx context.Context, key kcpworkqueue.ClusterQueueKey) error {
+	if c.clients != nil {
+		client, err := c.clients.Cluster(key.Cluster.Path())
+		if err != nil {
+			return fmt.Errorf("failed to get client for cluster %q: %w", key.Cluster, err)
+		}
+		ctx = context.WithValue(ctx, clientKey[R]{}, client)
+	}
+	return c.reconciler.Reconcile(ctx, key.Cluster, cache.ObjectName{Namespace: key.Namespace, Name: key.Name})
+}
+
+type clientKey[R any] struct{}
+
+// ClientFrom returns the client of the cluster being reconciled from the
+// context passed to Reconcile by a Controller[R] with Options.Clients set.
+func ClientFrom[R any](ctx context.Context) (client R, ok bool) {
+	client, ok = ctx.Value(clientKey[R]{}).(R)
+	return client, ok
+}
@@ -0,0 +1,289 @@
+/*
+Copyright 2026 The kcp Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
+	fcache "k8s.io/client-go/tools/cache/testing"
+	"k8s.io/client-go/util/workqueue"
+
+	kcpclient "github.com/kcp-dev/apimachinery/v2/pkg/client"
+	"github.com/kcp-dev/apimachinery/v2/pkg/partition"
+	kcpworkqueue "github.com/kcp-dev/apimachinery/v2/pkg/workqueue"
+	"github.com/kcp-dev/apimachinery/v2/third_party/informers"
+	"github.com/kcp-dev/logicalcluster/v3"
+)
+
+func newPod(cluster, namespace, name string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:   namespace,
+			Name:        name,
+			Annotations: map[string]string{logicalcluster.AnnotationKey: cluster},
+		},
+	}
+}
+
+type reconciled struct {
+	cluster logicalcluster.Name
+	key     cache.ObjectName
+}
+
+// startController runs the informer and the controller until the test ends.
+func startController[R any](t *testing.T, source *fcache.FakeControllerSource, options Options[R]) *Controller[R] {
+	t.Helper()
+
+	options.Informer = informers.NewSharedIndexInformer(source, &corev1.Pod{}, 0, cache.Indexers{})
+	c, err := New(options)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	wg.Go(func() { options.Informer.RunWithContext(ctx) })
+	wg.Go(func() { require.NoError(t, c.Run(ctx)) })
+	t.Cleanup(func() {
+		cancel()
+		wg.Wait()
+	})
+	return c
+}
+
+func TestControllerReconcilesAcrossClusters(t *testing.T) {
+	source := fcache.NewFakeControllerSource()
+	source.Add(newPod("c1", "ns1", "a"))
+	source.Add(newPod("c2", "ns1", "b"))
+
+	got := make(chan reconciled, 10)
+	startController(t, source, Options[any]{
+		Name: "test",
+		Reconciler: ReconcilerFunc(func(ctx context.Context, cluster logicalcluster.Name, key cache.ObjectName) error {
+			got <- reconciled{cluster: cluster, key: key}
+			return nil
+		}),
+	})
+
+	require.ElementsMatch(t, []reconciled{
+		{cluster: "c1", key: cache.ObjectName{Namespace: "ns1", Name: "a"}},
+		{cluster: "c2", key: cache.ObjectName{Namespace: "ns1", Name: "b"}},
+	}, []reconciled{<-got, <-got})
+
+	source.Delete(newPod("c1", "ns1", "a"))
+	require.Equal(t, reconciled{cluster: "c1", key: cache.ObjectName{Namespace: "ns1", Name: "a"}}, <-got)
+}
+
+func TestControllerRetriesWithBackoff(t *testing.T) {
+	source := fcache.NewFakeControllerSource()
+	source.Add(newPod("c1", "ns1", "a"))
+
+	var attempts atomic.Int32
+	done := make(chan struct{})
+	c := startController(t, source, Options[any]{
+		Name: "test",
+		Queue: kcpworkqueue.NewFairQueue(kcpworkqueue.FairQueueConfig{
+			RateLimiter: func(logicalcluster.Name) workqueue.TypedRateLimiter[kcpworkqueue.ClusterQueueKey] {
+				return workqueue.NewTypedItemExponentialFailureRateLimiter[kcpworkqueue.ClusterQueueKey](time.Millisecond, 10*time.Millisecond)
+			},
+		}),
+		Reconciler: ReconcilerFunc(func(ctx context.Context, cluster logicalcluster.Name, key cache.ObjectName) error {
+			if attempts.Add(1) < 3 {
+				return errors.New("not yet")
+			}
+			close(done)
+			return nil
+		}),
+	})
+
+	select {
+	case <-done:
+	case <-time.After(wait.ForeverTestTimeout):
+		t.Fatal("key was not retried")
+	}
+	require.Eventually(t, func() bool {
+		return c.Queue().NumRequeues(kcpworkqueue.ClusterQueueKey{Cluster: "c1", Namespace: "ns1", Name: "a"}) == 0
+	}, wait.ForeverTestTimeout, 10*time.Millisecond, "key was not forgotten after success")
+}
+
+func TestControllerMaxConcurrentReconciles(t *testing.T) {
+	source := fcache.NewFakeControllerSource()
+	for _, name := range []string{"a", "b", "c", "d", "e", "f"} {
+		source.Add(newPod("c1", "ns1", name))
+	}
+
+	var running, maxRunning atomic.Int32
+	var finished sync.WaitGroup
+	finished.Add(6)
+	startController(t, source, Options[any]{
+		Name:                    "test",
+		MaxConcurrentReconciles: 2,
+		Reconciler: ReconcilerFunc(func(ctx context.Context, cluster logicalcluster.Name, key cache.ObjectName) error {
+			defer finished.Done()
+			n := running.Add(1)
+			defer running.Add(-1)
+			for {
+				m := maxRunning.Load()
+				if n <= m || maxRunning.CompareAndSwap(m, n) {
+					break
+				}
+			}
+			time.Sleep(10 * time.Millisecond)
+			return nil
+		}),
+	})
+
+	finished.Wait()
+	require.Equal(t, int32(2), maxRunning.Load())
+}
+
+func TestControllerGracefulShutdown(t *testing.T) {
+	source := fcache.NewFakeControllerSource()
+	source.Add(newPod("c1", "ns1", "a"))
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var finished atomic.Bool
+	c, err := New(Options[any]{
+		Name:     "test",
+		Informer: informers.NewSharedIndexInformer(source, &corev1.Pod{}, 0, cache.Indexers{}),
+		Reconciler: ReconcilerFunc(func(ctx context.Context, cluster logicalcluster.Name, key cache.ObjectName) error {
+			close(started)
+			<-release
+			finished.Store(true)
+			return nil
+		}),
+	})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go c.informer.RunWithContext(ctx)
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		require.NoError(t, c.Run(ctx))
+	}()
+
+	<-started
+	cancel()
+	select {
+	case <-stopped:
+		t.Fatal("Run returned while a reconcile was in progress")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(release)
+	<-stopped
+	require.True(t, finished.Load())
+	require.True(t, c.Queue().ShuttingDown())
+	require.Error(t, c.Run(context.Background()), "a controller can only be run once")
+}
+
+func TestControllerClientFrom(t *testing.T) {
+	source := fcache.NewFakeControllerSource()
+	source.Add(newPod("c1", "ns1", "a"))
+
+	clients := kcpclient.NewCache(&rest.Config{Host: "https://kcp.example"}, &http.Client{}, &kcpclient.Constructor[string]{
+		NewForConfigAndClient: func(cfg *rest.Config, _ *http.Client) (string, error) {
+			return cfg.Host, nil
+		},
+	})
+	got := make(chan string, 1)
+	startController(t, source, Options[string]{
+		Name:    "test",
+		Clients: clients,
+		Reconciler: ReconcilerFunc(func(ctx context.Context, cluster logicalcluster.Name, key cache.ObjectName) error {
+			client, ok := ClientFrom[string](ctx)
+			require.True(t, ok)
+			got <- client
+			return nil
+		}),
+	})
+
+	require.Equal(t, "https://kcp.example/clusters/c1", <-got)
+}
+
+type testMembers struct {
+	lock    sync.Mutex
+	members []string
+}
+
+func (m *testMembers) Members() ([]string, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return m.members, nil
+}
+
+func (m *testMembers) set(members ...string) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.members = members
+}
+
+func TestControllerPartitioner(t *testing.T) {
+	members := &testMembers{}
+	members.set("replica-a", "replica-b")
+	partitioner, err := partition.NewClusterPartitioner("replica-a", members)
+	require.NoError(t, err)
+
+	var owned, notOwned logicalcluster.Name
+	for i := 0; owned == "" || notOwned == ""; i++ {
+		name := logicalcluster.Name(fmt.Sprintf("cluster-%d", i))
+		if partitioner.Owns(name) {
+			owned = name
+		} else {
+			notOwned = name
+		}
+	}
+
+	source := fcache.NewFakeControllerSource()
+	source.Add(newPod(owned.String(), "ns1", "a"))
+	source.Add(newPod(notOwned.String(), "ns1", "b"))
+
+	got := make(chan logicalcluster.Name, 10)
+	startController(t, source, Options[any]{
+		Name:        "test",
+		Partitioner: partitioner,
+		Reconciler: ReconcilerFunc(func(ctx context.Context, cluster logicalcluster.Name, key cache.ObjectName) error {
+			got <- cluster
+			return nil
+		}),
+	})
+
+	require.Equal(t, owned, <-got)
+	select {
+	case cluster := <-got:
+		t.Fatalf("reconciled cluster %s that is not owned", cluster)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	members.set("replica-a")
+	require.NoError(t, partitioner.Refresh())
+	require.ElementsMatch(t, []logicalcluster.Name{owned, notOwned}, []logicalcluster.Name{<-got, <-got})
+}
@@ -0,0 +1,117 @@
+/*
+Copyright 2026 The kcp Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package partition
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	coordinationv1 "k8s.io/api/coordination/v1"
+	"k8s.io/utils/clock"
+)
+
+// LeaseLister lists the Lease objects that replicas hold to announce
+// themselves, e.g. from a Lease informer restricted to one namespace and
+// label selector.
+type LeaseLister interface {
+	List() ([]*coordinationv1.Lease, error)
+}
+
+// LeaseListerFunc is an adaptor to use a function as a LeaseLister.
+type LeaseListerFunc func() ([]*coordinationv1.Lease, error)
+
+// List calls f.
+func (f LeaseListerFunc) List() ([]*coordinationv1.Lease, error) {
+	return f()
+}
+
+// LeaseMembers is a MembershipSource whose members are the holders of the
+// Leases that have not expired.
+type LeaseMembers struct {
+	Leases LeaseLister
+	// Clock optionally allows injecting a fake clock for testing purposes.
+	Clock clock.PassiveClock
+}
+
+// Members returns the holder identities of the leases that were renewed within
+// their lease duration.
+func (m LeaseMembers) Members() ([]string, error) {
+	leases, err := m.Leases.List()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list leases: %w", err)
+	}
+	c := m.Clock
+	if c == nil {
+		c = clock.RealClock{}
+	}
+	now := c.Now()
+
+	var members []string
+	for _, lease := range leases {
+		spec := lease.Spec
+		if spec.HolderIdentity == nil || *spec.HolderIdentity == "" || spec.RenewTime == nil || spec.LeaseDurationSeconds == nil {
+			continue
+		}
+		expiry := spec.RenewTime.Add(time.Duration(*spec.LeaseDurationSeconds) * time.Second)
+		if now.Before(expiry) {
+			members = append(members, *spec.HolderIdentity)
+		}
+	}
+	return members, nil
+}
+
+// LocalLeases is an in-memory stand-in for the Lease objects of the replicas,
+// keyed by name. It can be used as the LeaseLister of LeaseMembers where no
+// API server is available, e.g. in tests or single-process deployments.
+type LocalLeases struct {
+	lock   sync.Mutex
+	leases map[string]*coordinationv1.Lease
+}
+
+// NewLocalLeases creates an empty set of leases.
+func NewLocalLeases() *LocalLeases {
+	return &LocalLeases{leases: map[string]*coordinationv1.Lease{}}
+}
+
+// Update creates or replaces the lease with the name of lease.
+func (l *LocalLeases) Update(lease *coordinationv1.Lease) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.leases[lease.Name] = lease.DeepCopy()
+}
+
+// Delete removes the lease called name.
+func (l *LocalLeases) Delete(name string) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	delete(l.leases, name)
+}
+
+// List returns copies of all leases, sorted by name.
+func (l *LocalLeases) List() ([]*coordinationv1.Lease, error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	leases := make([]*coordinationv1.Lease, 0, len(l.leases))
+	for _, name := range slices.Sorted(maps.Keys(l.leases)) {
+		leases = append(leases, l.leases[name].DeepCopy())
+	}
+	return leases, nil
+}
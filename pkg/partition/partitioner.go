@@ -0,0 +1,342 @@
+/*
+Copyright 2026 The kcp Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package partition assigns logical clusters to controller replicas, so that
+// every replica reconciles only the clusters it owns.
+package partition
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+
+	kcpcache "github.com/kcp-dev/apimachinery/v2/pkg/cache"
+	"github.com/kcp-dev/apimachinery/v2/pkg/util/crypto"
+	kcpworkqueue "github.com/kcp-dev/apimachinery/v2/pkg/workqueue"
+	"github.com/kcp-dev/logicalcluster/v3"
+)
+
+// MembershipSource reports the replicas that clusters are partitioned across.
+type MembershipSource interface {
+	// Members returns the identities of the current replicas.
+	Members() ([]string, error)
+}
+
+// StaticMembers is a MembershipSource with a fixed list of replicas.
+type StaticMembers []string
+
+// Members returns the list.
+func (m StaticMembers) Members() ([]string, error) {
+	return m, nil
+}
+
+// ClusterPartitioner assigns every logical cluster to exactly one replica.
+//
+// Clusters are assigned with rendezvous hashing: every replica scores every
+// cluster with crypto.Base36Sha224 of the replica and cluster names, and the
+// replica with the highest score owns the cluster. All replicas with the same
+// members compute the same owners without coordination, and when a replica
+// joins or leaves, only the clusters it gains or loses change owners.
+type ClusterPartitioner struct {
+	self   string
+	source MembershipSource
+
+	lock     sync.RWMutex
+	members  []string
+	handlers map[*func()]struct{}
+}
+
+// NewClusterPartitioner creates a partitioner for the replica self, and reads
+// the initial members from source. self owns no clusters while it is not one
+// of the members.
+func NewClusterPartitioner(self string, source MembershipSource) (*ClusterPartitioner, error) {
+	p := &ClusterPartitioner{
+		self:     self,
+		source:   source,
+		handlers: map[*func()]struct{}{},
+	}
+	if err := p.Refresh(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Refresh reads the members from the source. If they changed, the change
+// handlers are called.
+func (p *ClusterPartitioner) Refresh() error {
+	members, err := p.source.Members()
+	if err != nil {
+		return fmt.Errorf("failed to get members: %w", err)
+	}
+	members = slices.Compact(slices.Sorted(slices.Values(members)))
+
+	p.lock.Lock()
+	if slices.Equal(p.members, members) {
+		p.lock.Unlock()
+		return nil
+	}
+	p.members = members
+	handlers := make([]func(), 0, len(p.handlers))
+	for h := range p.handlers {
+		handlers = append(handlers, *h)
+	}
+	p.lock.Unlock()
+
+	for _, h := range handlers {
+		h()
+	}
+	return nil
+}
+
+// Run refreshes the members every period until ctx is done.
+func (p *ClusterPartitioner) Run(ctx context.Context, period time.Duration) {
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		if err := p.Refresh(); err != nil {
+			utilruntime.HandleErrorWithContext(ctx, err, "Failed to refresh cluster partitioner members")
+		}
+	}, period)
+}
+
+// AddChangeHandler registers fn to be called whenever the members, and with
+// them possibly the owned clusters, change. Use it to enqueue the clusters
+// this replica may have gained, see EnqueueOwned. The returned func removes
+// the handler again.
+func (p *ClusterPartitioner) AddChangeHandler(fn func()) (remove func()) {
+	h := &fn
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.handlers[h] = struct{}{}
+	return func() {
+		p.lock.Lock()
+		defer p.lock.Unlock()
+		delete(p.handlers, h)
+	}
+}
+
+// Members returns the current members, sorted.
+func (p *ClusterPartitioner) Members() []string {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	return slices.Clone(p.members)
+}
+
+// Owner returns the replica owning cluster, or "" if there are no members.
+func (p *ClusterPartitioner) Owner(cluster logicalcluster.Name) string {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	var owner, ownerScore string
+	for _, member := range p.members {
+		score := crypto.Base36Sha224.String(member + "|" + cluster.String())
+		if owner == "" || higherScore(score, ownerScore) {
+			owner, ownerScore = member, score
+		}
+	}
+	return owner
+}
+
+// higherScore compares two base36 encodings numerically: the encodings have
+// no leading zeros, and the base36 alphabet is in ASCII order.
+func higherScore(a, b string) bool {
+	if len(a) != len(b) {
+		return len(a) > len(b)
+	}
+	return a > b
+}
+
+// Owns returns whether this replica owns cluster.
+func (p *ClusterPartitioner) Owns(cluster logicalcluster.Name) bool {
+	return p.Owner(cluster) == p.self
+}
+
+// OwnsObject returns whether this replica owns the cluster of obj. obj can be
+// an object or a cache.DeletedFinalStateUnknown tombstone. Objects without a
+// cluster are not owned.
+func (p *ClusterPartitioner) OwnsObject(obj interface{}) bool {
+	cluster, ok := clusterOf(obj)
+	return ok && p.Owns(cluster)
+}
+
+func clusterOf(obj interface{}) (logicalcluster.Name, bool) {
+	if d, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		_, clusterName, _, _, err := kcpcache.SplitMetaShardClusterNamespaceKey(d.Key)
+		return clusterName, err == nil && clusterName != ""
+	}
+	m, err := meta.Accessor(obj)
+	if err != nil {
+		return "", false
+	}
+	clusterName := logicalcluster.From(m)
+	return clusterName, clusterName != ""
+}
+
+// EventHandler wraps handler so that it only sees the objects of owned
+// clusters. Ownership is checked when a notification is delivered, so when a
+// cluster changes owners, nothing is delivered for its objects until they
+// change: the new owner gets no adds and the old one no deletes. Use
+// OwnedClusterSet to have them delivered, or EnqueueOwned from a change
+// handler.
+func (p *ClusterPartitioner) EventHandler(handler cache.ResourceEventHandler) cache.ResourceEventHandler {
+	return cache.FilteringResourceEventHandler{
+		FilterFunc: p.OwnsObject,
+		Handler:    handler,
+	}
+}
+
+// OwnedClusterSet scopes informer down to the clusters this replica owns.
+// Handlers of the returned informer get adds for the objects of the clusters
+// this replica gains when the members change, and deletes for the objects of
+// the clusters it loses. As the scope is checked on delivery, a handler
+// added while a cluster is gained can get the adds of its objects twice.
+// informer must implement kcpcache.ClusterSetScoper
+// and kcpcache.ClusterLifecycleNotifier, which tells the partitioner which
+// clusters exist. The returned func stops following the members; the set of
+// clusters is not changed anymore afterwards.
+func (p *ClusterPartitioner) OwnedClusterSet(informer cache.SharedIndexInformer) (kcpcache.ClusterSetInformer, func(), error) {
+	scoper, ok := informer.(kcpcache.ClusterSetScoper)
+	if !ok {
+		return nil, nil, fmt.Errorf("informer %T cannot be scoped to a cluster set", informer)
+	}
+	notifier, ok := informer.(kcpcache.ClusterLifecycleNotifier)
+	if !ok {
+		return nil, nil, fmt.Errorf("informer %T does not report cluster lifecycle events", informer)
+	}
+
+	owned := &ownedClusters{
+		partitioner: p,
+		informer:    scoper.ClusterSet(),
+		clusters:    sets.New[logicalcluster.Name](),
+	}
+	removeLifecycleHandler, err := notifier.AddClusterLifecycleHandler(kcpcache.ClusterLifecycleHandlerFuncs{
+		AddFunc:    owned.added,
+		RemoveFunc: owned.removed,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	removeChangeHandler := p.AddChangeHandler(owned.resync)
+	return owned.informer, func() {
+		removeChangeHandler()
+		removeLifecycleHandler()
+	}, nil
+}
+
+// ownedClusters keeps the cluster set of informer in line with the clusters
+// the partitioner owns.
+type ownedClusters struct {
+	partitioner *ClusterPartitioner
+	informer    kcpcache.ClusterSetInformer
+
+	// lock guards clusters, the clusters that hold objects, and orders the
+	// changes of the cluster set.
+	lock     sync.Mutex
+	clusters sets.Set[logicalcluster.Name]
+}
+
+func (o *ownedClusters) added(clusterName logicalcluster.Name) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	o.clusters.Insert(clusterName)
+	if o.partitioner.Owns(clusterName) {
+		o.informer.Add(clusterName)
+	}
+}
+
+func (o *ownedClusters) removed(clusterName logicalcluster.Name) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	o.clusters.Delete(clusterName)
+	o.informer.Remove(clusterName)
+}
+
+// resync adds the clusters that are owned now, and removes those that are
+// not anymore.
+func (o *ownedClusters) resync() {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	for clusterName := range o.clusters {
+		if o.partitioner.Owns(clusterName) {
+			o.informer.Add(clusterName)
+		} else {
+			o.informer.Remove(clusterName)
+		}
+	}
+}
+
+// EnqueueOwned adds the key of every object in store that this replica owns
+// to queue. Call it from a change handler to pick up the clusters this
+// replica gained, whose events were skipped before.
+func (p *ClusterPartitioner) EnqueueOwned(store cache.Store, queue workqueue.TypedInterface[kcpworkqueue.ClusterQueueKey]) {
+	for _, obj := range store.List() {
+		if !p.OwnsObject(obj) {
+			continue
+		}
+		if err := kcpworkqueue.Enqueue(queue, obj); err != nil {
+			utilruntime.HandleError(fmt.Errorf("failed to enqueue object: %w", err))
+		}
+	}
+}
+
+// NewPartitionedQueue wraps queue so that keys of clusters this replica does
+// not own are dropped instead of added. Keys already queued when a cluster
+// changes owners are still handed out.
+func NewPartitionedQueue(queue workqueue.TypedRateLimitingInterface[kcpworkqueue.ClusterQueueKey], partitioner *ClusterPartitioner) workqueue.TypedRateLimitingInterface[kcpworkqueue.ClusterQueueKey] {
+	return &partitionedQueue{
+		TypedRateLimitingInterface: queue,
+		partitioner:                partitioner,
+	}
+}
+
+type partitionedQueue struct {
+	workqueue.TypedRateLimitingInterface[kcpworkqueue.ClusterQueueKey]
+	partitioner *ClusterPartitioner
+}
+
+func (q *partitionedQueue) Add(item kcpworkqueue.ClusterQueueKey) {
+	if q.partitioner.Owns(item.Cluster) {
+		q.TypedRateLimitingInterface.Add(item)
+	}
+}
+
+func (q *partitionedQueue) AddAfter(item kcpworkqueue.ClusterQueueKey, duration time.Duration) {
+	if q.partitioner.Owns(item.Cluster) {
+		q.TypedRateLimitingInterface.AddAfter(item, duration)
+	}
+}
+
+func (q *partitionedQueue) AddRateLimited(item kcpworkqueue.ClusterQueueKey) {
+	if q.partitioner.Owns(item.Cluster) {
+		q.TypedRateLimitingInterface.AddRateLimited(item)
+	}
+}
+
+// ForgetCluster passes through to the wrapped queue, if it supports it.
+func (q *partitionedQueue) ForgetCluster(cluster logicalcluster.Name) {
+	if forgetter, ok := q.TypedRateLimitingInterface.(interface {
+		ForgetCluster(cluster logicalcluster.Name)
+	}); ok {
+		forgetter.ForgetCluster(cluster)
+	}
+}
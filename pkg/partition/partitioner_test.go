@@ -0,0 +1,332 @@
+/*
+Copyright 2026 The kcp Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package partition
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	coordinationv1 "k8s.io/api/coordination/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/cache"
+	fcache "k8s.io/client-go/tools/cache/testing"
+	"k8s.io/client-go/util/workqueue"
+	clocktesting "k8s.io/utils/clock/testing"
+	"k8s.io/utils/ptr"
+
+	kcpcache "github.com/kcp-dev/apimachinery/v2/pkg/cache"
+	kcpworkqueue "github.com/kcp-dev/apimachinery/v2/pkg/workqueue"
+	"github.com/kcp-dev/apimachinery/v2/third_party/informers"
+	"github.com/kcp-dev/logicalcluster/v3"
+)
+
+func clusters(n int) []logicalcluster.Name {
+	names := make([]logicalcluster.Name, n)
+	for i := range names {
+		names[i] = logicalcluster.Name(fmt.Sprintf("cluster-%d", i))
+	}
+	return names
+}
+
+func owners(t *testing.T, members []string, names []logicalcluster.Name) map[logicalcluster.Name]string {
+	t.Helper()
+	p, err := NewClusterPartitioner("", StaticMembers(members))
+	require.NoError(t, err)
+	result := map[logicalcluster.Name]string{}
+	for _, name := range names {
+		result[name] = p.Owner(name)
+	}
+	return result
+}
+
+func TestClusterPartitionerAssignsEveryClusterOnce(t *testing.T) {
+	members := []string{"replica-a", "replica-b", "replica-c"}
+	names := clusters(300)
+
+	count := map[string]int{}
+	for _, member := range members {
+		p, err := NewClusterPartitioner(member, StaticMembers(members))
+		require.NoError(t, err)
+		for _, name := range names {
+			if p.Owns(name) {
+				count[member]++
+			}
+		}
+	}
+
+	total := 0
+	for _, member := range members {
+		require.Greater(t, count[member], 50, "replica %s owns too few clusters: %v", member, count)
+		total += count[member]
+	}
+	require.Equal(t, len(names), total)
+}
+
+func TestClusterPartitionerMinimalReshuffling(t *testing.T) {
+	names := clusters(300)
+	before := owners(t, []string{"replica-a", "replica-b", "replica-c"}, names)
+	after := owners(t, []string{"replica-a", "replica-b", "replica-c", "replica-d"}, names)
+
+	moved := 0
+	for _, name := range names {
+		if before[name] != after[name] {
+			require.Equal(t, "replica-d", after[name], "cluster %s moved between existing replicas", name)
+			moved++
+		}
+	}
+	require.Greater(t, moved, 0)
+	require.Less(t, moved, len(names)/2)
+
+	// Membership order and duplicates do not matter.
+	require.Equal(t, before, owners(t, []string{"replica-c", "replica-a", "replica-b", "replica-a"}, names))
+}
+
+func TestClusterPartitionerNotAMember(t *testing.T) {
+	p, err := NewClusterPartitioner("replica-x", StaticMembers{"replica-a"})
+	require.NoError(t, err)
+	for _, name := range clusters(10) {
+		require.False(t, p.Owns(name))
+	}
+
+	p, err = NewClusterPartitioner("replica-x", StaticMembers{})
+	require.NoError(t, err)
+	require.Equal(t, "", p.Owner("cluster-0"))
+}
+
+func TestLeaseMembers(t *testing.T) {
+	now := time.Now()
+	clock := clocktesting.NewFakePassiveClock(now)
+	leases := NewLocalLeases()
+	lease := func(name, holder string, renewed time.Time) *coordinationv1.Lease {
+		return &coordinationv1.Lease{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec: coordinationv1.LeaseSpec{
+				HolderIdentity:       ptr.To(holder),
+				LeaseDurationSeconds: ptr.To[int32](10),
+				RenewTime:            &metav1.MicroTime{Time: renewed},
+			},
+		}
+	}
+	leases.Update(lease("a", "replica-a", now))
+	leases.Update(lease("b", "replica-b", now.Add(-5*time.Second)))
+	leases.Update(lease("c", "replica-c", now.Add(-time.Minute)))
+	leases.Update(&coordinationv1.Lease{ObjectMeta: metav1.ObjectMeta{Name: "unheld"}})
+
+	p, err := NewClusterPartitioner("replica-a", LeaseMembers{Leases: leases, Clock: clock})
+	require.NoError(t, err)
+	require.Equal(t, []string{"replica-a", "replica-b"}, p.Members())
+
+	changes := 0
+	remove := p.AddChangeHandler(func() { changes++ })
+	clock.SetTime(now.Add(6 * time.Second))
+	require.NoError(t, p.Refresh())
+	require.Equal(t, []string{"replica-a"}, p.Members())
+	require.Equal(t, 1, changes)
+
+	require.NoError(t, p.Refresh())
+	require.Equal(t, 1, changes, "handlers must only be called on changes")
+
+	remove()
+	leases.Delete("a")
+	require.NoError(t, p.Refresh())
+	require.Empty(t, p.Members())
+	require.Equal(t, 1, changes)
+}
+
+func newUnstructured(cluster, name string) *unstructured.Unstructured {
+	u := new(unstructured.Unstructured)
+	u.SetAnnotations(map[string]string{logicalcluster.AnnotationKey: cluster})
+	u.SetName(name)
+	return u
+}
+
+// ownedAndNot returns a cluster owned by self and one that is not.
+func ownedAndNot(t *testing.T, p *ClusterPartitioner) (owned, notOwned logicalcluster.Name) {
+	t.Helper()
+	for _, name := range clusters(100) {
+		if p.Owns(name) && owned == "" {
+			owned = name
+		}
+		if !p.Owns(name) && notOwned == "" {
+			notOwned = name
+		}
+	}
+	require.NotEmpty(t, owned)
+	require.NotEmpty(t, notOwned)
+	return owned, notOwned
+}
+
+func TestClusterPartitionerEventHandler(t *testing.T) {
+	p, err := NewClusterPartitioner("replica-a", StaticMembers{"replica-a", "replica-b"})
+	require.NoError(t, err)
+	owned, notOwned := ownedAndNot(t, p)
+
+	var added, deleted []string
+	handler := p.EventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			added = append(added, obj.(*unstructured.Unstructured).GetName())
+		},
+		DeleteFunc: func(obj interface{}) {
+			deleted = append(deleted, obj.(cache.DeletedFinalStateUnknown).Key)
+		},
+	})
+	handler.OnAdd(newUnstructured(owned.String(), "owned"), false)
+	handler.OnAdd(newUnstructured(notOwned.String(), "not-owned"), false)
+	handler.OnDelete(cache.DeletedFinalStateUnknown{Key: kcpcache.ToClusterAwareKey(owned.String(), "", "owned")})
+	handler.OnDelete(cache.DeletedFinalStateUnknown{Key: kcpcache.ToClusterAwareKey(notOwned.String(), "", "not-owned")})
+
+	require.Equal(t, []string{"owned"}, added)
+	require.Equal(t, []string{kcpcache.ToClusterAwareKey(owned.String(), "", "owned")}, deleted)
+}
+
+func TestPartitionedQueue(t *testing.T) {
+	p, err := NewClusterPartitioner("replica-a", StaticMembers{"replica-a", "replica-b"})
+	require.NoError(t, err)
+	owned, notOwned := ownedAndNot(t, p)
+
+	queue := NewPartitionedQueue(kcpworkqueue.NewFairQueue(kcpworkqueue.FairQueueConfig{}), p)
+	defer queue.ShutDown()
+	queue.Add(kcpworkqueue.ClusterQueueKey{Cluster: owned, Name: "a"})
+	queue.Add(kcpworkqueue.ClusterQueueKey{Cluster: notOwned, Name: "b"})
+	queue.AddRateLimited(kcpworkqueue.ClusterQueueKey{Cluster: notOwned, Name: "c"})
+	require.Equal(t, 1, queue.Len())
+
+	store := cache.NewStore(kcpcache.MetaClusterNamespaceKeyFunc)
+	require.NoError(t, store.Add(newUnstructured(owned.String(), "d")))
+	require.NoError(t, store.Add(newUnstructured(notOwned.String(), "e")))
+	plain := workqueue.NewTyped[kcpworkqueue.ClusterQueueKey]()
+	defer plain.ShutDown()
+	p.EnqueueOwned(store, plain)
+	require.Equal(t, 1, plain.Len())
+	item, _ := plain.Get()
+	require.Equal(t, kcpworkqueue.ClusterQueueKey{Cluster: owned, Name: "d"}, item)
+}
+
+type testMembers struct {
+	lock    sync.Mutex
+	members []string
+}
+
+func (m *testMembers) Members() ([]string, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return m.members, nil
+}
+
+func (m *testMembers) set(members ...string) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.members = members
+}
+
+func newPod(cluster, name string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:   "ns",
+			Name:        name,
+			Annotations: map[string]string{logicalcluster.AnnotationKey: cluster},
+		},
+	}
+}
+
+func TestOwnedClusterSet(t *testing.T) {
+	members := &testMembers{}
+	members.set("replica-a", "replica-b")
+	p, err := NewClusterPartitioner("replica-a", members)
+	require.NoError(t, err)
+	owned, notOwned := ownedAndNot(t, p)
+
+	source := fcache.NewFakeControllerSource()
+	source.Add(newPod(owned.String(), "a"))
+	source.Add(newPod(notOwned.String(), "b"))
+	informer := informers.NewSharedIndexInformer(source, &corev1.Pod{}, 0, cache.Indexers{})
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		informer.RunWithContext(ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-stopped
+	})
+
+	set, remove, err := p.OwnedClusterSet(informer)
+	require.NoError(t, err)
+	defer remove()
+	// The scope is checked on delivery, wait for the owned cluster to join
+	// it before adding the handler, which would otherwise get its objects
+	// both from the initial list and from the join.
+	require.Eventually(t, func() bool {
+		return set.Clusters().Has(owned)
+	}, wait.ForeverTestTimeout, 10*time.Millisecond)
+
+	var lock sync.Mutex
+	var events []string
+	record := func(event string, obj interface{}) {
+		if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+			obj = tombstone.Obj
+		}
+		lock.Lock()
+		defer lock.Unlock()
+		events = append(events, event+" "+obj.(*corev1.Pod).Name)
+	}
+	recorded := func() []string {
+		lock.Lock()
+		defer lock.Unlock()
+		return append([]string(nil), events...)
+	}
+	registration, err := set.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(obj interface{}) { record("add", obj) },
+		DeleteFunc: func(obj interface{}) { record("delete", obj) },
+	})
+	require.NoError(t, err)
+	// The initial list also holds b, which is only dropped on delivery. Wait
+	// for it to be delivered, or b would be added twice once it is owned.
+	require.Eventually(t, registration.HasSynced, wait.ForeverTestTimeout, 10*time.Millisecond)
+	require.Equal(t, []string{"add a"}, recorded())
+
+	// The objects of a cluster are handed off when it changes owners.
+	members.set("replica-a")
+	require.NoError(t, p.Refresh())
+	require.Eventually(t, func() bool {
+		return len(recorded()) == 2
+	}, wait.ForeverTestTimeout, 10*time.Millisecond)
+	members.set("replica-b")
+	require.NoError(t, p.Refresh())
+	require.Eventually(t, func() bool {
+		return len(recorded()) == 4
+	}, wait.ForeverTestTimeout, 10*time.Millisecond)
+	require.Equal(t, "add b", recorded()[1])
+	require.ElementsMatch(t, []string{"delete a", "delete b"}, recorded()[2:])
+	require.Empty(t, set.Clusters().List())
+}
+
+func TestOwnedClusterSetUnsupported(t *testing.T) {
+	p, err := NewClusterPartitioner("replica-a", StaticMembers{"replica-a"})
+	require.NoError(t, err)
+	_, _, err = p.OwnedClusterSet(cache.NewSharedIndexInformer(fcache.NewFakeControllerSource(), &corev1.Pod{}, 0, cache.Indexers{}))
+	require.Error(t, err)
+}
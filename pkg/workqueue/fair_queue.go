@@ -0,0 +1,455 @@
+/*
+Copyright 2026 The kcp Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workqueue
+
+import (
+	"slices"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/utils/clock"
+
+	"github.com/kcp-dev/logicalcluster/v3"
+)
+
+// FairQueue is a rate limiting work queue that hands out the items of the
+// logical clusters in turn, so that a burst of items from one cluster does
+// not delay the items of all other clusters.
+type FairQueue interface {
+	workqueue.TypedRateLimitingInterface[ClusterQueueKey]
+
+	// ForgetCluster drops the queued items, the rate limiter and the metrics
+	// of cluster. Items being processed are not added again when they are
+	// done. Items still waiting in AddAfter or AddRateLimited are added once
+	// their delay has passed.
+	//
+	// Call it when the logical cluster has been deleted, e.g. from a
+	// kcpclient.OnClusterDeleted subscription.
+	ForgetCluster(cluster logicalcluster.Name)
+}
+
+// ClusterMetricsProvider creates the per-cluster metrics of a FairQueue.
+type ClusterMetricsProvider interface {
+	// NewClusterDepthMetric returns the gauge of the number of queued items
+	// of cluster in the queue called name.
+	NewClusterDepthMetric(name string, cluster logicalcluster.Name) workqueue.GaugeMetric
+	// DeleteClusterMetrics drops all metrics of cluster in the queue called
+	// name.
+	DeleteClusterMetrics(name string, cluster logicalcluster.Name)
+}
+
+// FairQueueConfig specifies optional configurations to customize a FairQueue.
+type FairQueueConfig struct {
+	// Name for the queue. If unnamed, the metrics will not be registered.
+	Name string
+
+	// MetricsProvider optionally allows specifying a metrics provider for the
+	// depth, adds, latency, work duration and retries metrics of the queue.
+	MetricsProvider workqueue.MetricsProvider
+
+	// ClusterMetricsProvider optionally allows specifying a metrics provider
+	// for the per-cluster metrics of the queue.
+	ClusterMetricsProvider ClusterMetricsProvider
+
+	// RateLimiter optionally returns the rate limiter for the items of a
+	// cluster. It is called once per cluster, until the cluster is forgotten.
+	// Defaults to workqueue.DefaultTypedControllerRateLimiter, so that every
+	// cluster gets its own overall retry budget.
+	RateLimiter func(cluster logicalcluster.Name) workqueue.TypedRateLimiter[ClusterQueueKey]
+
+	// Weight optionally returns how many items of a cluster are handed out
+	// in a row before the next cluster's turn. It is called whenever a
+	// cluster without queued items gets a new item. Defaults to 1; values
+	// below 1 are treated as 1.
+	Weight func(cluster logicalcluster.Name) int
+
+	// Clock optionally allows injecting a real or fake clock for testing purposes.
+	Clock clock.WithTicker
+}
+
+// NewFairQueue constructs a new FairQueue.
+// Remember to call Forget! If you don't, you may end up tracking failures forever.
+func NewFairQueue(config FairQueueConfig) FairQueue {
+	if config.Clock == nil {
+		config.Clock = clock.RealClock{}
+	}
+	if config.RateLimiter == nil {
+		config.RateLimiter = func(logicalcluster.Name) workqueue.TypedRateLimiter[ClusterQueueKey] {
+			return workqueue.DefaultTypedControllerRateLimiter[ClusterQueueKey]()
+		}
+	}
+
+	core := newFairCore(config)
+	rateLimiter := &clusterRateLimiter{
+		newLimiter: config.RateLimiter,
+		limiters:   map[logicalcluster.Name]workqueue.TypedRateLimiter[ClusterQueueKey]{},
+	}
+	delayingQueue := workqueue.NewTypedDelayingQueueWithConfig(workqueue.TypedDelayingQueueConfig[ClusterQueueKey]{
+		Name:            config.Name,
+		MetricsProvider: config.MetricsProvider,
+		Clock:           config.Clock,
+		Queue:           core,
+	})
+	return &fairQueue{
+		TypedRateLimitingInterface: workqueue.NewTypedRateLimitingQueueWithConfig[ClusterQueueKey](rateLimiter, workqueue.TypedRateLimitingQueueConfig[ClusterQueueKey]{
+			Name:            config.Name,
+			MetricsProvider: config.MetricsProvider,
+			Clock:           config.Clock,
+			DelayingQueue:   delayingQueue,
+		}),
+		core:        core,
+		rateLimiter: rateLimiter,
+	}
+}
+
+type fairQueue struct {
+	workqueue.TypedRateLimitingInterface[ClusterQueueKey]
+
+	core        *fairCore
+	rateLimiter *clusterRateLimiter
+}
+
+func (q *fairQueue) ForgetCluster(cluster logicalcluster.Name) {
+	q.core.forgetCluster(cluster)
+	q.rateLimiter.forgetCluster(cluster)
+}
+
+// fairCore is the workqueue.TypedInterface of a FairQueue. It has the same
+// semantics as the client-go queue: an item is queued at most once, and an
+// item added while it is processed is queued again when it is done. Only the
+// order differs: queued items are kept per cluster, and the clusters with
+// queued items take turns in a weighted round-robin.
+type fairCore struct {
+	cond   *sync.Cond
+	clock  clock.Clock
+	weight func(cluster logicalcluster.Name) int
+
+	// clusters holds the queued items of every cluster that has any.
+	clusters map[logicalcluster.Name]*clusterItems
+	// ring holds the clusters with queued items in their round-robin order.
+	// next is the index of the cluster whose turn it is.
+	ring []logicalcluster.Name
+	next int
+	len  int
+
+	// dirty and processing are the same as in the client-go queue: dirty
+	// holds the items that need processing, processing the items that are
+	// being processed. Every queued item is dirty and not processing.
+	dirty      sets.Set[ClusterQueueKey]
+	processing sets.Set[ClusterQueueKey]
+
+	shuttingDown bool
+	drain        bool
+
+	addTimes        map[ClusterQueueKey]time.Time
+	processingStart map[ClusterQueueKey]time.Time
+
+	name           string
+	depth          workqueue.GaugeMetric
+	adds           workqueue.CounterMetric
+	latency        workqueue.HistogramMetric
+	workDuration   workqueue.HistogramMetric
+	clusterMetrics ClusterMetricsProvider
+	clusterDepths  map[logicalcluster.Name]workqueue.GaugeMetric
+}
+
+// clusterItems are the queued items of one cluster.
+type clusterItems struct {
+	items  []ClusterQueueKey
+	weight int
+	// served counts the items handed out in the cluster's current turn.
+	served int
+}
+
+func newFairCore(config FairQueueConfig) *fairCore {
+	q := &fairCore{
+		cond:            sync.NewCond(&sync.Mutex{}),
+		clock:           config.Clock,
+		weight:          config.Weight,
+		clusters:        map[logicalcluster.Name]*clusterItems{},
+		dirty:           sets.New[ClusterQueueKey](),
+		processing:      sets.New[ClusterQueueKey](),
+		addTimes:        map[ClusterQueueKey]time.Time{},
+		processingStart: map[ClusterQueueKey]time.Time{},
+		name:            config.Name,
+		depth:           noopMetric{},
+		adds:            noopMetric{},
+		latency:         noopMetric{},
+		workDuration:    noopMetric{},
+		clusterDepths:   map[logicalcluster.Name]workqueue.GaugeMetric{},
+	}
+	if config.Name != "" {
+		if config.MetricsProvider != nil {
+			q.depth = config.MetricsProvider.NewDepthMetric(config.Name)
+			q.adds = config.MetricsProvider.NewAddsMetric(config.Name)
+			q.latency = config.MetricsProvider.NewLatencyMetric(config.Name)
+			q.workDuration = config.MetricsProvider.NewWorkDurationMetric(config.Name)
+		}
+		q.clusterMetrics = config.ClusterMetricsProvider
+	}
+	return q
+}
+
+func (q *fairCore) Add(item ClusterQueueKey) {
+	q.cond.L.Lock()
+	defer q.cond.L.Unlock()
+	if q.shuttingDown {
+		return
+	}
+	if q.dirty.Has(item) {
+		return
+	}
+
+	q.adds.Inc()
+	if _, ok := q.addTimes[item]; !ok {
+		q.addTimes[item] = q.clock.Now()
+	}
+	q.dirty.Insert(item)
+	if q.processing.Has(item) {
+		return
+	}
+
+	q.push(item)
+	q.cond.Signal()
+}
+
+func (q *fairCore) Len() int {
+	q.cond.L.Lock()
+	defer q.cond.L.Unlock()
+	return q.len
+}
+
+func (q *fairCore) Get() (item ClusterQueueKey, shutdown bool) {
+	q.cond.L.Lock()
+	defer q.cond.L.Unlock()
+	for q.len == 0 && !q.shuttingDown {
+		q.cond.Wait()
+	}
+	if q.len == 0 {
+		// We must be shutting down.
+		return ClusterQueueKey{}, true
+	}
+
+	item = q.pop()
+
+	now := q.clock.Now()
+	if start, ok := q.addTimes[item]; ok {
+		q.latency.Observe(now.Sub(start).Seconds())
+		delete(q.addTimes, item)
+	}
+	q.processingStart[item] = now
+	q.processing.Insert(item)
+	q.dirty.Delete(item)
+
+	return item, false
+}
+
+func (q *fairCore) Done(item ClusterQueueKey) {
+	q.cond.L.Lock()
+	defer q.cond.L.Unlock()
+
+	if start, ok := q.processingStart[item]; ok {
+		q.workDuration.Observe(q.clock.Since(start).Seconds())
+		delete(q.processingStart, item)
+	}
+
+	q.processing.Delete(item)
+	if q.dirty.Has(item) {
+		q.push(item)
+		q.cond.Signal()
+	} else if q.processing.Len() == 0 {
+		q.cond.Broadcast()
+	}
+}
+
+func (q *fairCore) ShutDown() {
+	q.cond.L.Lock()
+	defer q.cond.L.Unlock()
+
+	q.drain = false
+	q.shuttingDown = true
+	q.cond.Broadcast()
+}
+
+func (q *fairCore) ShutDownWithDrain() {
+	q.cond.L.Lock()
+	defer q.cond.L.Unlock()
+
+	q.drain = true
+	q.shuttingDown = true
+	q.cond.Broadcast()
+
+	for q.processing.Len() != 0 && q.drain {
+		q.cond.Wait()
+	}
+}
+
+func (q *fairCore) ShuttingDown() bool {
+	q.cond.L.Lock()
+	defer q.cond.L.Unlock()
+
+	return q.shuttingDown
+}
+
+// push queues item behind the other items of its cluster. A cluster without
+// queued items joins the round-robin as the last one. Must be called with
+// the lock held.
+func (q *fairCore) push(item ClusterQueueKey) {
+	c, ok := q.clusters[item.Cluster]
+	if !ok {
+		c = &clusterItems{weight: 1}
+		if q.weight != nil {
+			c.weight = max(q.weight(item.Cluster), 1)
+		}
+		q.clusters[item.Cluster] = c
+		q.ring = append(q.ring, item.Cluster)
+	}
+	c.items = append(c.items, item)
+	q.len++
+	q.depth.Inc()
+	q.clusterDepth(item.Cluster).Inc()
+}
+
+// pop returns the next item of the cluster whose turn it is, and moves on to
+// the next cluster once the cluster has had weight items in a row or has no
+// more. Must be called with the lock held and items queued.
+func (q *fairCore) pop() ClusterQueueKey {
+	cluster := q.ring[q.next]
+	c := q.clusters[cluster]
+	item := c.items[0]
+	c.items[0] = ClusterQueueKey{}
+	c.items = c.items[1:]
+	c.served++
+	q.len--
+	q.depth.Dec()
+	q.clusterDepth(cluster).Dec()
+
+	switch {
+	case len(c.items) == 0:
+		delete(q.clusters, cluster)
+		q.ring = slices.Delete(q.ring, q.next, q.next+1)
+	case c.served >= c.weight:
+		c.served = 0
+		q.next++
+	}
+	if q.next >= len(q.ring) {
+		q.next = 0
+	}
+	return item
+}
+
+func (q *fairCore) forgetCluster(cluster logicalcluster.Name) {
+	q.cond.L.Lock()
+	defer q.cond.L.Unlock()
+
+	if c, ok := q.clusters[cluster]; ok {
+		for _, item := range c.items {
+			q.dirty.Delete(item)
+			delete(q.addTimes, item)
+			q.depth.Dec()
+		}
+		q.len -= len(c.items)
+		delete(q.clusters, cluster)
+
+		i := slices.Index(q.ring, cluster)
+		q.ring = slices.Delete(q.ring, i, i+1)
+		if i < q.next {
+			q.next--
+		}
+		if q.next >= len(q.ring) {
+			q.next = 0
+		}
+	}
+	for item := range q.processing {
+		if item.Cluster == cluster {
+			q.dirty.Delete(item)
+			delete(q.addTimes, item)
+		}
+	}
+
+	if _, ok := q.clusterDepths[cluster]; ok {
+		delete(q.clusterDepths, cluster)
+		q.clusterMetrics.DeleteClusterMetrics(q.name, cluster)
+	}
+}
+
+// clusterDepth returns the depth metric of cluster. Must be called with the
+// lock held.
+func (q *fairCore) clusterDepth(cluster logicalcluster.Name) workqueue.GaugeMetric {
+	if q.clusterMetrics == nil {
+		return noopMetric{}
+	}
+	m, ok := q.clusterDepths[cluster]
+	if !ok {
+		m = q.clusterMetrics.NewClusterDepthMetric(q.name, cluster)
+		q.clusterDepths[cluster] = m
+	}
+	return m
+}
+
+// clusterRateLimiter keeps a separate rate limiter per cluster.
+type clusterRateLimiter struct {
+	newLimiter func(cluster logicalcluster.Name) workqueue.TypedRateLimiter[ClusterQueueKey]
+
+	lock     sync.Mutex
+	limiters map[logicalcluster.Name]workqueue.TypedRateLimiter[ClusterQueueKey]
+}
+
+func (r *clusterRateLimiter) When(item ClusterQueueKey) time.Duration {
+	return r.limiter(item.Cluster, true).When(item)
+}
+
+func (r *clusterRateLimiter) Forget(item ClusterQueueKey) {
+	if l := r.limiter(item.Cluster, false); l != nil {
+		l.Forget(item)
+	}
+}
+
+func (r *clusterRateLimiter) NumRequeues(item ClusterQueueKey) int {
+	if l := r.limiter(item.Cluster, false); l != nil {
+		return l.NumRequeues(item)
+	}
+	return 0
+}
+
+// limiter returns the rate limiter of cluster, creating it if create is set.
+func (r *clusterRateLimiter) limiter(cluster logicalcluster.Name, create bool) workqueue.TypedRateLimiter[ClusterQueueKey] {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	l, ok := r.limiters[cluster]
+	if !ok && create {
+		l = r.newLimiter(cluster)
+		r.limiters[cluster] = l
+	}
+	return l
+}
+
+func (r *clusterRateLimiter) forgetCluster(cluster logicalcluster.Name) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	delete(r.limiters, cluster)
+}
+
+type noopMetric struct{}
+
+func (noopMetric) Inc()            {}
+func (noopMetric) Dec()            {}
+func (noopMetric) Observe(float64) {}
@@ -0,0 +1,211 @@
+/*
+Copyright 2026 The kcp Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workqueue
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"k8s.io/client-go/util/workqueue"
+
+	"github.com/kcp-dev/logicalcluster/v3"
+)
+
+func key(cluster, name string) ClusterQueueKey {
+	return ClusterQueueKey{Cluster: logicalcluster.Name(cluster), Name: name}
+}
+
+func drain(t *testing.T, q FairQueue) []ClusterQueueKey {
+	t.Helper()
+	var got []ClusterQueueKey
+	for q.Len() > 0 {
+		item, shutdown := q.Get()
+		require.False(t, shutdown)
+		got = append(got, item)
+		q.Done(item)
+	}
+	return got
+}
+
+func TestFairQueueRoundRobin(t *testing.T) {
+	q := NewFairQueue(FairQueueConfig{})
+	defer q.ShutDown()
+
+	for _, name := range []string{"a1", "a2", "a3", "a4"} {
+		q.Add(key("busy", name))
+	}
+	q.Add(key("quiet", "b1"))
+	q.Add(key("other", "c1"))
+	q.Add(key("other", "c2"))
+
+	require.Equal(t, []ClusterQueueKey{
+		key("busy", "a1"),
+		key("quiet", "b1"),
+		key("other", "c1"),
+		key("busy", "a2"),
+		key("other", "c2"),
+		key("busy", "a3"),
+		key("busy", "a4"),
+	}, drain(t, q))
+}
+
+func TestFairQueueWeights(t *testing.T) {
+	q := NewFairQueue(FairQueueConfig{
+		Weight: func(cluster logicalcluster.Name) int {
+			if cluster == "heavy" {
+				return 2
+			}
+			return 0
+		},
+	})
+	defer q.ShutDown()
+
+	for _, name := range []string{"h1", "h2", "h3"} {
+		q.Add(key("heavy", name))
+	}
+	for _, name := range []string{"l1", "l2"} {
+		q.Add(key("light", name))
+	}
+
+	require.Equal(t, []ClusterQueueKey{
+		key("heavy", "h1"),
+		key("heavy", "h2"),
+		key("light", "l1"),
+		key("heavy", "h3"),
+		key("light", "l2"),
+	}, drain(t, q))
+}
+
+func TestFairQueueDeduplicates(t *testing.T) {
+	q := NewFairQueue(FairQueueConfig{})
+	defer q.ShutDown()
+
+	q.Add(key("c1", "n1"))
+	q.Add(key("c1", "n1"))
+	require.Equal(t, 1, q.Len())
+
+	item, _ := q.Get()
+	q.Add(item)
+	require.Equal(t, 0, q.Len(), "item being processed must not be queued")
+	q.Done(item)
+	require.Equal(t, 1, q.Len(), "item added while processing must be queued when done")
+}
+
+func TestFairQueueForgetCluster(t *testing.T) {
+	metrics := newTestClusterMetrics()
+	rateLimiters := map[logicalcluster.Name]int{}
+	q := NewFairQueue(FairQueueConfig{
+		Name:                   "test",
+		ClusterMetricsProvider: metrics,
+		RateLimiter: func(cluster logicalcluster.Name) workqueue.TypedRateLimiter[ClusterQueueKey] {
+			rateLimiters[cluster]++
+			return workqueue.NewTypedItemExponentialFailureRateLimiter[ClusterQueueKey](time.Hour, time.Hour)
+		},
+	})
+	defer q.ShutDown()
+
+	q.Add(key("gone", "n1"))
+	q.Add(key("gone", "n2"))
+	q.Add(key("kept", "n1"))
+	processing, _ := q.Get()
+	require.Equal(t, key("gone", "n1"), processing)
+	q.Add(processing)
+	q.AddRateLimited(key("gone", "n3"))
+	require.Equal(t, 1, q.NumRequeues(key("gone", "n3")))
+	require.Equal(t, 1, metrics.depth("gone"))
+	require.Equal(t, 1, metrics.depth("kept"))
+
+	q.ForgetCluster("gone")
+	q.Done(processing)
+
+	require.Equal(t, 0, q.NumRequeues(key("gone", "n3")))
+	require.False(t, metrics.has("gone"))
+	require.Equal(t, []ClusterQueueKey{key("kept", "n1")}, drain(t, q))
+
+	q.AddRateLimited(key("gone", "n4"))
+	require.Equal(t, 2, rateLimiters["gone"], "a forgotten cluster must get a new rate limiter")
+}
+
+func TestFairQueueShutDownWithDrain(t *testing.T) {
+	q := NewFairQueue(FairQueueConfig{})
+
+	q.Add(key("c1", "n1"))
+	item, _ := q.Get()
+
+	drained := make(chan struct{})
+	go func() {
+		defer close(drained)
+		q.ShutDownWithDrain()
+	}()
+
+	select {
+	case <-drained:
+		t.Fatal("ShutDownWithDrain returned while an item is processed")
+	case <-time.After(50 * time.Millisecond):
+	}
+	q.Done(item)
+	<-drained
+
+	_, shutdown := q.Get()
+	require.True(t, shutdown)
+}
+
+type testClusterMetrics struct {
+	lock   sync.Mutex
+	depths map[logicalcluster.Name]*testGauge
+}
+
+func newTestClusterMetrics() *testClusterMetrics {
+	return &testClusterMetrics{depths: map[logicalcluster.Name]*testGauge{}}
+}
+
+func (m *testClusterMetrics) NewClusterDepthMetric(_ string, cluster logicalcluster.Name) workqueue.GaugeMetric {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	g := &testGauge{}
+	m.depths[cluster] = g
+	return g
+}
+
+func (m *testClusterMetrics) DeleteClusterMetrics(_ string, cluster logicalcluster.Name) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	delete(m.depths, cluster)
+}
+
+func (m *testClusterMetrics) depth(cluster logicalcluster.Name) int {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return m.depths[cluster].value
+}
+
+func (m *testClusterMetrics) has(cluster logicalcluster.Name) bool {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	_, ok := m.depths[cluster]
+	return ok
+}
+
+type testGauge struct {
+	value int
+}
+
+func (g *testGauge) Inc() { g.value++ }
+func (g *testGauge) Dec() { g.value-- }
@@ -0,0 +1,141 @@
+/*
+Copyright 2026 The kcp Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workqueue
+
+import (
+	"fmt"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+
+	kcpcache "github.com/kcp-dev/apimachinery/v2/pkg/cache"
+	"github.com/kcp-dev/logicalcluster/v3"
+)
+
+// ClusterQueueKey identifies an object in a logical cluster. It is comparable
+// and meant to be used as the item type of the typed client-go work queues,
+// e.g. workqueue.TypedRateLimitingInterface[ClusterQueueKey], instead of
+// string keys that have to be split again by every worker.
+type ClusterQueueKey struct {
+	Cluster   logicalcluster.Name
+	Namespace string
+	Name      string
+}
+
+// String returns the key as formatted by kcpcache.MetaClusterNamespaceKeyFunc.
+func (k ClusterQueueKey) String() string {
+	return kcpcache.ClusterKey(k).String()
+}
+
+// ClusterQueueKeyFor returns the queue key of obj. obj can be an object
+// implementing metav1.Object, a cache.DeletedFinalStateUnknown tombstone, or
+// a cache.ExplicitKey.
+func ClusterQueueKeyFor(obj interface{}) (ClusterQueueKey, error) {
+	switch t := obj.(type) {
+	case cache.DeletedFinalStateUnknown:
+		return ParseClusterQueueKey(t.Key)
+	case cache.ExplicitKey:
+		return ParseClusterQueueKey(string(t))
+	}
+	m, err := meta.Accessor(obj)
+	if err != nil {
+		return ClusterQueueKey{}, fmt.Errorf("object has no meta: %v", err)
+	}
+	return ClusterQueueKey{Cluster: logicalcluster.From(m), Namespace: m.GetNamespace(), Name: m.GetName()}, nil
+}
+
+// ParseClusterQueueKey returns the queue key for a key formatted by
+// kcpcache.MetaClusterNamespaceKeyFunc or
+// kcpcache.MetaShardClusterNamespaceKeyFunc. The shard is dropped, as queue
+// keys identify objects by their logical cluster.
+func ParseClusterQueueKey(key string) (ClusterQueueKey, error) {
+	_, clusterName, namespace, name, err := kcpcache.SplitMetaShardClusterNamespaceKey(key)
+	if err != nil {
+		return ClusterQueueKey{}, err
+	}
+	return ClusterQueueKey{Cluster: clusterName, Namespace: namespace, Name: name}, nil
+}
+
+// Enqueue adds the key of obj to queue. See ClusterQueueKeyFor for the
+// accepted objects.
+func Enqueue(queue workqueue.TypedInterface[ClusterQueueKey], obj interface{}) error {
+	key, err := ClusterQueueKeyFor(obj)
+	if err != nil {
+		return err
+	}
+	queue.Add(key)
+	return nil
+}
+
+// EnqueueAfter adds the key of obj to queue after duration has passed. See
+// ClusterQueueKeyFor for the accepted objects.
+func EnqueueAfter(queue workqueue.TypedDelayingInterface[ClusterQueueKey], obj interface{}, duration time.Duration) error {
+	key, err := ClusterQueueKeyFor(obj)
+	if err != nil {
+		return err
+	}
+	queue.AddAfter(key, duration)
+	return nil
+}
+
+// EnqueueRateLimited adds the key of obj to queue after the rate limiter
+// says it is ok. See ClusterQueueKeyFor for the accepted objects.
+func EnqueueRateLimited(queue workqueue.TypedRateLimitingInterface[ClusterQueueKey], obj interface{}) error {
+	key, err := ClusterQueueKeyFor(obj)
+	if err != nil {
+		return err
+	}
+	queue.AddRateLimited(key)
+	return nil
+}
+
+// NewEnqueueHandler returns an event handler that adds the key of every
+// added, updated or deleted object to queue. Objects without a key are
+// reported through utilruntime.HandleError.
+func NewEnqueueHandler(queue workqueue.TypedInterface[ClusterQueueKey]) cache.ResourceEventHandlerFuncs {
+	enqueue := func(obj interface{}) {
+		if err := Enqueue(queue, obj); err != nil {
+			utilruntime.HandleError(fmt.Errorf("failed to enqueue object: %w", err))
+		}
+	}
+	return cache.ResourceEventHandlerFuncs{
+		AddFunc: enqueue,
+		UpdateFunc: func(_, obj interface{}) {
+			enqueue(obj)
+		},
+		DeleteFunc: enqueue,
+	}
+}
+
+// Get returns the object identified by key from lister.
+func Get(lister kcpcache.GenericClusterLister, key ClusterQueueKey) (runtime.Object, error) {
+	clusterLister := lister.ByCluster(key.Cluster)
+	if key.Namespace == "" {
+		return clusterLister.Get(key.Name)
+	}
+	return clusterLister.ByNamespace(key.Namespace).Get(key.Name)
+}
+
+// GetByKey returns the object identified by key from indexer, and whether
+// it exists.
+func GetByKey(indexer cache.Indexer, key ClusterQueueKey) (item interface{}, exists bool, err error) {
+	return indexer.GetByKey(key.String())
+}
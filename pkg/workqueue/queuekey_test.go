@@ -0,0 +1,142 @@
+/*
+Copyright 2026 The kcp Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workqueue
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+
+	kcpcache "github.com/kcp-dev/apimachinery/v2/pkg/cache"
+	"github.com/kcp-dev/logicalcluster/v3"
+)
+
+func newUnstructured(cluster, namespace, name string) *unstructured.Unstructured {
+	u := new(unstructured.Unstructured)
+	u.SetAnnotations(map[string]string{
+		logicalcluster.AnnotationKey: cluster,
+	})
+	u.SetNamespace(namespace)
+	u.SetName(name)
+	return u
+}
+
+func TestClusterQueueKeyFor(t *testing.T) {
+	tests := map[string]struct {
+		obj     interface{}
+		want    ClusterQueueKey
+		wantErr bool
+	}{
+		"namespaced object": {
+			obj:  newUnstructured("c1", "ns1", "n1"),
+			want: ClusterQueueKey{Cluster: "c1", Namespace: "ns1", Name: "n1"},
+		},
+		"cluster-scoped object": {
+			obj:  newUnstructured("c1", "", "n1"),
+			want: ClusterQueueKey{Cluster: "c1", Name: "n1"},
+		},
+		"tombstone": {
+			obj:  cache.DeletedFinalStateUnknown{Key: "c1|ns1/n1", Obj: newUnstructured("c1", "ns1", "n1")},
+			want: ClusterQueueKey{Cluster: "c1", Namespace: "ns1", Name: "n1"},
+		},
+		"tombstone with shard": {
+			obj:  cache.DeletedFinalStateUnknown{Key: "s1|c1|ns1/n1", Obj: newUnstructured("c1", "ns1", "n1")},
+			want: ClusterQueueKey{Cluster: "c1", Namespace: "ns1", Name: "n1"},
+		},
+		"explicit key": {
+			obj:  cache.ExplicitKey("c1|n1"),
+			want: ClusterQueueKey{Cluster: "c1", Name: "n1"},
+		},
+		"invalid explicit key": {
+			obj:     cache.ExplicitKey("s1|c1|c2|n1"),
+			wantErr: true,
+		},
+		"invalid object": {
+			obj:     "invalid",
+			wantErr: true,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := ClusterQueueKeyFor(tt.obj)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestClusterQueueKeyString(t *testing.T) {
+	obj := newUnstructured("c1", "ns1", "n1")
+	want, err := kcpcache.MetaClusterNamespaceKeyFunc(obj)
+	require.NoError(t, err)
+
+	key, err := ClusterQueueKeyFor(obj)
+	require.NoError(t, err)
+	require.Equal(t, want, key.String())
+
+	parsed, err := ParseClusterQueueKey(key.String())
+	require.NoError(t, err)
+	require.Equal(t, key, parsed)
+}
+
+func TestEnqueueHandler(t *testing.T) {
+	queue := workqueue.NewTypedRateLimitingQueue(workqueue.DefaultTypedControllerRateLimiter[ClusterQueueKey]())
+	defer queue.ShutDown()
+
+	handler := NewEnqueueHandler(queue)
+	handler.OnAdd(newUnstructured("c1", "ns1", "n1"), true)
+	handler.OnUpdate(newUnstructured("c1", "ns1", "n1"), newUnstructured("c1", "ns1", "n1"))
+	handler.OnDelete(cache.DeletedFinalStateUnknown{Key: "c2|n2"})
+
+	require.Equal(t, 2, queue.Len())
+	first, _ := queue.Get()
+	second, _ := queue.Get()
+	require.Equal(t, ClusterQueueKey{Cluster: "c1", Namespace: "ns1", Name: "n1"}, first)
+	require.Equal(t, ClusterQueueKey{Cluster: "c2", Name: "n2"}, second)
+}
+
+func TestGet(t *testing.T) {
+	indexer := cache.NewIndexer(kcpcache.MetaClusterNamespaceKeyFunc, cache.Indexers{})
+	require.NoError(t, indexer.Add(newUnstructured("c1", "ns1", "n1")))
+	require.NoError(t, indexer.Add(newUnstructured("c1", "", "n1")))
+	lister := kcpcache.NewGenericClusterLister(indexer, schema.GroupResource{Resource: "things"})
+
+	obj, err := Get(lister, ClusterQueueKey{Cluster: "c1", Namespace: "ns1", Name: "n1"})
+	require.NoError(t, err)
+	require.Equal(t, "ns1", obj.(*unstructured.Unstructured).GetNamespace())
+
+	obj, err = Get(lister, ClusterQueueKey{Cluster: "c1", Name: "n1"})
+	require.NoError(t, err)
+	require.Equal(t, "", obj.(*unstructured.Unstructured).GetNamespace())
+
+	_, err = Get(lister, ClusterQueueKey{Cluster: "c2", Name: "n1"})
+	require.True(t, errors.IsNotFound(err))
+
+	_, exists, err := GetByKey(indexer, ClusterQueueKey{Cluster: "c1", Namespace: "ns1", Name: "n1"})
+	require.NoError(t, err)
+	require.True(t, exists)
+}
@@ -0,0 +1,219 @@
+/*
+Copyright 2026 The kcp Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package informers
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/tools/cache"
+
+	kcpcache "github.com/kcp-dev/apimachinery/v2/pkg/cache"
+	"github.com/kcp-dev/logicalcluster/v3"
+)
+
+// clusterLifecycleTracker notifies ClusterLifecycleHandlers when the first
+// object of a logical cluster is added to the informer's cache and when the
+// last one is removed. Whether a cluster holds objects is read from the
+// ClusterIndexName index of the cache. It is only created once the first
+// handler is registered, so informers without lifecycle handlers pay nothing
+// for it.
+//
+// observeAdd, observeDelete and the handlers set must be used with the
+// informer's blockDeltas held; this is what orders the notifications with
+// respect to the cache contents. The handlers are called in that order on a
+// goroutine of their own, so that they do not block event distribution.
+type clusterLifecycleTracker struct {
+	indexer cache.Indexer
+	// clusters are the clusters handlers were last notified as added.
+	clusters sets.Set[logicalcluster.Name]
+	handlers map[*clusterLifecycleRegistration]struct{}
+
+	// lock guards pending and delivering.
+	lock    sync.Mutex
+	pending []clusterLifecycleNotification
+	// delivering is set while a goroutine delivers pending notifications.
+	delivering bool
+}
+
+type clusterLifecycleRegistration struct {
+	handler kcpcache.ClusterLifecycleHandler
+	removed atomic.Bool
+}
+
+// clusterLifecycleNotification is a notification for the handlers that were
+// registered when it was queued.
+type clusterLifecycleNotification struct {
+	clusterName logicalcluster.Name
+	added       bool
+	handlers    []*clusterLifecycleRegistration
+}
+
+func newClusterLifecycleTracker(indexer cache.Indexer) *clusterLifecycleTracker {
+	t := &clusterLifecycleTracker{
+		indexer:  indexer,
+		clusters: sets.New[logicalcluster.Name](),
+		handlers: map[*clusterLifecycleRegistration]struct{}{},
+	}
+	for _, value := range indexer.ListIndexFuncValues(kcpcache.ClusterIndexName) {
+		t.clusters.Insert(logicalcluster.Name(value))
+	}
+	return t
+}
+
+// observeAdd is called for every object newly added to the cache.
+func (t *clusterLifecycleTracker) observeAdd(obj interface{}) {
+	clusterName, ok := clusterNameOf(obj)
+	if !ok || t.clusters.Has(clusterName) {
+		return
+	}
+	t.clusters.Insert(clusterName)
+	t.notify(clusterName, true, t.registrations())
+}
+
+// observeDelete is called for every object removed from the cache. Deletes
+// for clusters that still hold objects, or that were already notified as
+// removed, are ignored.
+func (t *clusterLifecycleTracker) observeDelete(obj interface{}) {
+	clusterName, ok := clusterNameOf(obj)
+	if !ok || !t.clusters.Has(clusterName) {
+		return
+	}
+	keys, err := t.indexer.IndexKeys(kcpcache.ClusterIndexName, kcpcache.ClusterIndexKey(clusterName))
+	if err != nil {
+		utilruntime.HandleError(err)
+		return
+	}
+	if len(keys) > 0 {
+		return
+	}
+	t.clusters.Delete(clusterName)
+	t.notify(clusterName, false, t.registrations())
+}
+
+func (t *clusterLifecycleTracker) registrations() []*clusterLifecycleRegistration {
+	registrations := make([]*clusterLifecycleRegistration, 0, len(t.handlers))
+	for registration := range t.handlers {
+		registrations = append(registrations, registration)
+	}
+	return registrations
+}
+
+// notify queues a notification for handlers, and starts delivering the
+// queue if no goroutine does already.
+func (t *clusterLifecycleTracker) notify(clusterName logicalcluster.Name, added bool, handlers []*clusterLifecycleRegistration) {
+	if len(handlers) == 0 {
+		return
+	}
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.pending = append(t.pending, clusterLifecycleNotification{clusterName: clusterName, added: added, handlers: handlers})
+	if !t.delivering {
+		t.delivering = true
+		go t.deliver()
+	}
+}
+
+// deliver calls the handlers of the pending notifications in order, until
+// none are left.
+func (t *clusterLifecycleTracker) deliver() {
+	for {
+		t.lock.Lock()
+		notifications := t.pending
+		t.pending = nil
+		if len(notifications) == 0 {
+			t.delivering = false
+		}
+		t.lock.Unlock()
+		if len(notifications) == 0 {
+			return
+		}
+
+		for _, notification := range notifications {
+			for _, registration := range notification.handlers {
+				if registration.removed.Load() {
+					continue
+				}
+				if notification.added {
+					registration.handler.OnClusterAdded(notification.clusterName)
+				} else {
+					registration.handler.OnClusterRemoved(notification.clusterName)
+				}
+			}
+		}
+	}
+}
+
+// clusterNameOf returns the logical cluster of obj, which may be a
+// cache.DeletedFinalStateUnknown keyed with or without a shard.
+func clusterNameOf(obj interface{}) (logicalcluster.Name, bool) {
+	clusterName, _, ok := clusterAndNamespaceOf(obj)
+	return clusterName, ok
+}
+
+// clusterAndNamespaceOf is like clusterNameOf, but also returns the namespace
+// of obj.
+func clusterAndNamespaceOf(obj interface{}) (logicalcluster.Name, string, bool) {
+	key, err := kcpcache.DeletionHandlingMetaClusterNamespaceKeyFunc(obj)
+	if err != nil {
+		return "", "", false
+	}
+	_, clusterName, namespace, _, err := kcpcache.SplitMetaShardClusterNamespaceKey(key)
+	if err != nil || clusterName.Empty() {
+		return "", "", false
+	}
+	return clusterName, namespace, true
+}
+
+// AddClusterLifecycleHandler registers a handler that is notified when the
+// first object of a logical cluster is added to the informer's cache and
+// when the last one is removed. Handlers are called in order on a goroutine
+// of their own, outside of event distribution, so by the time a handler is
+// called the cache may have changed again. A removed handler is not called
+// anymore, except for a notification that is being delivered to it already.
+func (s *sharedIndexInformer) AddClusterLifecycleHandler(handler kcpcache.ClusterLifecycleHandler) (remove func(), err error) {
+	s.startedLock.Lock()
+	defer s.startedLock.Unlock()
+
+	if s.stopped {
+		return nil, fmt.Errorf("cluster lifecycle handler %v was not added to shared informer because it has stopped already", handler)
+	}
+
+	s.blockDeltas.Lock()
+	defer s.blockDeltas.Unlock()
+
+	if s.clusterLifecycle == nil {
+		s.clusterLifecycle = newClusterLifecycleTracker(s.indexer)
+	}
+
+	registration := &clusterLifecycleRegistration{handler: handler}
+	s.clusterLifecycle.handlers[registration] = struct{}{}
+	for clusterName := range s.clusterLifecycle.clusters {
+		s.clusterLifecycle.notify(clusterName, true, []*clusterLifecycleRegistration{registration})
+	}
+
+	return func() {
+		registration.removed.Store(true)
+		s.blockDeltas.Lock()
+		defer s.blockDeltas.Unlock()
+		delete(s.clusterLifecycle.handlers, registration)
+	}, nil
+}
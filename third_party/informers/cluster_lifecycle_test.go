@@ -0,0 +1,138 @@
+/*
+Copyright 2026 The kcp Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package informers
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+	fcache "k8s.io/client-go/tools/cache/testing"
+
+	"github.com/kcp-dev/logicalcluster/v3"
+)
+
+// recordingLifecycleHandler records the cluster lifecycle notifications it
+// receives. If gate is not nil, every notification waits for it to be
+// closed.
+type recordingLifecycleHandler struct {
+	gate chan struct{}
+
+	lock   sync.Mutex
+	events []string
+}
+
+func (h *recordingLifecycleHandler) record(event string) {
+	if h.gate != nil {
+		<-h.gate
+	}
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.events = append(h.events, event)
+}
+
+func (h *recordingLifecycleHandler) recorded() []string {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	return append([]string(nil), h.events...)
+}
+
+func (h *recordingLifecycleHandler) OnClusterAdded(clusterName logicalcluster.Name) {
+	h.record("added " + clusterName.String())
+}
+
+func (h *recordingLifecycleHandler) OnClusterRemoved(clusterName logicalcluster.Name) {
+	h.record("removed " + clusterName.String())
+}
+
+func (h *recordingLifecycleHandler) waitFor(t *testing.T, n int) []string {
+	t.Helper()
+	require.Eventually(t, func() bool {
+		return len(h.recorded()) >= n
+	}, wait.ForeverTestTimeout, 10*time.Millisecond)
+	return h.recorded()
+}
+
+func TestClusterLifecycleHandler(t *testing.T) {
+	source := fcache.NewFakeControllerSource()
+	source.Add(newPod("c1", "a", ""))
+	source.Add(newPod("c1", "b", ""))
+	informer, _, _ := startInformer(t, source)
+
+	handler := &recordingLifecycleHandler{}
+	_, err := informer.AddClusterLifecycleHandler(handler)
+	require.NoError(t, err)
+	require.Equal(t, []string{"added c1"}, handler.waitFor(t, 1))
+
+	source.Add(newPod("c2", "a", ""))
+	source.Delete(newPod("c1", "a", ""))
+	source.Delete(newPod("c1", "b", ""))
+	require.Equal(t, []string{"added c1", "added c2", "removed c1"}, handler.waitFor(t, 3))
+
+	// Purging a cluster deletes all of its objects, but removes it once.
+	source.Add(newPod("c2", "b", ""))
+	require.Eventually(t, func() bool {
+		return clusterKeys(informer, "c2") == 2
+	}, wait.ForeverTestTimeout, 10*time.Millisecond)
+	require.NoError(t, informer.PurgeCluster("c2"))
+	require.Equal(t, []string{"added c1", "added c2", "removed c1", "removed c2"}, handler.waitFor(t, 4))
+	require.Never(t, func() bool {
+		return len(handler.recorded()) > 4
+	}, 100*time.Millisecond, 10*time.Millisecond)
+}
+
+func TestClusterLifecycleHandlerDoesNotBlockDistribution(t *testing.T) {
+	source := fcache.NewFakeControllerSource()
+	source.Add(newPod("c1", "a", ""))
+	informer, _, _ := startInformer(t, source)
+
+	stuck := &recordingLifecycleHandler{gate: make(chan struct{})}
+	t.Cleanup(func() {
+		select {
+		case <-stuck.gate:
+		default:
+			close(stuck.gate)
+		}
+	})
+	remove, err := informer.AddClusterLifecycleHandler(stuck)
+	require.NoError(t, err)
+
+	// Objects are still distributed, and handlers added, while the
+	// lifecycle handler is stuck.
+	handler := newRecordingHandler(false)
+	_, err = informer.AddEventHandler(handler)
+	require.NoError(t, err)
+	source.Add(newPod("c2", "b", ""))
+	source.Delete(newPod("c1", "a", ""))
+	require.Eventually(t, func() bool {
+		return len(handler.recorded()) == 3
+	}, wait.ForeverTestTimeout, 10*time.Millisecond)
+
+	// The stuck handler gets its notifications in order once released, and
+	// a removed handler gets none.
+	removed := &recordingLifecycleHandler{}
+	removeOther, err := informer.AddClusterLifecycleHandler(removed)
+	require.NoError(t, err)
+	removeOther()
+	close(stuck.gate)
+	require.Equal(t, []string{"added c1", "added c2", "removed c1"}, stuck.waitFor(t, 3))
+	require.Empty(t, removed.recorded())
+	remove()
+}
@@ -0,0 +1,247 @@
+/*
+Copyright 2026 The kcp Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package informers
+
+import (
+	"context"
+	"fmt"
+
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+
+	kcpcache "github.com/kcp-dev/apimachinery/v2/pkg/cache"
+	"github.com/kcp-dev/logicalcluster/v3"
+)
+
+// clusterReflector is the reflector of one logical cluster in lazy mode. It
+// implements cache.DoneChecker for the cluster's initial list.
+type clusterReflector struct {
+	clusterName logicalcluster.Name
+	// refs counts the scoped informers holding this reflector.
+	refs int
+	// pinned is set once Cluster took its reference, which is never
+	// released.
+	pinned bool
+	// previous is closed once the previous reflector of the cluster has
+	// stopped and the cluster has been purged. The reflector only starts
+	// then, so that the purge cannot remove what it lists.
+	previous <-chan struct{}
+	// purged is closed once the reflector has stopped after its last
+	// release, and the cluster has been purged.
+	purged chan struct{}
+
+	// queue, cancel and done are set once the reflector is started.
+	queue  cache.Queue
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	// synced is closed once the reflector's initial list was processed.
+	synced chan struct{}
+}
+
+func (r *clusterReflector) Name() string {
+	return fmt.Sprintf("reflector for cluster %s", r.clusterName)
+}
+
+func (r *clusterReflector) Done() <-chan struct{} {
+	return r.synced
+}
+
+// acquireClusterReflector takes a reference on the reflector of clusterName,
+// creating it if needed. It is started right away if the informer is running,
+// and otherwise when it starts. A pinned reference is only taken once per
+// reflector and never released.
+func (s *sharedIndexInformer) acquireClusterReflector(clusterName logicalcluster.Name, pin bool) {
+	s.clusterReflectorsLock.Lock()
+	defer s.clusterReflectorsLock.Unlock()
+
+	r, ok := s.clusterReflectors[clusterName]
+	if !ok {
+		r = &clusterReflector{
+			clusterName: clusterName,
+			synced:      make(chan struct{}),
+			previous:    s.purgingClusters[clusterName],
+		}
+		s.clusterReflectors[clusterName] = r
+		if s.clusterReflectorsCtx != nil {
+			s.startClusterReflector(s.clusterReflectorsCtx, r)
+		}
+	}
+	if pin {
+		if r.pinned {
+			return
+		}
+		r.pinned = true
+	}
+	r.refs++
+}
+
+// releaseClusterReflector drops a reference on the reflector of clusterName.
+// The last release stops the reflector, waits for it to finish and purges the
+// cluster from the cache, so that a later acquire starts from a clean slate.
+// A reflector acquired again in the meantime only starts after the purge.
+func (s *sharedIndexInformer) releaseClusterReflector(clusterName logicalcluster.Name) {
+	s.clusterReflectorsLock.Lock()
+	r, ok := s.clusterReflectors[clusterName]
+	if !ok {
+		s.clusterReflectorsLock.Unlock()
+		return
+	}
+	r.refs--
+	if r.refs > 0 {
+		s.clusterReflectorsLock.Unlock()
+		return
+	}
+	delete(s.clusterReflectors, clusterName)
+	if r.cancel == nil {
+		s.clusterReflectorsLock.Unlock()
+		return
+	}
+	r.cancel()
+	r.purged = make(chan struct{})
+	s.purgingClusters[clusterName] = r.purged
+	s.clusterReflectorsLock.Unlock()
+
+	// Stopping the reflector and purging can wait for the handlers, don't
+	// block other clusters meanwhile.
+	<-r.done
+	if err := s.PurgeCluster(clusterName); err != nil {
+		utilruntime.HandleError(fmt.Errorf("failed to purge cluster %q from informer cache: %w", clusterName, err))
+	}
+
+	s.clusterReflectorsLock.Lock()
+	defer s.clusterReflectorsLock.Unlock()
+	close(r.purged)
+	if s.purgingClusters[clusterName] == r.purged {
+		delete(s.purgingClusters, clusterName)
+	}
+}
+
+// startClusterReflector runs r until ctx is done or r is released. Must be
+// called with clusterReflectorsLock held.
+func (s *sharedIndexInformer) startClusterReflector(ctx context.Context, r *clusterReflector) {
+	ctx, r.cancel = context.WithCancel(ctx)
+	r.done = make(chan struct{})
+
+	logger := klog.FromContext(ctx).WithValues("cluster", r.clusterName)
+	// Resyncs are driven for all reflectors at once by runClusterReflectors.
+	controller, queue := s.newController(logger, controllerConfig{
+		listerWatcher: s.clusterListerWatcher(r.clusterName.Path()),
+		clientState:   newPartitionStore(s.indexer, kcpcache.ClusterIndexName, kcpcache.ClusterIndexKey(r.clusterName), kcpcache.ClusterIndexFunc, s.keyFunc),
+		keyFunc:       kcpcache.DeletionHandlingMetaClusterNamespaceKeyFunc,
+		transform:     s.transform,
+	})
+	r.queue = queue
+
+	go func() {
+		defer close(r.done)
+		if r.previous != nil {
+			select {
+			case <-r.previous:
+			case <-ctx.Done():
+				return
+			}
+		}
+		var wg wait.Group
+		defer wg.Wait()
+		wg.Start(func() {
+			select {
+			case <-ctx.Done():
+			case <-controller.HasSyncedChecker().Done():
+				close(r.synced)
+			}
+		})
+		controller.RunWithContext(klog.NewContext(ctx, logger))
+	}()
+}
+
+// runClusterReflectors starts all reflectors acquired so far and the ones
+// acquired later, and drives resyncs, until ctx is done. It then waits for
+// all reflectors to stop.
+func (s *sharedIndexInformer) runClusterReflectors(ctx context.Context) {
+	func() {
+		s.clusterReflectorsLock.Lock()
+		defer s.clusterReflectorsLock.Unlock()
+
+		s.clusterReflectorsCtx = ctx
+		for _, r := range s.clusterReflectors {
+			s.startClusterReflector(ctx, r)
+		}
+	}()
+
+	s.driveResyncs(ctx, s.resyncClusterReflectors)
+
+	s.clusterReflectorsLock.Lock()
+	defer s.clusterReflectorsLock.Unlock()
+	s.clusterReflectorsCtx = nil
+	for _, r := range s.clusterReflectors {
+		if r.done != nil {
+			<-r.done
+		}
+	}
+}
+
+// driveResyncs calls resync whenever a listener needs a resync, until ctx is
+// done. It replaces the reflectors' own resync timers when several reflectors
+// feed one informer, so that every reflector resyncs at the same time and the
+// listeners' resync state is consulted only once per period.
+func (s *sharedIndexInformer) driveResyncs(ctx context.Context, resync func()) {
+	if s.resyncCheckPeriod <= 0 {
+		<-ctx.Done()
+		return
+	}
+	wait.UntilWithContext(ctx, func(context.Context) {
+		if s.processor.shouldResync() {
+			resync()
+		}
+	}, s.resyncCheckPeriod)
+}
+
+func (s *sharedIndexInformer) resyncClusterReflectors() {
+	s.clusterReflectorsLock.Lock()
+	defer s.clusterReflectorsLock.Unlock()
+
+	for _, r := range s.clusterReflectors {
+		if r.queue == nil {
+			continue
+		}
+		if err := r.queue.Resync(); err != nil {
+			utilruntime.HandleError(fmt.Errorf("failed to resync cluster %q: %w", r.clusterName, err))
+		}
+	}
+}
+
+// clusterReflectorSynced returns the DoneChecker for the initial list of
+// clusterName, or nil if the informer is not in lazy mode. If no reflector
+// exists for the cluster, the returned DoneChecker is never done: nothing
+// watches the cluster, so it cannot be synced.
+func (s *sharedIndexInformer) clusterReflectorSynced(clusterName logicalcluster.Name) cache.DoneChecker {
+	if s.clusterListerWatcher == nil {
+		return nil
+	}
+
+	s.clusterReflectorsLock.Lock()
+	defer s.clusterReflectorsLock.Unlock()
+
+	r, ok := s.clusterReflectors[clusterName]
+	if !ok {
+		return &clusterReflector{clusterName: clusterName, synced: make(chan struct{})}
+	}
+	return r
+}
@@ -0,0 +1,191 @@
+/*
+Copyright 2026 The kcp Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package informers
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/tools/cache"
+	fcache "k8s.io/client-go/tools/cache/testing"
+
+	kcpcache "github.com/kcp-dev/apimachinery/v2/pkg/cache"
+	"github.com/kcp-dev/logicalcluster/v3"
+)
+
+// startLazyInformer runs a lazy informer with a reflector per cluster in
+// sources until the test ends.
+func startLazyInformer(t *testing.T, sources map[logicalcluster.Name]*fcache.FakeControllerSource) *sharedIndexInformer {
+	t.Helper()
+
+	informer := NewClusterAwareSharedIndexInformer(nil, &corev1.Pod{}, SharedIndexInformerOptions{
+		ClusterListerWatcher: func(clusterPath logicalcluster.Path) cache.ListerWatcher {
+			name, _ := clusterPath.Name()
+			return sources[name]
+		},
+	}).(*sharedIndexInformer)
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		informer.RunWithContext(ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-stopped
+	})
+	return informer
+}
+
+func (s *sharedIndexInformer) clusterReflectorRefs(clusterName logicalcluster.Name) int {
+	s.clusterReflectorsLock.Lock()
+	defer s.clusterReflectorsLock.Unlock()
+	if r, ok := s.clusterReflectors[clusterName]; ok {
+		return r.refs
+	}
+	return 0
+}
+
+func clusterKeys(informer cache.SharedIndexInformer, clusterName logicalcluster.Name) int {
+	objs, err := informer.GetIndexer().ByIndex(kcpcache.ClusterIndexName, kcpcache.ClusterIndexKey(clusterName))
+	if err != nil {
+		return -1
+	}
+	return len(objs)
+}
+
+func TestLazyClusterPinsReflector(t *testing.T) {
+	c1 := fcache.NewFakeControllerSource()
+	c1.Add(newPod("c1", "a", ""))
+	informer := startLazyInformer(t, map[logicalcluster.Name]*fcache.FakeControllerSource{"c1": c1})
+
+	scoped := informer.Cluster("c1")
+	informer.Cluster("c1")
+	require.True(t, cache.WaitForCacheSync(wait.NeverStop, scoped.HasSynced))
+	require.Equal(t, 1, informer.clusterReflectorRefs("c1"), "Cluster takes one reference")
+
+	// Releasing the references of ClusterWithContext leaves the pinned
+	// reflector running.
+	ctx, cancel := context.WithCancel(context.Background())
+	informer.ClusterWithContext(ctx, "c1")
+	require.Equal(t, 2, informer.clusterReflectorRefs("c1"))
+	cancel()
+	require.Eventually(t, func() bool {
+		return informer.clusterReflectorRefs("c1") == 1
+	}, wait.ForeverTestTimeout, 10*time.Millisecond)
+	require.Equal(t, 1, clusterKeys(informer, "c1"))
+}
+
+// gatedWatcher is a fake source whose watches after a list only start once
+// gate is closed, ignoring the reflector being stopped.
+type gatedWatcher struct {
+	*fcache.FakeControllerSource
+	gate chan struct{}
+}
+
+func (w gatedWatcher) Watch(options metav1.ListOptions) (watch.Interface, error) {
+	if options.ResourceVersion != "" {
+		<-w.gate
+	}
+	return w.FakeControllerSource.Watch(options)
+}
+
+func TestLazyReleaseDoesNotBlockOtherClusters(t *testing.T) {
+	c1, c2 := fcache.NewFakeControllerSource(), fcache.NewFakeControllerSource()
+	for i := range 3 {
+		c1.Add(newPod("c1", fmt.Sprintf("pod-%d", i), ""))
+	}
+	c2.Add(newPod("c2", "a", ""))
+	gate := make(chan struct{})
+	t.Cleanup(func() {
+		select {
+		case <-gate:
+		default:
+			close(gate)
+		}
+	})
+	informer := NewClusterAwareSharedIndexInformer(nil, &corev1.Pod{}, SharedIndexInformerOptions{
+		ClusterListerWatcher: func(clusterPath logicalcluster.Path) cache.ListerWatcher {
+			if clusterPath.String() == "c1" {
+				return gatedWatcher{FakeControllerSource: c1, gate: gate}
+			}
+			return c2
+		},
+	}).(*sharedIndexInformer)
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		informer.RunWithContext(ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-stopped
+	})
+
+	// The reflector of c1 lists, and cannot stop before its watch starts.
+	c1Ctx, c1Cancel := context.WithCancel(context.Background())
+	informer.ClusterWithContext(c1Ctx, "c1")
+	require.Eventually(t, func() bool {
+		return clusterKeys(informer, "c1") == 3
+	}, wait.ForeverTestTimeout, 10*time.Millisecond)
+	c1Cancel()
+	require.Eventually(t, func() bool {
+		return informer.clusterReflectorRefs("c1") == 0
+	}, wait.ForeverTestTimeout, 10*time.Millisecond)
+
+	// Other clusters can be acquired while c1 is being released, and so can
+	// c1 again.
+	acquired := make(chan struct{})
+	go func() {
+		defer close(acquired)
+		informer.ClusterWithContext(context.Background(), "c2")
+		informer.ClusterWithContext(context.Background(), "c1")
+	}()
+	select {
+	case <-acquired:
+	case <-time.After(wait.ForeverTestTimeout):
+		t.Fatal("acquiring a cluster is blocked by a release")
+	}
+	require.Eventually(t, func() bool {
+		return clusterKeys(informer, "c2") == 1
+	}, wait.ForeverTestTimeout, 10*time.Millisecond)
+
+	// c1 is purged before its new reflector lists it again, so its objects
+	// stay once the new reflector has synced.
+	informer.clusterReflectorsLock.Lock()
+	reflector := informer.clusterReflectors["c1"]
+	informer.clusterReflectorsLock.Unlock()
+	close(gate)
+	select {
+	case <-reflector.Done():
+	case <-time.After(wait.ForeverTestTimeout):
+		t.Fatal("the new reflector of c1 did not sync")
+	}
+	require.Equal(t, 3, clusterKeys(informer, "c1"))
+	require.Never(t, func() bool {
+		return clusterKeys(informer, "c1") != 3
+	}, 500*time.Millisecond, time.Millisecond)
+}
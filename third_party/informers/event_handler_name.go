@@ -26,18 +26,34 @@ import (
 	"k8s.io/client-go/tools/cache"
 )
 
+// handlerWrapper is implemented by handlers that wrap another handler, like
+// the ones of scoped informers and kcpcache.NewResourceEventHandler.
+type handlerWrapper interface {
+	Unwrap() any
+}
+
 func nameForHandler(handler cache.ResourceEventHandler) (name string) {
+	// kcp modification: name wrapped handlers after the handler they wrap.
+	var named any = handler
+	for {
+		wrapper, ok := named.(handlerWrapper)
+		if !ok {
+			break
+		}
+		named = wrapper.Unwrap()
+	}
+
 	defer func() {
 		// Last resort: let Sprintf handle it.
 		if name == "" {
-			name = fmt.Sprintf("%T", handler)
+			name = fmt.Sprintf("%T", named)
 		}
 	}()
 
-	if handler == nil {
+	if named == nil {
 		return ""
 	}
-	switch handler := handler.(type) {
+	switch handler := named.(type) {
 	case *cache.ResourceEventHandlerFuncs:
 		return nameForHandlerFuncs(*handler)
 	case cache.ResourceEventHandlerFuncs:
@@ -57,6 +73,11 @@ func nameForHandler(handler cache.ResourceEventHandler) (name string) {
 				value = value.Elem()
 			}
 		}
+		// kcp modification: structs of funcs like
+		// kcpcache.ClusterEventHandlerFuncs are named after their funcs.
+		if name := nameForFuncFields(value); name != "" {
+			return name
+		}
 		name := value.Type().PkgPath()
 		if name != "" {
 			name += "."
@@ -68,6 +89,25 @@ func nameForHandler(handler cache.ResourceEventHandler) (name string) {
 	}
 }
 
+// nameForFuncFields names a struct whose fields are all funcs after those
+// funcs. It returns "" for other values.
+func nameForFuncFields(value reflect.Value) string {
+	if value.Kind() != reflect.Struct || value.NumField() == 0 {
+		return ""
+	}
+	fs := make([]any, 0, value.NumField())
+	for i := range value.NumField() {
+		field := value.Field(i)
+		if field.Kind() != reflect.Func || !field.CanInterface() {
+			return ""
+		}
+		if !field.IsNil() {
+			fs = append(fs, field.Interface())
+		}
+	}
+	return nameForFunctions(fs...)
+}
+
 func nameForHandlerFuncs(funcs cache.ResourceEventHandlerFuncs) string {
 	return nameForFunctions(funcs.AddFunc, funcs.UpdateFunc, funcs.DeleteFunc)
 }
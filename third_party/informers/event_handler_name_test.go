@@ -0,0 +1,92 @@
+/*
+Copyright 2026 The kcp Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package informers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/cache"
+
+	kcpcache "github.com/kcp-dev/apimachinery/v2/pkg/cache"
+	"github.com/kcp-dev/logicalcluster/v3"
+)
+
+type podHandler struct{}
+
+func (podHandler) OnAdd(logicalcluster.Name, *corev1.Pod, bool)           {}
+func (podHandler) OnUpdate(logicalcluster.Name, *corev1.Pod, *corev1.Pod) {}
+func (podHandler) OnDelete(logicalcluster.Name, *corev1.Pod)              {}
+
+func TestNameForHandler(t *testing.T) {
+	const pkg = "github.com/kcp-dev/apimachinery/v2/third_party/informers"
+	funcs := kcpcache.ClusterEventHandlerFuncs[*corev1.Pod]{
+		AddFunc:    func(logicalcluster.Name, *corev1.Pod, bool) {},
+		DeleteFunc: func(logicalcluster.Name, *corev1.Pod) {},
+	}
+	plainFuncs := cache.ResourceEventHandlerFuncs{
+		AddFunc: func(interface{}) {},
+	}
+
+	for _, tc := range []struct {
+		name     string
+		handler  cache.ResourceEventHandler
+		expected string
+	}{
+		{
+			name:     "funcs",
+			handler:  plainFuncs,
+			expected: pkg + ".TestNameForHandler",
+		},
+		{
+			name:     "cluster event handler funcs",
+			handler:  kcpcache.NewResourceEventHandler[*corev1.Pod](funcs),
+			expected: pkg + ".TestNameForHandler",
+		},
+		{
+			name:     "pointer to cluster event handler funcs",
+			handler:  kcpcache.NewResourceEventHandler[*corev1.Pod](&funcs),
+			expected: pkg + ".TestNameForHandler",
+		},
+		{
+			name:     "empty cluster event handler funcs",
+			handler:  kcpcache.NewResourceEventHandler[*corev1.Pod](kcpcache.ClusterEventHandlerFuncs[*corev1.Pod]{}),
+			expected: "github.com/kcp-dev/apimachinery/v2/pkg/cache.ClusterEventHandlerFuncs[*k8s.io/api/core/v1.Pod]",
+		},
+		{
+			name:     "typed handler",
+			handler:  kcpcache.NewResourceEventHandler[*corev1.Pod](podHandler{}),
+			expected: pkg + ".podHandler",
+		},
+		{
+			name:     "scoped handler",
+			handler:  &scopedHandler{handler: plainFuncs},
+			expected: pkg + ".TestNameForHandler",
+		},
+		{
+			name:     "scoped typed handler",
+			handler:  &scopedHandler{handler: kcpcache.NewResourceEventHandler[*corev1.Pod](&podHandler{})},
+			expected: pkg + ".podHandler",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expected, nameForHandler(tc.handler))
+		})
+	}
+}
@@ -0,0 +1,84 @@
+/*
+Copyright 2026 The kcp Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package informers
+
+import (
+	"fmt"
+	"runtime/debug"
+	"time"
+
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+)
+
+// HandlerPanicError is passed to HandlerOptions.OnPanic when a panic of an
+// event handler has been recovered.
+type HandlerPanicError struct {
+	// HandlerName is the name of the handler, as used in logs and metrics.
+	HandlerName string
+	// Key is the cluster-aware key of the object the handler was notified
+	// about, or empty if it has none.
+	Key string
+	// Value is the value the handler panicked with.
+	Value interface{}
+	// Stack is the stack trace of the panic.
+	Stack []byte
+}
+
+func (e *HandlerPanicError) Error() string {
+	return fmt.Sprintf("event handler %s panicked on %q: %v", e.HandlerName, e.Key, e.Value)
+}
+
+// recoverPanic recovers a panic of the handler of p while it handles
+// notification. It must be deferred directly.
+func (p *processorListener) recoverPanic(notification interface{}) {
+	r := recover()
+	if r == nil {
+		return
+	}
+	key, _ := notificationKey(notification)
+	err := &HandlerPanicError{
+		HandlerName: p.handlerName,
+		Key:         key,
+		Value:       r,
+		Stack:       debug.Stack(),
+	}
+	utilruntime.HandleErrorWithLogger(p.logger, err, "Recovered panic of event handler", "handler", p.handlerName, "key", key)
+	// An initial add that panicked was not counted as delivered.
+	p.skip(notification)
+	if p.onPanic != nil {
+		p.onPanic(err)
+	}
+}
+
+// slowHandlerLogLevel is the verbosity at which slow handlers are logged.
+const slowHandlerLogLevel = 2
+
+// watchSlow reports the handling of notification once it takes longer than
+// the slow handler threshold of p. The returned func must be called when the
+// handler returns.
+func (p *processorListener) watchSlow(notification interface{}) func() {
+	timer := time.AfterFunc(p.slowHandlerThreshold, func() {
+		key, _ := notificationKey(notification)
+		p.logger.V(slowHandlerLogLevel).Info("Event handler is slow", "handler", p.handlerName, "key", key, "threshold", p.slowHandlerThreshold)
+		if p.metrics != nil {
+			p.metrics.slow.Inc()
+		}
+	})
+	return func() {
+		timer.Stop()
+	}
+}
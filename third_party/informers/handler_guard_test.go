@@ -0,0 +1,123 @@
+/*
+Copyright 2026 The kcp Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package informers
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/cache"
+	fcache "k8s.io/client-go/tools/cache/testing"
+	"k8s.io/klog/v2/ktesting"
+)
+
+func TestRecoverPanics(t *testing.T) {
+	source := fcache.NewFakeControllerSource()
+	source.Add(newPod("c1", "a", ""))
+	source.Add(newPod("c1", "b", ""))
+	informer, _, _ := startInformer(t, source)
+
+	var lock sync.Mutex
+	var added []string
+	panicking := cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			pod := obj.(*corev1.Pod)
+			if pod.Name == "a" {
+				panic("boom")
+			}
+			lock.Lock()
+			defer lock.Unlock()
+			added = append(added, pod.Name)
+		},
+	}
+	panics := make(chan error, 1)
+	registration, err := informer.AddEventHandlerWithHandlerOptions(panicking, HandlerOptions{
+		RecoverPanics: true,
+		OnPanic:       func(err error) { panics <- err },
+	})
+	require.NoError(t, err)
+	other := newRecordingHandler(false)
+	_, err = informer.AddEventHandler(other)
+	require.NoError(t, err)
+
+	select {
+	case err := <-panics:
+		var panicErr *HandlerPanicError
+		require.True(t, errors.As(err, &panicErr))
+		require.Equal(t, "c1|ns/a", panicErr.Key)
+		require.Equal(t, "boom", panicErr.Value)
+		require.NotEmpty(t, panicErr.Stack)
+	case <-time.After(wait.ForeverTestTimeout):
+		t.Fatal("OnPanic was not called")
+	}
+
+	// The panicking handler gets the next notification and syncs, the other
+	// handler is not affected.
+	require.True(t, cache.WaitForCacheSync(wait.NeverStop, registration.HasSynced))
+	lock.Lock()
+	require.Equal(t, []string{"b"}, added)
+	lock.Unlock()
+	require.Eventually(t, func() bool {
+		return len(other.recorded()) == 2
+	}, wait.ForeverTestTimeout, 10*time.Millisecond)
+	require.ElementsMatch(t, []string{"add a@1", "add b@2"}, other.recorded())
+}
+
+func TestSlowHandler(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		verbosity int
+		wantLogs  int
+	}{
+		{name: "logged", verbosity: slowHandlerLogLevel, wantLogs: 1},
+		{name: "not verbose", verbosity: slowHandlerLogLevel - 1},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			logger := ktesting.NewLogger(t, ktesting.NewConfig(ktesting.Verbosity(tc.verbosity), ktesting.BufferLogs(true)))
+			handler := newRecordingHandler(true)
+			metrics := newTestHandlerMetrics()
+			p := startListener(t, handler, true, func(p *processorListener) {
+				p.logger = logger
+				p.metrics = newHandlerMetrics(metrics, cache.InformerNameAndResource{}).newListenerMetrics("test")
+				p.slowHandlerThreshold = 10 * time.Millisecond
+			})
+			occupy(t, p, handler)
+			require.Eventually(t, func() bool {
+				return metrics.counter("slow") == 1
+			}, wait.ForeverTestTimeout, time.Millisecond)
+			close(handler.released)
+			require.Eventually(t, func() bool {
+				return len(handler.recorded()) == 1
+			}, wait.ForeverTestTimeout, 10*time.Millisecond)
+
+			var logs []string
+			for _, entry := range logger.GetSink().(ktesting.Underlier).GetBuffer().Data() {
+				logs = append(logs, entry.Message)
+			}
+			require.Len(t, logs, tc.wantLogs)
+			for _, message := range logs {
+				require.Equal(t, "Event handler is slow", message)
+			}
+		})
+	}
+}
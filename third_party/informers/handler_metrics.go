@@ -0,0 +1,128 @@
+/*
+Copyright 2026 The kcp Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package informers
+
+import (
+	"sync"
+	"time"
+
+	"k8s.io/client-go/tools/cache"
+
+	kcpcache "github.com/kcp-dev/apimachinery/v2/pkg/cache"
+)
+
+// handlerMetrics creates the metrics of the listeners of one informer.
+type handlerMetrics struct {
+	provider kcpcache.HandlerMetricsProvider
+	id       cache.InformerNameAndResource
+
+	lock sync.Mutex
+	// pending holds the pending notifications gauges by handler name.
+	pending map[string]*pendingGauge
+}
+
+// newHandlerMetrics returns the handler metrics of the informer identified by
+// id, or nil if provider is nil.
+func newHandlerMetrics(provider kcpcache.HandlerMetricsProvider, id cache.InformerNameAndResource) *handlerMetrics {
+	if provider == nil {
+		return nil
+	}
+	return &handlerMetrics{
+		provider: provider,
+		id:       id,
+		pending:  map[string]*pendingGauge{},
+	}
+}
+
+// pendingGauge is the pending notifications gauge of the handlers with the
+// same name. The handlers share the gauge, so it reports their total.
+type pendingGauge struct {
+	lock  sync.Mutex
+	gauge cache.GaugeMetric
+	total int64
+}
+
+func (g *pendingGauge) add(delta int64) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	g.total += delta
+	g.gauge.Set(float64(g.total))
+}
+
+// listenerMetrics are the metrics of one processorListener.
+type listenerMetrics struct {
+	pending         *pendingGauge
+	deliveryLatency cache.HistogramMetric
+	handlerDuration cache.HistogramMetric
+	removed         cache.CounterMetric
+	dropped         cache.CounterMetric
+	slow            cache.CounterMetric
+	// reported is the listener's share of pending. Only used by pop().
+	reported int64
+}
+
+func (m *handlerMetrics) newListenerMetrics(handlerName string) *listenerMetrics {
+	m.lock.Lock()
+	pending, ok := m.pending[handlerName]
+	if !ok {
+		pending = &pendingGauge{gauge: m.provider.NewPendingNotificationsMetric(m.id, handlerName)}
+		m.pending[handlerName] = pending
+	}
+	m.lock.Unlock()
+
+	return &listenerMetrics{
+		pending:         pending,
+		deliveryLatency: m.provider.NewDeliveryLatencyMetric(m.id, handlerName),
+		handlerDuration: m.provider.NewHandlerDurationMetric(m.id, handlerName),
+		removed:         m.provider.NewRemovedHandlersMetric(m.id, handlerName),
+		dropped:         m.provider.NewDroppedNotificationsMetric(m.id, handlerName),
+		slow:            m.provider.NewSlowNotificationsMetric(m.id, handlerName),
+	}
+}
+
+// timedNotification is a notification queued for a listener with metrics,
+// together with the time it was queued at.
+type timedNotification struct {
+	notification interface{}
+	queued       time.Time
+}
+
+// reportPending reports the number of notifications not yet delivered to
+// the handler: the buffered ones, plus the one waiting to be dispatched if
+// dispatching is true. The gauge is shared by the handlers with the same
+// name, so only the change is applied to it.
+func (p *processorListener) reportPending(dispatching bool) {
+	if p.metrics == nil {
+		return
+	}
+	pending := p.pendingNotificationsLength.Load()
+	if dispatching {
+		pending++
+	}
+	p.setReportedPending(pending)
+}
+
+// setReportedPending updates the listener's share of the pending gauge to
+// pending. pop() sets it to zero when it exits, as the notifications left
+// are never delivered.
+func (p *processorListener) setReportedPending(pending int64) {
+	if p.metrics == nil || pending == p.metrics.reported {
+		return
+	}
+	p.metrics.pending.add(pending - p.metrics.reported)
+	p.metrics.reported = pending
+}
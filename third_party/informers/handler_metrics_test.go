@@ -0,0 +1,155 @@
+/*
+Copyright 2026 The kcp Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package informers
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/cache"
+	fcache "k8s.io/client-go/tools/cache/testing"
+
+	kcpcache "github.com/kcp-dev/apimachinery/v2/pkg/cache"
+)
+
+// testHandlerMetrics is a kcpcache.HandlerMetricsProvider that records the
+// metrics of all handlers together.
+type testHandlerMetrics struct {
+	lock       sync.Mutex
+	gauges     map[string]float64
+	counters   map[string]float64
+	histograms map[string][]float64
+}
+
+var _ kcpcache.HandlerMetricsProvider = &testHandlerMetrics{}
+
+func newTestHandlerMetrics() *testHandlerMetrics {
+	return &testHandlerMetrics{
+		gauges:     map[string]float64{},
+		counters:   map[string]float64{},
+		histograms: map[string][]float64{},
+	}
+}
+
+func (m *testHandlerMetrics) gauge(name string) float64 {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return m.gauges[name]
+}
+
+func (m *testHandlerMetrics) counter(name string) float64 {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return m.counters[name]
+}
+
+func (m *testHandlerMetrics) histogram(name string) []float64 {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return append([]float64(nil), m.histograms[name]...)
+}
+
+type testMetric struct {
+	m    *testHandlerMetrics
+	name string
+}
+
+func (t testMetric) Set(value float64) {
+	t.m.lock.Lock()
+	defer t.m.lock.Unlock()
+	t.m.gauges[t.name] = value
+}
+
+func (t testMetric) Inc() {
+	t.m.lock.Lock()
+	defer t.m.lock.Unlock()
+	t.m.counters[t.name]++
+}
+
+func (t testMetric) Observe(value float64) {
+	t.m.lock.Lock()
+	defer t.m.lock.Unlock()
+	t.m.histograms[t.name] = append(t.m.histograms[t.name], value)
+}
+
+func (m *testHandlerMetrics) NewPendingNotificationsMetric(cache.InformerNameAndResource, string) cache.GaugeMetric {
+	return testMetric{m: m, name: "pending"}
+}
+
+func (m *testHandlerMetrics) NewDeliveryLatencyMetric(cache.InformerNameAndResource, string) cache.HistogramMetric {
+	return testMetric{m: m, name: "latency"}
+}
+
+func (m *testHandlerMetrics) NewHandlerDurationMetric(cache.InformerNameAndResource, string) cache.HistogramMetric {
+	return testMetric{m: m, name: "duration"}
+}
+
+func (m *testHandlerMetrics) NewRemovedHandlersMetric(cache.InformerNameAndResource, string) cache.CounterMetric {
+	return testMetric{m: m, name: "removed"}
+}
+
+func (m *testHandlerMetrics) NewDroppedNotificationsMetric(cache.InformerNameAndResource, string) cache.CounterMetric {
+	return testMetric{m: m, name: "dropped"}
+}
+
+func (m *testHandlerMetrics) NewSlowNotificationsMetric(cache.InformerNameAndResource, string) cache.CounterMetric {
+	return testMetric{m: m, name: "slow"}
+}
+
+func TestHandlerMetrics(t *testing.T) {
+	source := fcache.NewFakeControllerSource()
+	source.Add(newPod("c1", "a", ""))
+	source.Add(newPod("c1", "b", ""))
+	source.Add(newPod("c1", "c", ""))
+	metrics := newTestHandlerMetrics()
+	informer, _, _ := startInformerWithOptions(t, source, SharedIndexInformerOptions{HandlerMetricsProvider: metrics})
+
+	// Both handlers are named after their type, so they share the gauge.
+	first, second := newRecordingHandler(true), newRecordingHandler(true)
+	_, err := informer.AddEventHandler(first)
+	require.NoError(t, err)
+	registration, err := informer.AddEventHandler(second)
+	require.NoError(t, err)
+	<-first.started
+	<-second.started
+
+	// Each handler holds one notification, and has two more pending.
+	require.Eventually(t, func() bool {
+		return metrics.gauge("pending") == 4
+	}, wait.ForeverTestTimeout, 10*time.Millisecond)
+
+	// Removing a handler takes its notifications off the gauge.
+	require.NoError(t, informer.RemoveEventHandler(registration))
+	close(second.released)
+	require.Eventually(t, func() bool {
+		return metrics.gauge("pending") == 2
+	}, wait.ForeverTestTimeout, 10*time.Millisecond)
+	require.Equal(t, float64(1), metrics.counter("removed"))
+
+	close(first.released)
+	require.Eventually(t, func() bool {
+		return len(first.recorded()) == 3 && metrics.gauge("pending") == 0
+	}, wait.ForeverTestTimeout, 10*time.Millisecond)
+	require.Eventually(t, func() bool {
+		return len(metrics.histogram("duration")) >= 4
+	}, wait.ForeverTestTimeout, 10*time.Millisecond)
+	require.GreaterOrEqual(t, len(metrics.histogram("latency")), 4)
+}
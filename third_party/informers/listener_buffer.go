@@ -0,0 +1,385 @@
+/*
+Copyright 2026 The kcp Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package informers
+
+import (
+	"errors"
+	"fmt"
+
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+
+	kcpcache "github.com/kcp-dev/apimachinery/v2/pkg/cache"
+)
+
+// ErrHandlerOverflow is wrapped by the error passed to
+// HandlerOptions.OnDisconnect.
+var ErrHandlerOverflow = errors.New("event handler notification buffer overflowed")
+
+// OverflowPolicy decides what happens to the notifications of a handler
+// whose buffer holds HandlerOptions.MaxPendingNotifications notifications.
+type OverflowPolicy int
+
+const (
+	// OverflowBlock blocks the informer until the handler has caught up.
+	// This delays the notifications of all other handlers of the informer.
+	// A handler that never returns stalls the informer until it is removed
+	// with RemoveEventHandler or the informer is stopped.
+	OverflowBlock OverflowPolicy = iota
+	// OverflowDropOldest drops the oldest notification. When the buffer
+	// fills up, the buffered notifications are first coalesced, keeping
+	// only the latest of consecutive updates of an object, with the old
+	// object of the first one. They are not coalesced again before the
+	// buffer has drained to half its size. Handlers must tolerate missed
+	// adds, updates and deletes, e.g. by reading the lister.
+	OverflowDropOldest
+	// OverflowDisconnect drops all buffered notifications, removes the
+	// handler from the informer and calls HandlerOptions.OnDisconnect.
+	OverflowDisconnect
+)
+
+func (p OverflowPolicy) String() string {
+	switch p {
+	case OverflowBlock:
+		return "Block"
+	case OverflowDropOldest:
+		return "DropOldest"
+	case OverflowDisconnect:
+		return "Disconnect"
+	}
+	return fmt.Sprintf("OverflowPolicy(%d)", int(p))
+}
+
+// bufferLimit is the bound on the notifications of a processorListener.
+// Apart from slots, it is only used by pop().
+type bufferLimit struct {
+	max    int
+	policy OverflowPolicy
+	// slots holds a token for every notification on its way to the handler
+	// with OverflowBlock. add() blocks while it is full.
+	slots        chan struct{}
+	onDisconnect func(err error)
+	remove       func() error
+	disconnected bool
+	// compacted is set once the buffer has been coalesced, until it has
+	// drained to half its size.
+	compacted bool
+}
+
+// setBufferLimit bounds the buffer of p to maxPending notifications. It must be
+// called before p is started.
+func (p *processorListener) setBufferLimit(maxPending int, policy OverflowPolicy, onDisconnect func(error), remove func() error) {
+	if maxPending == 0 {
+		return
+	}
+	p.bufferLimit = &bufferLimit{
+		max:          maxPending,
+		policy:       policy,
+		onDisconnect: onDisconnect,
+		remove:       remove,
+	}
+	if policy == OverflowBlock {
+		p.bufferLimit.slots = make(chan struct{}, maxPending)
+	}
+}
+
+// bufferFull reports whether the buffer has no room left besides the
+// notification waiting to be dispatched. OverflowBlock never gets here. The
+// initial list is exempt: nothing is dropped before the handler has synced.
+func (p *processorListener) bufferFull() bool {
+	if p.bufferLimit == nil || p.bufferLimit.slots != nil || p.bufferLimit.disconnected {
+		return false
+	}
+	return p.pendingNotificationsLength.Load()+1 >= int64(p.bufferLimit.max) && p.syncTracker.HasSynced()
+}
+
+// bufferDrained is called after a notification has been dispatched.
+func (p *processorListener) bufferDrained() {
+	if p.bufferLimit != nil && p.bufferLimit.compacted && p.pendingNotificationsLength.Load()+1 <= int64(p.bufferLimit.max/2) {
+		p.bufferLimit.compacted = false
+	}
+}
+
+// overflow makes room in the full buffer according to the policy, and
+// returns the notification to dispatch next, which is nil if the handler has
+// been disconnected. waiting is the notification currently waiting to be
+// dispatched, which is the oldest one.
+func (p *processorListener) overflow(waiting interface{}) interface{} {
+	switch {
+	case p.bufferLimit.policy == OverflowDisconnect:
+		p.discard(waiting)
+		for {
+			notification, ok := p.readPending()
+			if !ok {
+				break
+			}
+			p.discard(notification)
+		}
+		p.disconnect()
+		return nil
+	case !p.bufferLimit.compacted:
+		queue := []interface{}{waiting}
+		for {
+			notification, ok := p.readPending()
+			if !ok {
+				break
+			}
+			queue = append(queue, notification)
+		}
+		queue = coalesceUpdates(queue)
+		for _, notification := range queue[1:] {
+			p.writePending(notification)
+		}
+		waiting = queue[0]
+		p.bufferLimit.compacted = true
+	}
+
+	// Drop the oldest notifications until there is room for one more.
+	for waiting != nil && p.pendingNotificationsLength.Load()+1 >= int64(p.bufferLimit.max) {
+		p.discard(waiting)
+		waiting, _ = p.readPending()
+	}
+	return waiting
+}
+
+// releaseSlots gives back the OverflowBlock slots of n notifications that
+// were merged or dropped, and therefore never reach run.
+func (p *processorListener) releaseSlots(n int) {
+	if p.bufferLimit == nil || p.bufferLimit.slots == nil {
+		return
+	}
+	for range n {
+		<-p.bufferLimit.slots
+	}
+}
+
+// coalescedNotification is a pending notification of a listener with
+// CoalesceNotifications. Later notifications for key are merged into it
+// while it is pending. notification is nil if it has been dropped.
+type coalescedNotification struct {
+	key          string
+	notification interface{}
+}
+
+// writePending queues notification, merging it with the pending
+// notification for the same object if p coalesces notifications.
+func (p *processorListener) writePending(notification interface{}) {
+	if p.coalesced == nil {
+		p.pendingNotifications.WriteOne(notification)
+		p.pendingNotificationsLength.Add(1)
+		return
+	}
+
+	key, ok := notificationKey(notification)
+	if !ok {
+		p.pendingNotifications.WriteOne(&coalescedNotification{notification: notification})
+		p.pendingNotificationsLength.Add(1)
+		return
+	}
+	if pending, found := p.coalesced[key]; found {
+		if merged, ok := mergeNotifications(pending.notification, notification); ok {
+			if merged == nil {
+				// add+delete, the handler never learns about the object.
+				p.skip(pending.notification)
+				pending.notification = nil
+				delete(p.coalesced, key)
+				p.pendingNotificationsLength.Add(-1)
+				p.releaseSlots(2)
+				return
+			}
+			pending.notification = merged
+			p.releaseSlots(1)
+			return
+		}
+	}
+	pending := &coalescedNotification{key: key, notification: notification}
+	p.coalesced[key] = pending
+	p.pendingNotifications.WriteOne(pending)
+	p.pendingNotificationsLength.Add(1)
+}
+
+// merges reports whether writePending would merge notification into a
+// pending notification.
+func (p *processorListener) merges(notification interface{}) bool {
+	if p.coalesced == nil {
+		return false
+	}
+	key, ok := notificationKey(notification)
+	if !ok {
+		return false
+	}
+	pending, found := p.coalesced[key]
+	if !found {
+		return false
+	}
+	_, ok = mergeNotifications(pending.notification, notification)
+	return ok
+}
+
+// readPending dequeues the oldest pending notification.
+func (p *processorListener) readPending() (interface{}, bool) {
+	for {
+		notification, ok := p.pendingNotifications.ReadOne()
+		if !ok {
+			return nil, false
+		}
+		if p.coalesced == nil {
+			p.pendingNotificationsLength.Add(-1)
+			return notification, true
+		}
+		pending := notification.(*coalescedNotification)
+		if p.coalesced[pending.key] == pending {
+			delete(p.coalesced, pending.key)
+		}
+		if pending.notification == nil {
+			continue
+		}
+		p.pendingNotificationsLength.Add(-1)
+		return pending.notification, true
+	}
+}
+
+// disconnect removes p from its informer in the background, as pop() must
+// keep draining addCh for the removal to get the locks it needs.
+func (p *processorListener) disconnect() {
+	p.bufferLimit.disconnected = true
+	err := fmt.Errorf("handler %s has more than %d pending notifications: %w", p.handlerName, p.bufferLimit.max, ErrHandlerOverflow)
+	p.logger.Info("Disconnecting event handler", "handler", p.handlerName, "maxPendingNotifications", p.bufferLimit.max)
+	go func() {
+		if err := p.bufferLimit.remove(); err != nil {
+			utilruntime.HandleErrorWithLogger(p.logger, err, "Failed to remove disconnected event handler", "handler", p.handlerName)
+		}
+		if p.bufferLimit.onDisconnect != nil {
+			p.bufferLimit.onDisconnect(err)
+		}
+	}()
+}
+
+// discard drops notification instead of delivering it.
+func (p *processorListener) discard(notification interface{}) {
+	p.skip(notification)
+	if p.metrics != nil {
+		p.metrics.dropped.Inc()
+	}
+}
+
+// skip accounts for notification not being delivered.
+func (p *processorListener) skip(notification interface{}) {
+	if a, ok := unwrapTimed(notification).(addNotification); ok && a.isInInitialList {
+		// The handler will never see it, but must still get synced.
+		p.syncTracker.Finished()
+	}
+}
+
+// coalesceUpdates merges consecutive updates of the same object in queue
+// into one update from the old object of the first to the new object of the
+// last, in the place of the first. Updates are consecutive if no other
+// notification for the object comes between them.
+func coalesceUpdates(queue []interface{}) []interface{} {
+	ret := make([]interface{}, 0, len(queue))
+	last := make(map[string]int, len(queue))
+	for _, notification := range queue {
+		key, ok := notificationKey(notification)
+		if !ok {
+			ret = append(ret, notification)
+			continue
+		}
+		if i, found := last[key]; found {
+			if merged, ok := mergeUpdates(ret[i], notification); ok {
+				ret[i] = merged
+				continue
+			}
+		}
+		last[key] = len(ret)
+		ret = append(ret, notification)
+	}
+	return ret
+}
+
+// mergeUpdates merges the update next into the earlier update prev.
+func mergeUpdates(prev, next interface{}) (interface{}, bool) {
+	prevUpdate, ok := unwrapTimed(prev).(updateNotification)
+	if !ok {
+		return nil, false
+	}
+	nextUpdate, ok := unwrapTimed(next).(updateNotification)
+	if !ok {
+		return nil, false
+	}
+	return rewrapTimed(prev, updateNotification{oldObj: prevUpdate.oldObj, newObj: nextUpdate.newObj}), true
+}
+
+// mergeNotifications merges next into the earlier notification prev for the
+// same object. It returns a nil notification if the two cancel out.
+func mergeNotifications(prev, next interface{}) (interface{}, bool) {
+	add, ok := unwrapTimed(prev).(addNotification)
+	if !ok {
+		return mergeUpdates(prev, next)
+	}
+	switch n := unwrapTimed(next).(type) {
+	case updateNotification:
+		newObj := n.newObj
+		if _, forced := add.newObj.(forcedObject); forced {
+			// The add was sent when the object's cluster joined the scope.
+			newObj = forcedObject{obj: newObj}
+		}
+		return rewrapTimed(prev, addNotification{newObj: newObj, isInInitialList: add.isInInitialList}), true
+	case deleteNotification:
+		return nil, true
+	}
+	return nil, false
+}
+
+// notificationKey returns the cluster-aware key of the object of
+// notification, qualified with the shard in multi-shard informers.
+func notificationKey(notification interface{}) (string, bool) {
+	var obj interface{}
+	switch n := unwrapTimed(notification).(type) {
+	case addNotification:
+		obj = n.newObj
+	case updateNotification:
+		obj = n.newObj
+	case deleteNotification:
+		obj = n.oldObj
+	default:
+		return "", false
+	}
+	if forced, ok := obj.(forcedObject); ok {
+		obj = forced.obj
+	}
+	key, err := kcpcache.DeletionHandlingMetaShardClusterNamespaceKeyFunc(obj)
+	if err != nil {
+		return "", false
+	}
+	return key, true
+}
+
+func unwrapTimed(notification interface{}) interface{} {
+	if timed, ok := notification.(timedNotification); ok {
+		return timed.notification
+	}
+	return notification
+}
+
+// rewrapTimed replaces the notification in original, keeping the time it
+// was queued at, if original is a timedNotification.
+func rewrapTimed(original, notification interface{}) interface{} {
+	if timed, ok := original.(timedNotification); ok {
+		timed.notification = notification
+		return timed
+	}
+	return notification
+}
@@ -0,0 +1,598 @@
+/*
+Copyright 2026 The kcp Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package informers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/cache"
+	fcache "k8s.io/client-go/tools/cache/testing"
+	"k8s.io/klog/v2"
+
+	"github.com/kcp-dev/logicalcluster/v3"
+)
+
+func newPod(cluster, name, resourceVersion string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:       "ns",
+			Name:            name,
+			ResourceVersion: resourceVersion,
+			Annotations:     map[string]string{logicalcluster.AnnotationKey: cluster},
+		},
+	}
+}
+
+// recordingHandler records the notifications it gets as strings. Once armed,
+// it blocks on its first notification until released.
+type recordingHandler struct {
+	lock   sync.Mutex
+	events []string
+
+	armed    bool
+	started  chan struct{}
+	released chan struct{}
+}
+
+func newRecordingHandler(armed bool) *recordingHandler {
+	return &recordingHandler{
+		armed:    armed,
+		started:  make(chan struct{}),
+		released: make(chan struct{}),
+	}
+}
+
+func (h *recordingHandler) record(event string) {
+	h.lock.Lock()
+	armed := h.armed
+	h.armed = false
+	h.lock.Unlock()
+	if armed {
+		close(h.started)
+		<-h.released
+	}
+
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.events = append(h.events, event)
+}
+
+func (h *recordingHandler) recorded() []string {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	return append([]string(nil), h.events...)
+}
+
+func (h *recordingHandler) OnAdd(obj interface{}, isInInitialList bool) {
+	if forced, ok := obj.(forcedObject); ok {
+		pod := forced.obj.(*corev1.Pod)
+		h.record(fmt.Sprintf("forced add %s@%s", pod.Name, pod.ResourceVersion))
+		return
+	}
+	pod := obj.(*corev1.Pod)
+	h.record(fmt.Sprintf("add %s@%s", pod.Name, pod.ResourceVersion))
+}
+
+func (h *recordingHandler) OnUpdate(oldObj, newObj interface{}) {
+	oldPod, newPod := oldObj.(*corev1.Pod), newObj.(*corev1.Pod)
+	h.record(fmt.Sprintf("update %s@%s->%s", newPod.Name, oldPod.ResourceVersion, newPod.ResourceVersion))
+}
+
+func (h *recordingHandler) OnDelete(obj interface{}) {
+	if forced, ok := obj.(forcedObject); ok {
+		pod := forced.obj.(*corev1.Pod)
+		h.record(fmt.Sprintf("forced delete %s@%s", pod.Name, pod.ResourceVersion))
+		return
+	}
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	pod := obj.(*corev1.Pod)
+	h.record(fmt.Sprintf("delete %s@%s", pod.Name, pod.ResourceVersion))
+}
+
+type doneChecker chan struct{}
+
+func (c doneChecker) Name() string {
+	return "test"
+}
+
+func (c doneChecker) Done() <-chan struct{} {
+	return c
+}
+
+// startListener starts a listener for handler, configured by configure. The
+// upstream informer has synced if synced is set.
+func startListener(t *testing.T, handler cache.ResourceEventHandler, synced bool, configure func(*processorListener)) *processorListener {
+	t.Helper()
+
+	upstream := make(doneChecker)
+	if synced {
+		close(upstream)
+	}
+	p := newProcessListener(klog.Background(), handler, 0, 0, time.Now(), initialBufferSize, upstream)
+	configure(p)
+	var wg sync.WaitGroup
+	wg.Go(p.run)
+	wg.Go(p.pop)
+	wg.Go(p.watchSynced)
+	t.Cleanup(func() {
+		close(p.addCh)
+		wg.Wait()
+	})
+	return p
+}
+
+// occupy makes the armed handler of p block on a first notification, so that
+// the next ones are buffered.
+func occupy(t *testing.T, p *processorListener, handler *recordingHandler) {
+	t.Helper()
+	p.add(addNotification{newObj: newPod("c1", "first", "1")})
+	select {
+	case <-handler.started:
+	case <-time.After(wait.ForeverTestTimeout):
+		t.Fatal("handler was not called")
+	}
+}
+
+func update(name, oldVersion, newVersion string) updateNotification {
+	return updateNotification{oldObj: newPod("c1", name, oldVersion), newObj: newPod("c1", name, newVersion)}
+}
+
+func add(name, version string) addNotification {
+	return addNotification{newObj: newPod("c1", name, version)}
+}
+
+func del(name, version string) deleteNotification {
+	return deleteNotification{oldObj: newPod("c1", name, version)}
+}
+
+func TestCoalesceNotifications(t *testing.T) {
+	forcedAdd := func(name, version string) addNotification {
+		return addNotification{newObj: forcedObject{obj: newPod("c1", name, version)}}
+	}
+	forcedDel := func(name, version string) deleteNotification {
+		return deleteNotification{oldObj: forcedObject{obj: newPod("c1", name, version)}}
+	}
+	for _, tc := range []struct {
+		name          string
+		max           int
+		notifications []interface{}
+		want          []string
+		wantDropped   int
+	}{
+		{
+			name:          "add and update",
+			notifications: []interface{}{add("a", "1"), update("a", "1", "2"), update("a", "2", "3")},
+			want:          []string{"add next@1", "add a@3"},
+		},
+		{
+			name:          "updates",
+			notifications: []interface{}{update("a", "1", "2"), update("a", "2", "3"), update("a", "3", "4")},
+			want:          []string{"add next@1", "update a@1->4"},
+		},
+		{
+			name:          "add and delete",
+			notifications: []interface{}{add("a", "1"), update("a", "1", "2"), del("a", "2")},
+			want:          []string{"add next@1"},
+		},
+		{
+			name:          "update and delete",
+			notifications: []interface{}{update("a", "1", "2"), del("a", "2")},
+			want:          []string{"add next@1", "update a@1->2", "delete a@2"},
+		},
+		{
+			name:          "delete and add",
+			notifications: []interface{}{del("a", "1"), add("a", "2"), update("a", "2", "3")},
+			want:          []string{"add next@1", "delete a@1", "add a@3"},
+		},
+		{
+			name:          "merged in place of the first",
+			notifications: []interface{}{update("a", "1", "2"), update("b", "1", "2"), update("a", "2", "3")},
+			want:          []string{"add next@1", "update a@1->3", "update b@1->2"},
+		},
+		{
+			name: "other cluster",
+			notifications: []interface{}{
+				update("a", "1", "2"),
+				updateNotification{oldObj: newPod("c2", "a", "1"), newObj: newPod("c2", "a", "2")},
+			},
+			want: []string{"add next@1", "update a@1->2", "update a@1->2"},
+		},
+		{
+			name:          "forced add and update",
+			notifications: []interface{}{forcedAdd("a", "1"), update("a", "1", "2")},
+			want:          []string{"add next@1", "forced add a@2"},
+		},
+		{
+			name:          "forced add and forced delete",
+			notifications: []interface{}{forcedAdd("a", "1"), forcedDel("a", "1")},
+			want:          []string{"add next@1"},
+		},
+		{
+			name:          "forced delete and forced add",
+			notifications: []interface{}{forcedDel("a", "1"), forcedAdd("a", "1")},
+			want:          []string{"add next@1", "forced delete a@1", "forced add a@1"},
+		},
+		{
+			name:          "drop oldest does not drop for a merged notification",
+			max:           3,
+			notifications: []interface{}{update("a", "1", "2"), update("b", "1", "2"), update("a", "2", "3"), update("c", "1", "2")},
+			want:          []string{"update a@1->3", "update b@1->2", "update c@1->2"},
+			wantDropped:   1,
+		},
+		{
+			name:          "drop oldest does not merge into the next notification",
+			max:           3,
+			notifications: []interface{}{update("a", "1", "2"), update("b", "1", "2"), update("c", "1", "2"), update("a", "2", "3")},
+			want:          []string{"update b@1->2", "update c@1->2", "update a@2->3"},
+			wantDropped:   2,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			handler := newRecordingHandler(true)
+			metrics := newTestHandlerMetrics()
+			p := startListener(t, handler, true, func(p *processorListener) {
+				p.metrics = newHandlerMetrics(metrics, cache.InformerNameAndResource{}).newListenerMetrics("test")
+				p.coalesced = map[string]*coalescedNotification{}
+				if tc.max > 0 {
+					p.setBufferLimit(tc.max, OverflowDropOldest, nil, nil)
+				}
+			})
+			occupy(t, p, handler)
+			// The next notification to be handled is not merged.
+			p.add(add("next", "1"))
+			for _, notification := range tc.notifications {
+				p.add(notification)
+			}
+			close(handler.released)
+
+			want := append([]string{"add first@1"}, tc.want...)
+			require.Eventually(t, func() bool {
+				return len(handler.recorded()) == len(want)
+			}, wait.ForeverTestTimeout, 10*time.Millisecond)
+			require.Never(t, func() bool {
+				return len(handler.recorded()) > len(want)
+			}, 50*time.Millisecond, 10*time.Millisecond)
+			require.Equal(t, want, handler.recorded())
+			require.Equal(t, float64(tc.wantDropped), metrics.counter("dropped"))
+			require.Zero(t, metrics.gauge("pending"))
+		})
+	}
+}
+
+func TestCoalesceNotificationsInitialList(t *testing.T) {
+	handler := newRecordingHandler(true)
+	p := startListener(t, handler, true, func(p *processorListener) {
+		p.coalesced = map[string]*coalescedNotification{}
+	})
+	p.add(addNotification{newObj: newPod("c1", "first", "1"), isInInitialList: true})
+	<-handler.started
+	p.add(addNotification{newObj: newPod("c1", "next", "1"), isInInitialList: true})
+	p.add(addNotification{newObj: newPod("c1", "a", "1"), isInInitialList: true})
+	p.add(addNotification{newObj: newPod("c1", "b", "1"), isInInitialList: true})
+	p.add(update("b", "1", "2"))
+	p.add(del("a", "1"))
+	require.False(t, p.HasSynced())
+	close(handler.released)
+
+	// The cancelled initial add of a still counts as handled.
+	require.Eventually(t, p.HasSynced, wait.ForeverTestTimeout, 10*time.Millisecond)
+	require.Equal(t, []string{"add first@1", "add next@1", "add b@2"}, handler.recorded())
+}
+
+func TestBufferOverflow(t *testing.T) {
+	for _, tc := range []struct {
+		name          string
+		max           int
+		policy        OverflowPolicy
+		notifications []interface{}
+		want          []string
+		wantDropped   int
+		disconnected  bool
+	}{
+		{
+			name:          "drop oldest",
+			max:           2,
+			policy:        OverflowDropOldest,
+			notifications: []interface{}{add("a", "1"), add("b", "1"), add("c", "1")},
+			want:          []string{"add first@1", "add b@1", "add c@1"},
+			wantDropped:   1,
+		},
+		{
+			name:   "drop oldest coalesces updates first",
+			max:    2,
+			policy: OverflowDropOldest,
+			notifications: []interface{}{
+				update("a", "1", "2"), update("a", "2", "3"), update("a", "3", "4"),
+			},
+			want: []string{"add first@1", "update a@1->3", "update a@3->4"},
+		},
+		{
+			name:   "drop oldest coalesces only once while full",
+			max:    2,
+			policy: OverflowDropOldest,
+			notifications: []interface{}{
+				update("a", "1", "2"), update("a", "2", "3"), update("a", "3", "4"), update("a", "4", "5"),
+			},
+			want:        []string{"add first@1", "update a@3->4", "update a@4->5"},
+			wantDropped: 1,
+		},
+		{
+			name:          "disconnect",
+			max:           2,
+			policy:        OverflowDisconnect,
+			notifications: []interface{}{add("a", "1"), add("b", "1"), add("c", "1"), add("d", "1")},
+			want:          []string{"add first@1"},
+			wantDropped:   4,
+			disconnected:  true,
+		},
+		{
+			name:          "no overflow",
+			max:           3,
+			policy:        OverflowDisconnect,
+			notifications: []interface{}{add("a", "1"), add("b", "1")},
+			want:          []string{"add first@1", "add a@1", "add b@1"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			handler := newRecordingHandler(true)
+			metrics := newTestHandlerMetrics()
+			disconnected := make(chan error, 1)
+			removed := make(chan struct{})
+			p := startListener(t, handler, true, func(p *processorListener) {
+				p.metrics = newHandlerMetrics(metrics, cache.InformerNameAndResource{}).newListenerMetrics("test")
+				p.setBufferLimit(tc.max, tc.policy, func(err error) { disconnected <- err }, func() error {
+					close(removed)
+					return nil
+				})
+			})
+			occupy(t, p, handler)
+			for _, notification := range tc.notifications {
+				p.add(notification)
+			}
+			close(handler.released)
+
+			if tc.disconnected {
+				err := <-disconnected
+				require.ErrorIs(t, err, ErrHandlerOverflow)
+				<-removed
+			}
+			require.Eventually(t, func() bool {
+				return len(handler.recorded()) == len(tc.want)
+			}, wait.ForeverTestTimeout, 10*time.Millisecond)
+			require.Equal(t, tc.want, handler.recorded())
+			require.Equal(t, float64(tc.wantDropped), metrics.counter("dropped"))
+		})
+	}
+}
+
+func TestBufferOverflowExemptsInitialList(t *testing.T) {
+	for _, policy := range []OverflowPolicy{OverflowDropOldest, OverflowDisconnect} {
+		t.Run(policy.String(), func(t *testing.T) {
+			handler := newRecordingHandler(true)
+			p := startListener(t, handler, false, func(p *processorListener) {
+				p.setBufferLimit(1, policy, func(err error) { t.Errorf("unexpected disconnect: %v", err) }, func() error { return nil })
+			})
+			p.add(addNotification{newObj: newPod("c1", "first", "1"), isInInitialList: true})
+			<-handler.started
+			for _, name := range []string{"a", "b", "c"} {
+				p.add(addNotification{newObj: newPod("c1", name, "1"), isInInitialList: true})
+			}
+			close(handler.released)
+
+			require.Eventually(t, func() bool {
+				return len(handler.recorded()) == 4
+			}, wait.ForeverTestTimeout, 10*time.Millisecond)
+			require.Equal(t, []string{"add first@1", "add a@1", "add b@1", "add c@1"}, handler.recorded())
+		})
+	}
+}
+
+func TestBufferOverflowBlock(t *testing.T) {
+	handler := newRecordingHandler(true)
+	p := startListener(t, handler, true, func(p *processorListener) {
+		p.setBufferLimit(1, OverflowBlock, nil, nil)
+	})
+	occupy(t, p, handler)
+	p.add(add("a", "1"))
+
+	added := make(chan struct{})
+	go func() {
+		defer close(added)
+		p.add(add("b", "1"))
+	}()
+	select {
+	case <-added:
+		t.Fatal("add did not block on the full buffer")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	close(handler.released)
+	<-added
+	require.Eventually(t, func() bool {
+		return len(handler.recorded()) == 3
+	}, wait.ForeverTestTimeout, 10*time.Millisecond)
+}
+
+func TestBufferOverflowBlockCoalesced(t *testing.T) {
+	handler := newRecordingHandler(true)
+	p := startListener(t, handler, true, func(p *processorListener) {
+		p.coalesced = map[string]*coalescedNotification{}
+		p.setBufferLimit(4, OverflowBlock, nil, nil)
+	})
+	occupy(t, p, handler)
+	p.add(add("next", "1"))
+	// Merged and dropped notifications give their slots back, so none of
+	// these blocks on the buffer.
+	added := make(chan struct{})
+	go func() {
+		defer close(added)
+		for _, notification := range []interface{}{
+			update("a", "1", "2"), update("a", "2", "3"), update("a", "3", "4"),
+			add("b", "1"), del("b", "1"),
+			update("a", "4", "5"), update("a", "5", "6"),
+		} {
+			p.add(notification)
+		}
+	}()
+	select {
+	case <-added:
+	case <-time.After(wait.ForeverTestTimeout):
+		t.Fatal("add blocked on slots of merged notifications")
+	}
+	close(handler.released)
+
+	want := []string{"add first@1", "add next@1", "update a@1->6"}
+	require.Eventually(t, func() bool {
+		return len(handler.recorded()) == len(want)
+	}, wait.ForeverTestTimeout, 10*time.Millisecond)
+	require.Equal(t, want, handler.recorded())
+	require.Zero(t, len(p.bufferLimit.slots))
+}
+
+// startInformer runs an informer of source until the test ends, or until the
+// returned cancel func is called.
+func startInformer(t *testing.T, source *fcache.FakeControllerSource) (*sharedIndexInformer, context.CancelFunc, <-chan struct{}) {
+	t.Helper()
+	return startInformerWithOptions(t, source, SharedIndexInformerOptions{})
+}
+
+// startInformerWithOptions is like startInformer, but creates the informer
+// with options.
+func startInformerWithOptions(t *testing.T, source *fcache.FakeControllerSource, options SharedIndexInformerOptions) (*sharedIndexInformer, context.CancelFunc, <-chan struct{}) {
+	t.Helper()
+
+	informer := NewClusterAwareSharedIndexInformer(source, &corev1.Pod{}, options).(*sharedIndexInformer)
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		informer.RunWithContext(ctx)
+	}()
+	t.Cleanup(cancel)
+	require.True(t, cache.WaitForCacheSync(ctx.Done(), informer.HasSynced))
+	return informer, cancel, stopped
+}
+
+// blockInformer adds a handler with OverflowBlock that blocks the
+// distribution of the notifications of informer, and a second handler that
+// records the notifications still distributed.
+func blockInformer(t *testing.T, source *fcache.FakeControllerSource, informer *sharedIndexInformer) (stuck, other *recordingHandler, registration cache.ResourceEventHandlerRegistration) {
+	t.Helper()
+
+	stuck = newRecordingHandler(true)
+	t.Cleanup(func() {
+		select {
+		case <-stuck.released:
+		default:
+			close(stuck.released)
+		}
+	})
+	registration, err := informer.AddEventHandlerWithHandlerOptions(stuck, HandlerOptions{MaxPendingNotifications: 1, OverflowPolicy: OverflowBlock})
+	require.NoError(t, err)
+	other = newRecordingHandler(false)
+	_, err = informer.AddEventHandler(other)
+	require.NoError(t, err)
+
+	for i := range 5 {
+		source.Add(newPod("c1", fmt.Sprintf("pod-%d", i), ""))
+	}
+	<-stuck.started
+	// The first pod is handled, the second one buffered, and the third one
+	// blocks the distribution.
+	require.Never(t, func() bool {
+		return len(other.recorded()) == 5
+	}, 200*time.Millisecond, 10*time.Millisecond)
+	return stuck, other, registration
+}
+
+func TestOverflowBlockRemoveStuckHandler(t *testing.T) {
+	source := fcache.NewFakeControllerSource()
+	informer, _, _ := startInformer(t, source)
+	_, other, registration := blockInformer(t, source, informer)
+
+	removed := make(chan error)
+	go func() {
+		removed <- informer.RemoveEventHandler(registration)
+	}()
+	select {
+	case err := <-removed:
+		require.NoError(t, err)
+	case <-time.After(wait.ForeverTestTimeout):
+		t.Fatal("the stuck handler could not be removed")
+	}
+	require.Eventually(t, func() bool {
+		return len(other.recorded()) == 5
+	}, wait.ForeverTestTimeout, 10*time.Millisecond)
+}
+
+func TestOverflowBlockStopInformer(t *testing.T) {
+	source := fcache.NewFakeControllerSource()
+	informer, cancel, stopped := startInformer(t, source)
+	stuck, _, _ := blockInformer(t, source, informer)
+
+	cancel()
+	// The controller stops although the handler is still stuck.
+	require.Eventually(t, informer.IsStopped, wait.ForeverTestTimeout, 10*time.Millisecond)
+
+	close(stuck.released)
+	select {
+	case <-stopped:
+	case <-time.After(wait.ForeverTestTimeout):
+		t.Fatal("the informer did not stop")
+	}
+}
+
+func TestOverflowDisconnectRemovesScopedHandler(t *testing.T) {
+	source := fcache.NewFakeControllerSource()
+	informer, _, _ := startInformer(t, source)
+	scoped := informer.Cluster("c1").(*scopedSharedIndexInformer)
+
+	handler := newRecordingHandler(true)
+	t.Cleanup(func() { close(handler.released) })
+	disconnected := make(chan error, 1)
+	registration, err := scoped.AddEventHandlerWithHandlerOptions(handler, HandlerOptions{
+		MaxPendingNotifications: 2,
+		OverflowPolicy:          OverflowDisconnect,
+		OnDisconnect:            func(err error) { disconnected <- err },
+	})
+	require.NoError(t, err)
+	require.True(t, cache.WaitForCacheSync(nil, registration.HasSynced))
+
+	for i := range 5 {
+		source.Add(newPod("c1", fmt.Sprintf("pod-%d", i), ""))
+	}
+	select {
+	case err := <-disconnected:
+		require.True(t, errors.Is(err, ErrHandlerOverflow))
+	case <-time.After(wait.ForeverTestTimeout):
+		t.Fatal("the handler was not disconnected")
+	}
+	require.Empty(t, scoped.registrations())
+	require.Nil(t, informer.processor.getListener(registration))
+}
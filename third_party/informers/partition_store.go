@@ -0,0 +1,138 @@
+/*
+Copyright 2026 The kcp Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package informers
+
+import (
+	"slices"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/tools/cache"
+)
+
+// partitionStore is the view of the informer's indexer that one of several
+// controllers syncs, e.g. the reflector of one logical cluster or of one
+// shard. The partition is the set of objects with indexValue in index
+// indexName. Writes go straight to the indexer; listing and Replace only
+// ever see the objects of the partition, so a relist of one partition cannot
+// delete the objects of another.
+type partitionStore struct {
+	indexer    cache.Indexer
+	indexName  string
+	indexValue string
+	// indexFunc is used to find the partition's objects if indexName is
+	// not registered on the indexer.
+	indexFunc cache.IndexFunc
+	keyFunc   cache.KeyFunc
+}
+
+func newPartitionStore(indexer cache.Indexer, indexName, indexValue string, indexFunc cache.IndexFunc, keyFunc cache.KeyFunc) *partitionStore {
+	return &partitionStore{
+		indexer:    indexer,
+		indexName:  indexName,
+		indexValue: indexValue,
+		indexFunc:  indexFunc,
+		keyFunc:    keyFunc,
+	}
+}
+
+func (p *partitionStore) Add(obj interface{}) error {
+	return p.indexer.Add(obj)
+}
+
+func (p *partitionStore) Update(obj interface{}) error {
+	return p.indexer.Update(obj)
+}
+
+func (p *partitionStore) Delete(obj interface{}) error {
+	return p.indexer.Delete(obj)
+}
+
+func (p *partitionStore) List() []interface{} {
+	if items, err := p.indexer.ByIndex(p.indexName, p.indexValue); err == nil {
+		return items
+	}
+	var items []interface{}
+	for _, item := range p.indexer.List() {
+		if values, err := p.indexFunc(item); err == nil && slices.Contains(values, p.indexValue) {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func (p *partitionStore) ListKeys() []string {
+	if keys, err := p.indexer.IndexKeys(p.indexName, p.indexValue); err == nil {
+		return keys
+	}
+	items := p.List()
+	keys := make([]string, 0, len(items))
+	for _, item := range items {
+		if key, err := p.keyFunc(item); err == nil {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+func (p *partitionStore) Get(obj interface{}) (item interface{}, exists bool, err error) {
+	return p.indexer.Get(obj)
+}
+
+func (p *partitionStore) GetByKey(key string) (item interface{}, exists bool, err error) {
+	return p.indexer.GetByKey(key)
+}
+
+// Replace replaces the objects of the partition with list. The resource
+// version is dropped, as it is only meaningful per partition.
+func (p *partitionStore) Replace(list []interface{}, _ string) error {
+	newKeys := sets.New[string]()
+	for _, obj := range list {
+		key, err := p.keyFunc(obj)
+		if err != nil {
+			return cache.KeyError{Obj: obj, Err: err}
+		}
+		newKeys.Insert(key)
+		if err := p.indexer.Update(obj); err != nil {
+			return err
+		}
+	}
+	for _, key := range p.ListKeys() {
+		if newKeys.Has(key) {
+			continue
+		}
+		obj, exists, err := p.indexer.GetByKey(key)
+		if err != nil || !exists {
+			continue
+		}
+		if err := p.indexer.Delete(obj); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LastStoreSyncResourceVersion returns an empty string, as resource versions
+// of the shared indexer are not meaningful for a single partition.
+func (p *partitionStore) LastStoreSyncResourceVersion() string {
+	return ""
+}
+
+func (p *partitionStore) Bookmark(string) {}
+
+func (p *partitionStore) Resync() error {
+	return nil
+}
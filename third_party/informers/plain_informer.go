@@ -0,0 +1,281 @@
+/*
+Copyright 2026 The kcp Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package informers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/cache"
+
+	kcpcache "github.com/kcp-dev/apimachinery/v2/pkg/cache"
+	"github.com/kcp-dev/logicalcluster/v3"
+)
+
+// NewSharedIndexInformerFromPlain creates a cluster-aware informer that is
+// fed by source, a client-go informer keyed with cache.MetaNamespaceKeyFunc,
+// e.g. one handed out by a third-party library. The objects of source are
+// indexed again in a cluster-aware indexer, so that ClusterLister, Cluster
+// and the other scopes can be used on them. The cluster of an object is read
+// from its logicalcluster.AnnotationKey annotation.
+//
+// Run does not run source, which must be started separately. The informer
+// has synced once source has synced and its initial objects have been
+// indexed. Resyncs are driven by the informer itself; source is watched
+// without resyncs. options.ClusterListerWatcher is not supported and is
+// ignored. exampleObject is the type of the objects of source, like for
+// NewClusterAwareSharedIndexInformer.
+func NewSharedIndexInformerFromPlain(source cache.SharedIndexInformer, exampleObject runtime.Object, options SharedIndexInformerOptions) kcpcache.ScopeableSharedIndexInformer {
+	options.ClusterListerWatcher = nil
+	informer := newSharedIndexInformer(nil, exampleObject, options, kcpcache.MetaClusterNamespaceKeyFunc)
+	informer.source = source
+	return informer
+}
+
+// runSource feeds the informer from its source until ctx is done, and closes
+// s.synced once the initial objects of the source have been indexed.
+func (s *sharedIndexInformer) runSource(ctx context.Context, wg *wait.Group) {
+	registration, err := s.source.AddEventHandlerWithResyncPeriod(&sourceHandler{informer: s}, 0)
+	if err != nil {
+		utilruntime.HandleErrorWithContext(ctx, err, "Failed to watch the source informer")
+		<-ctx.Done()
+		return
+	}
+	defer func() {
+		if err := s.source.RemoveEventHandler(registration); err != nil {
+			utilruntime.HandleErrorWithContext(ctx, err, "Failed to stop watching the source informer")
+		}
+	}()
+
+	wg.Start(func() {
+		select {
+		case <-ctx.Done():
+			// We were stopped without completing the sync.
+		case <-registration.HasSyncedChecker().Done():
+			close(s.synced)
+		}
+	})
+
+	s.driveResyncs(ctx, func() {
+		s.blockDeltas.Lock()
+		defer s.blockDeltas.Unlock()
+		for _, obj := range s.indexer.List() {
+			s.OnUpdate(obj, obj)
+		}
+	})
+}
+
+// sourceHandler applies the notifications of the source informer to the
+// indexer of informer, and distributes them.
+type sourceHandler struct {
+	informer *sharedIndexInformer
+}
+
+func (h *sourceHandler) OnAdd(obj interface{}, isInInitialList bool) {
+	s := h.informer
+	obj, err := h.transform(obj)
+	if err != nil {
+		utilruntime.HandleError(err)
+		return
+	}
+
+	s.blockDeltas.Lock()
+	defer s.blockDeltas.Unlock()
+	if err := s.indexer.Add(obj); err != nil {
+		utilruntime.HandleError(err)
+		return
+	}
+	s.OnAdd(obj, isInInitialList)
+}
+
+func (h *sourceHandler) OnUpdate(_, newObj interface{}) {
+	s := h.informer
+	newObj, err := h.transform(newObj)
+	if err != nil {
+		utilruntime.HandleError(err)
+		return
+	}
+
+	s.blockDeltas.Lock()
+	defer s.blockDeltas.Unlock()
+	// The old object of the source has not been transformed, use the one
+	// in the indexer instead.
+	oldObj, exists, err := s.indexer.Get(newObj)
+	if err != nil {
+		utilruntime.HandleError(err)
+		return
+	}
+	if err := s.indexer.Update(newObj); err != nil {
+		utilruntime.HandleError(err)
+		return
+	}
+	if !exists {
+		s.OnAdd(newObj, false)
+		return
+	}
+	s.OnUpdate(oldObj, newObj)
+}
+
+func (h *sourceHandler) OnDelete(obj interface{}) {
+	s := h.informer
+	tombstone, isTombstone := obj.(cache.DeletedFinalStateUnknown)
+	if isTombstone {
+		obj = tombstone.Obj
+	}
+
+	s.blockDeltas.Lock()
+	defer s.blockDeltas.Unlock()
+	var oldObj interface{}
+	var exists bool
+	var err error
+	if obj == nil {
+		oldObj, exists, err = h.getByPlainKey(tombstone.Key)
+	} else {
+		oldObj, exists, err = s.indexer.Get(obj)
+	}
+	if err != nil {
+		utilruntime.HandleError(err)
+		return
+	}
+	if !exists {
+		return
+	}
+	if err := s.indexer.Delete(oldObj); err != nil {
+		utilruntime.HandleError(err)
+		return
+	}
+	if isTombstone {
+		// The key of the source's tombstone lacks the cluster.
+		key, err := s.keyFunc(oldObj)
+		if err != nil {
+			utilruntime.HandleError(err)
+			return
+		}
+		s.OnDelete(cache.DeletedFinalStateUnknown{Key: key, Obj: oldObj})
+		return
+	}
+	s.OnDelete(oldObj)
+}
+
+// getByPlainKey returns the object of the indexer with the namespace and
+// name of key, a cache.MetaNamespaceKeyFunc key of the source. The key lacks
+// the cluster, it is an error if several clusters hold such an object.
+func (h *sourceHandler) getByPlainKey(key string) (interface{}, bool, error) {
+	namespace, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		return nil, false, err
+	}
+	var found interface{}
+	for _, obj := range h.informer.indexer.List() {
+		m, err := meta.Accessor(obj)
+		if err != nil {
+			return nil, false, err
+		}
+		if m.GetNamespace() != namespace || m.GetName() != name {
+			continue
+		}
+		if found != nil {
+			return nil, false, fmt.Errorf("deleted object %q of the source is ambiguous, it exists in several clusters", key)
+		}
+		found = obj
+	}
+	return found, found != nil, nil
+}
+
+func (h *sourceHandler) transform(obj interface{}) (interface{}, error) {
+	if h.informer.transform == nil {
+		return obj, nil
+	}
+	return h.informer.transform(obj)
+}
+
+// NewPlainSharedIndexInformer returns a client-go style view of clusterName
+// in informer. Its handlers only see the objects of the cluster, and its
+// store and indexer are keyed with cache.MetaNamespaceKeyFunc, without the
+// cluster, see kcpcache.NewScopedIndexer. The keys of the
+// cache.DeletedFinalStateUnknown tombstones its handlers get are rewritten
+// the same way. It can be passed to code that expects an informer of a
+// single cluster, like client-go listers.
+//
+// The view is informer.Cluster(clusterName): in lazy mode, the reflector of
+// clusterName is pinned until the informer stops, see
+// SharedIndexInformerOptions.ClusterListerWatcher.
+func NewPlainSharedIndexInformer(informer kcpcache.ScopeableSharedIndexInformer, clusterName logicalcluster.Name) cache.SharedIndexInformer {
+	return &plainSharedIndexInformer{
+		SharedIndexInformer: informer.Cluster(clusterName),
+		indexer:             kcpcache.NewScopedIndexer(informer.GetIndexer(), clusterName, metav1.NamespaceAll),
+	}
+}
+
+type plainSharedIndexInformer struct {
+	cache.SharedIndexInformer
+	indexer cache.Indexer
+}
+
+func (p *plainSharedIndexInformer) GetStore() cache.Store {
+	return p.indexer
+}
+
+func (p *plainSharedIndexInformer) GetIndexer() cache.Indexer {
+	return p.indexer
+}
+
+func (p *plainSharedIndexInformer) AddEventHandler(handler cache.ResourceEventHandler) (cache.ResourceEventHandlerRegistration, error) {
+	return p.SharedIndexInformer.AddEventHandler(&plainHandler{handler: handler})
+}
+
+func (p *plainSharedIndexInformer) AddEventHandlerWithResyncPeriod(handler cache.ResourceEventHandler, resyncPeriod time.Duration) (cache.ResourceEventHandlerRegistration, error) {
+	return p.SharedIndexInformer.AddEventHandlerWithResyncPeriod(&plainHandler{handler: handler}, resyncPeriod)
+}
+
+func (p *plainSharedIndexInformer) AddEventHandlerWithOptions(handler cache.ResourceEventHandler, options cache.HandlerOptions) (cache.ResourceEventHandlerRegistration, error) {
+	return p.SharedIndexInformer.AddEventHandlerWithOptions(&plainHandler{handler: handler}, options)
+}
+
+// plainHandler passes the notifications of a plain view to handler, with
+// the keys of tombstones rewritten to those of cache.MetaNamespaceKeyFunc.
+type plainHandler struct {
+	handler cache.ResourceEventHandler
+}
+
+func (h *plainHandler) OnAdd(obj interface{}, isInInitialList bool) {
+	h.handler.OnAdd(obj, isInInitialList)
+}
+
+func (h *plainHandler) OnUpdate(oldObj, newObj interface{}) {
+	h.handler.OnUpdate(oldObj, newObj)
+}
+
+func (h *plainHandler) OnDelete(obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		if _, _, namespace, name, err := kcpcache.SplitMetaShardClusterNamespaceKey(tombstone.Key); err == nil {
+			tombstone.Key = cache.NewObjectName(namespace, name).String()
+			obj = tombstone
+		}
+	}
+	h.handler.OnDelete(obj)
+}
+
+func (h *plainHandler) Unwrap() any {
+	return h.handler
+}
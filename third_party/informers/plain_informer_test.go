@@ -0,0 +1,146 @@
+/*
+Copyright 2026 The kcp Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package informers
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/cache"
+	fcache "k8s.io/client-go/tools/cache/testing"
+)
+
+// startPlainSource runs a client-go informer of source until the test ends.
+func startPlainSource(t *testing.T, source *fcache.FakeControllerSource) cache.SharedIndexInformer {
+	t.Helper()
+
+	plain := cache.NewSharedIndexInformer(source, &corev1.Pod{}, 0, cache.Indexers{})
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go plain.RunWithContext(ctx)
+	require.True(t, cache.WaitForCacheSync(ctx.Done(), plain.HasSynced))
+	return plain
+}
+
+// startFromPlain runs an informer fed by plain until the test ends.
+// handler, if not nil, is registered before the informer starts.
+func startFromPlain(t *testing.T, plain cache.SharedIndexInformer, handler cache.ResourceEventHandler) *sharedIndexInformer {
+	t.Helper()
+
+	informer := NewSharedIndexInformerFromPlain(plain, &corev1.Pod{}, SharedIndexInformerOptions{}).(*sharedIndexInformer)
+	if handler != nil {
+		_, err := informer.AddEventHandler(handler)
+		require.NoError(t, err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		informer.RunWithContext(ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-stopped
+	})
+	require.True(t, cache.WaitForCacheSync(ctx.Done(), informer.HasSynced))
+	return informer
+}
+
+func TestSharedIndexInformerFromPlain(t *testing.T) {
+	source := fcache.NewFakeControllerSource()
+	source.Add(newPod("c1", "a", ""))
+	source.Add(newPod("c2", "b", ""))
+	handler := newRecordingHandler(false)
+	informer := startFromPlain(t, startPlainSource(t, source), handler)
+
+	require.ElementsMatch(t, []string{"c1|ns/a", "c2|ns/b"}, informer.GetIndexer().ListKeys())
+	require.Equal(t, []string{"ns/a"}, NewPlainSharedIndexInformer(informer, "c1").GetIndexer().ListKeys())
+
+	source.Modify(newPod("c1", "a", ""))
+	source.Delete(newPod("c2", "b", ""))
+	require.Eventually(t, func() bool {
+		return len(handler.recorded()) == 4
+	}, wait.ForeverTestTimeout, 10*time.Millisecond)
+	require.ElementsMatch(t, []string{"add a@1", "add b@2"}, handler.recorded()[:2])
+	require.Equal(t, []string{"update a@1->3", "delete b@2"}, handler.recorded()[2:])
+	require.Equal(t, []string{"c1|ns/a"}, informer.GetIndexer().ListKeys())
+}
+
+func TestSharedIndexInformerFromPlainExampleObject(t *testing.T) {
+	// The source is not started, it is empty.
+	plain := cache.NewSharedIndexInformer(fcache.NewFakeControllerSource(), &corev1.Pod{}, 0, cache.Indexers{})
+	informer := NewSharedIndexInformerFromPlain(plain, &corev1.Pod{}, SharedIndexInformerOptions{})
+	require.Equal(t, "SharedIndexInformer *v1.Pod", informer.HasSyncedChecker().Name())
+}
+
+func TestPlainSharedIndexInformerTombstoneKeys(t *testing.T) {
+	source := fcache.NewFakeControllerSource()
+	source.Add(newPod("c1", "a", ""))
+	source.Add(newPod("c2", "b", ""))
+	informer := startFromPlain(t, startPlainSource(t, source), nil)
+
+	var lock sync.Mutex
+	var keys []string
+	_, err := NewPlainSharedIndexInformer(informer, "c1").AddEventHandler(cache.ResourceEventHandlerFuncs{
+		DeleteFunc: func(obj interface{}) {
+			lock.Lock()
+			defer lock.Unlock()
+			keys = append(keys, obj.(cache.DeletedFinalStateUnknown).Key)
+		},
+	})
+	require.NoError(t, err)
+
+	// The key of the tombstone is the one of the view's indexer.
+	require.NoError(t, informer.PurgeCluster("c1"))
+	require.Eventually(t, func() bool {
+		lock.Lock()
+		defer lock.Unlock()
+		return len(keys) == 1
+	}, wait.ForeverTestTimeout, 10*time.Millisecond)
+	require.Equal(t, []string{"ns/a"}, keys)
+}
+
+func TestSharedIndexInformerFromPlainTombstoneWithoutObject(t *testing.T) {
+	source := fcache.NewFakeControllerSource()
+	source.Add(newPod("c1", "a", ""))
+	source.Add(newPod("c1", "b", ""))
+	handler := newRecordingHandler(false)
+	informer := startFromPlain(t, startPlainSource(t, source), handler)
+	require.Eventually(t, func() bool {
+		return len(handler.recorded()) == 2
+	}, wait.ForeverTestTimeout, 10*time.Millisecond)
+
+	// The key of the source lacks the cluster, the object is looked up by
+	// its namespace and name.
+	sourceHandler := &sourceHandler{informer: informer}
+	sourceHandler.OnDelete(cache.DeletedFinalStateUnknown{Key: "ns/a"})
+	require.Eventually(t, func() bool {
+		return len(handler.recorded()) == 3
+	}, wait.ForeverTestTimeout, 10*time.Millisecond)
+	require.Equal(t, "delete a@1", handler.recorded()[2])
+
+	// A key held by several clusters cannot be resolved.
+	require.NoError(t, informer.GetIndexer().Add(newPod("c2", "b", "")))
+	sourceHandler.OnDelete(cache.DeletedFinalStateUnknown{Key: "ns/b"})
+	require.ElementsMatch(t, []string{"c1|ns/b", "c2|ns/b"}, informer.GetIndexer().ListKeys())
+}
@@ -18,10 +18,12 @@ package informers
 
 import (
 	"context"
+	"fmt"
 	"sync"
 	"time"
 
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/client-go/tools/cache"
 
 	kcpcache "github.com/kcp-dev/apimachinery/v2/pkg/cache"
@@ -33,9 +35,29 @@ import (
 type scopedSharedIndexInformer struct {
 	*sharedIndexInformer
 	clusterName logicalcluster.Name
+	// namespace further restricts an informer scoped to clusterName, see
+	// Namespace.
+	namespace string
+	// ctx is the context of ClusterWithContext, if any. Handlers added
+	// through the informer or its namespace informers are removed when it is
+	// done.
+	ctx context.Context
+	// subtree is set instead of clusterName for informers scoped to a
+	// workspace subtree, see Subtree.
+	subtree logicalcluster.Path
+	// clusterSet is set instead of clusterName for informers scoped to a
+	// set of clusters, see ClusterSet.
+	clusterSet *kcpcache.ClusterSet
 
 	handlerRegistrationsLock sync.Mutex
 	handlerRegistrations     map[cache.ResourceEventHandlerRegistration]bool
+	// unsubscribe ends the subscription to the changes of the cluster paths
+	// of a subtree informer, or of the members of a cluster set informer,
+	// while it has handlers. Guarded by handlerRegistrationsLock.
+	unsubscribe func()
+	// subtreeClusters holds the clusters that joined the subtree since the
+	// subscription. Guarded by handlerRegistrationsLock.
+	subtreeClusters sets.Set[logicalcluster.Name]
 }
 
 func newScopedSharedIndexInformer(sharedIndexInformer *sharedIndexInformer, cluster logicalcluster.Name) *scopedSharedIndexInformer {
@@ -46,8 +68,25 @@ func newScopedSharedIndexInformer(sharedIndexInformer *sharedIndexInformer, clus
 	}
 }
 
+func newSubtreeSharedIndexInformer(sharedIndexInformer *sharedIndexInformer, subtree logicalcluster.Path) *scopedSharedIndexInformer {
+	return &scopedSharedIndexInformer{
+		sharedIndexInformer:  sharedIndexInformer,
+		subtree:              subtree,
+		handlerRegistrations: make(map[cache.ResourceEventHandlerRegistration]bool),
+	}
+}
+
+func newClusterSetSharedIndexInformer(sharedIndexInformer *sharedIndexInformer, clusterSet *kcpcache.ClusterSet) *scopedSharedIndexInformer {
+	return &scopedSharedIndexInformer{
+		sharedIndexInformer:  sharedIndexInformer,
+		clusterSet:           clusterSet,
+		handlerRegistrations: make(map[cache.ResourceEventHandlerRegistration]bool),
+	}
+}
+
 func newScopedSharedIndexInformerWithContext(ctx context.Context, sharedIndexInformer *sharedIndexInformer, cluster logicalcluster.Name) *scopedSharedIndexInformer {
 	informer := newScopedSharedIndexInformer(sharedIndexInformer, cluster)
+	informer.ctx = ctx
 	go func() {
 		<-ctx.Done()
 		informer.unregisterAllHandlers()
@@ -55,6 +94,51 @@ func newScopedSharedIndexInformerWithContext(ctx context.Context, sharedIndexInf
 	return informer
 }
 
+var _ kcpcache.NamespaceScoper = &scopedSharedIndexInformer{}
+
+// Namespace returns an informer whose handlers only see the objects of the
+// informer's logical cluster in namespace. Its store and indexer are
+// read-only views of the objects in the namespace, see
+// kcpcache.NewScopedIndexer. If the informer was created with
+// ClusterWithContext, the handlers are removed when its context is done.
+//
+// Informers scoped to a subtree or a cluster set span several logical
+// clusters and cannot be scoped to a namespace, Namespace returns an error
+// for them.
+func (s *scopedSharedIndexInformer) Namespace(namespace string) (cache.SharedIndexInformer, error) {
+	if !s.singleCluster() {
+		if s.clusterSet != nil {
+			return nil, fmt.Errorf("cannot scope an informer for a cluster set to namespace %q", namespace)
+		}
+		return nil, fmt.Errorf("cannot scope an informer for subtree %s to namespace %q", s.subtree, namespace)
+	}
+	informer := newScopedSharedIndexInformer(s.sharedIndexInformer, s.clusterName)
+	informer.namespace = namespace
+	if s.ctx != nil {
+		informer.ctx = s.ctx
+		go func() {
+			<-s.ctx.Done()
+			informer.unregisterAllHandlers()
+		}()
+	}
+	return informer, nil
+}
+
+// GetStore returns the store of the shared informer, or a read-only view of
+// it for namespace informers.
+func (s *scopedSharedIndexInformer) GetStore() cache.Store {
+	return s.GetIndexer()
+}
+
+// GetIndexer returns the indexer of the shared informer, or a read-only view
+// of it for namespace informers.
+func (s *scopedSharedIndexInformer) GetIndexer() cache.Indexer {
+	if s.namespace == "" {
+		return s.sharedIndexInformer.GetIndexer()
+	}
+	return kcpcache.NewScopedIndexer(s.sharedIndexInformer.GetIndexer(), s.clusterName, s.namespace)
+}
+
 // AddEventHandler adds an event handler to the shared informer using the shared informer's resync
 // period.  Events to a single handler are delivered sequentially, but there is no coordination
 // between different handlers.
@@ -77,36 +161,163 @@ func (s *scopedSharedIndexInformer) AddEventHandler(handler cache.ResourceEventH
 // because the implementation takes time to do work and there may
 // be competing load and scheduling noise.
 func (s *scopedSharedIndexInformer) AddEventHandlerWithResyncPeriod(handler cache.ResourceEventHandler, resyncPeriod time.Duration) (cache.ResourceEventHandlerRegistration, error) {
-	scopedHandler := cache.ResourceEventHandlerFuncs{
-		AddFunc: func(obj interface{}) {
-			if s.objectMatches(obj) {
-				handler.OnAdd(obj, true)
-			}
-		},
-		UpdateFunc: func(oldObj, newObj interface{}) {
-			if s.objectMatches(newObj) {
-				handler.OnUpdate(oldObj, newObj)
-			}
-		},
-		DeleteFunc: func(obj interface{}) {
-			if s.objectMatches(obj) {
-				handler.OnDelete(obj)
-			}
-		},
-	}
+	return s.AddEventHandlerWithHandlerOptions(handler, HandlerOptions{ResyncPeriod: &resyncPeriod})
+}
 
-	registration, err := s.sharedIndexInformer.AddEventHandlerWithResyncPeriod(scopedHandler, resyncPeriod)
+// AddEventHandlerWithOptions adds an event handler for the objects in the
+// scope of the informer, see cache.SharedInformer.
+func (s *scopedSharedIndexInformer) AddEventHandlerWithOptions(handler cache.ResourceEventHandler, options cache.HandlerOptions) (cache.ResourceEventHandlerRegistration, error) {
+	return s.AddEventHandlerWithHandlerOptions(handler, HandlerOptions{Logger: options.Logger, ResyncPeriod: options.ResyncPeriod})
+}
+
+// AddEventHandlerWithHandlerOptions is AddEventHandlerWithOptions with the
+// additional options of kcp. A handler disconnected by OverflowDisconnect is
+// removed from this informer.
+func (s *scopedSharedIndexInformer) AddEventHandlerWithHandlerOptions(handler cache.ResourceEventHandler, options HandlerOptions) (cache.ResourceEventHandlerRegistration, error) {
+	// The registration is recorded while no notifications are distributed,
+	// so that scope changes either happen before its initial list, or
+	// send it adds and deletes.
+	registration, err := s.sharedIndexInformer.addEventHandler(&scopedHandler{informer: s, handler: handler}, options, s.RemoveEventHandler, func(registration cache.ResourceEventHandlerRegistration) {
+		s.handlerRegistrationsLock.Lock()
+		defer s.handlerRegistrationsLock.Unlock()
+		s.handlerRegistrations[registration] = true
+		switch {
+		case !s.subtree.Empty():
+			s.subscribeClusterPaths()
+		case s.clusterSet != nil:
+			s.subscribeClusterSet()
+		}
+	})
 	if err != nil {
 		return nil, err
 	}
-
-	s.handlerRegistrationsLock.Lock()
-	defer s.handlerRegistrationsLock.Unlock()
-	s.handlerRegistrations[registration] = true
+	if s.subtree.Empty() && s.clusterSet == nil {
+		s.handlerRegistrationsLock.Lock()
+		defer s.handlerRegistrationsLock.Unlock()
+		s.sharedIndexInformer.trackScopedInformer(s)
+	}
 
 	return registration, nil
 }
 
+// HasSynced returns true once the shared informer has synced, and every handler
+// registered for this informer's logical cluster, through this or any other
+// scoped informer for the same cluster, has been delivered the part of the
+// initial list that belongs to the cluster. In lazy mode, the initial list is
+// the one of the cluster's own reflector.
+func (s *scopedSharedIndexInformer) HasSynced() bool {
+	if !s.sharedIndexInformer.HasSynced() {
+		return false
+	}
+	if !s.singleCluster() {
+		for _, registration := range s.registrations() {
+			if !registration.HasSynced() {
+				return false
+			}
+		}
+		return true
+	}
+	if reflectorSynced := s.sharedIndexInformer.clusterReflectorSynced(s.clusterName); reflectorSynced != nil {
+		select {
+		case <-reflectorSynced.Done():
+		default:
+			return false
+		}
+	}
+	for _, registration := range s.sharedIndexInformer.clusterHandlerRegistrations(s.clusterName) {
+		if !registration.HasSynced() {
+			return false
+		}
+	}
+	return true
+}
+
+// HasSyncedChecker is the DoneChecker equivalent of HasSynced. The set of
+// handlers waited for is fixed when HasSyncedChecker is called. The checker
+// is never done if the informer stops before it has synced.
+func (s *scopedSharedIndexInformer) HasSyncedChecker() cache.DoneChecker {
+	checkers := []cache.DoneChecker{s.sharedIndexInformer.HasSyncedChecker()}
+	if !s.singleCluster() {
+		for _, registration := range s.registrations() {
+			checkers = append(checkers, registration.HasSyncedChecker())
+		}
+		scope := "cluster set"
+		if !s.subtree.Empty() {
+			scope = "subtree " + s.subtree.String()
+		}
+		return newAllDoneChecker(fmt.Sprintf("SharedIndexInformer %T for %s", s.objectType, scope), s.stopCh, checkers...)
+	}
+	if reflectorSynced := s.sharedIndexInformer.clusterReflectorSynced(s.clusterName); reflectorSynced != nil {
+		checkers = append(checkers, reflectorSynced)
+	}
+	for _, registration := range s.sharedIndexInformer.clusterHandlerRegistrations(s.clusterName) {
+		checkers = append(checkers, registration.HasSyncedChecker())
+	}
+	return newAllDoneChecker(fmt.Sprintf("SharedIndexInformer %T for cluster %s", s.objectType, s.clusterName), s.stopCh, checkers...)
+}
+
+// allDoneChecker is done once all of its checkers are done. It only starts
+// waiting for them when Done is first called, and stops waiting once stop is
+// closed, in which case it is never done.
+type allDoneChecker struct {
+	name     string
+	stop     <-chan struct{}
+	checkers []cache.DoneChecker
+
+	once sync.Once
+	done chan struct{}
+}
+
+func newAllDoneChecker(name string, stop <-chan struct{}, checkers ...cache.DoneChecker) *allDoneChecker {
+	return &allDoneChecker{
+		name:     name,
+		stop:     stop,
+		checkers: checkers,
+		done:     make(chan struct{}),
+	}
+}
+
+// wait closes c.done once all checkers are done. It only starts a goroutine
+// if some of them are not done yet.
+func (c *allDoneChecker) wait() {
+	pending := c.checkers
+	for len(pending) > 0 && isDone(pending[0]) {
+		pending = pending[1:]
+	}
+	if len(pending) == 0 {
+		close(c.done)
+		return
+	}
+	go func() {
+		for _, checker := range pending {
+			select {
+			case <-checker.Done():
+			case <-c.stop:
+				return
+			}
+		}
+		close(c.done)
+	}()
+}
+
+func isDone(checker cache.DoneChecker) bool {
+	select {
+	case <-checker.Done():
+		return true
+	default:
+		return false
+	}
+}
+
+func (c *allDoneChecker) Name() string {
+	return c.name
+}
+
+func (c *allDoneChecker) Done() <-chan struct{} {
+	c.once.Do(c.wait)
+	return c.done
+}
+
 // IsStopped reports whether the informer has already been stopped
 func (s *scopedSharedIndexInformer) IsStopped() bool {
 	s.startedLock.Lock()
@@ -115,6 +326,8 @@ func (s *scopedSharedIndexInformer) IsStopped() bool {
 }
 
 func (s *scopedSharedIndexInformer) RemoveEventHandler(handle cache.ResourceEventHandlerRegistration) error {
+	s.processor.interruptListener(handle)
+
 	s.startedLock.Lock()
 	defer s.startedLock.Unlock()
 
@@ -130,10 +343,29 @@ func (s *scopedSharedIndexInformer) RemoveEventHandler(handle cache.ResourceEven
 	s.handlerRegistrationsLock.Lock()
 	defer s.handlerRegistrationsLock.Unlock()
 	delete(s.handlerRegistrations, handle)
+	if len(s.handlerRegistrations) == 0 {
+		s.untrack()
+	}
 	return nil
 }
 
+// registrations returns the handler registrations made through s.
+func (s *scopedSharedIndexInformer) registrations() []cache.ResourceEventHandlerRegistration {
+	s.handlerRegistrationsLock.Lock()
+	defer s.handlerRegistrationsLock.Unlock()
+
+	registrations := make([]cache.ResourceEventHandlerRegistration, 0, len(s.handlerRegistrations))
+	for registration := range s.handlerRegistrations {
+		registrations = append(registrations, registration)
+	}
+	return registrations
+}
+
 func (s *scopedSharedIndexInformer) unregisterAllHandlers() {
+	for _, handle := range s.registrations() {
+		s.processor.interruptListener(handle)
+	}
+
 	s.startedLock.Lock()
 	defer s.startedLock.Unlock()
 	s.blockDeltas.Lock()
@@ -146,16 +378,196 @@ func (s *scopedSharedIndexInformer) unregisterAllHandlers() {
 		utilruntime.HandleError(s.processor.removeListener(handle))
 		delete(s.handlerRegistrations, handle)
 	}
+	s.untrack()
+}
+
+// untrack is called once the last handler is removed. Must be called with
+// handlerRegistrationsLock held.
+func (s *scopedSharedIndexInformer) untrack() {
+	if !s.singleCluster() {
+		if s.unsubscribe != nil {
+			s.unsubscribe()
+			s.unsubscribe = nil
+		}
+		return
+	}
+	s.sharedIndexInformer.untrackScopedInformer(s)
+}
+
+// singleCluster returns whether s is scoped to clusterName, rather than to a
+// subtree or a cluster set.
+func (s *scopedSharedIndexInformer) singleCluster() bool {
+	return s.subtree.Empty() && s.clusterSet == nil
 }
 
 func (s *scopedSharedIndexInformer) objectMatches(obj interface{}) bool {
-	key, err := kcpcache.MetaClusterNamespaceKeyFunc(obj)
-	if err != nil {
-		return false
+	if !s.subtree.Empty() {
+		return kcpcache.ObjectInSubtree(obj, s.subtree, s.clusterPaths)
 	}
-	cluster, _, _, err := kcpcache.SplitMetaClusterNamespaceKey(key)
-	if err != nil {
-		return false
+	cluster, namespace, ok := clusterAndNamespaceOf(obj)
+	if s.clusterSet != nil {
+		return ok && s.clusterSet.Has(cluster)
+	}
+	return ok && cluster == s.clusterName && (s.namespace == "" || namespace == s.namespace)
+}
+
+// scopedHandler passes the notifications for the objects in the scope of
+// informer on to handler.
+type scopedHandler struct {
+	informer *scopedSharedIndexInformer
+	handler  cache.ResourceEventHandler
+}
+
+// Unwrap returns the wrapped handler, which the listener is named after.
+func (h *scopedHandler) Unwrap() any {
+	return h.handler
+}
+
+func (h *scopedHandler) OnAdd(obj interface{}, isInInitialList bool) {
+	if forced, ok := obj.(forcedObject); ok {
+		h.handler.OnAdd(forced.obj, false)
+		return
+	}
+	if h.informer.objectMatches(obj) {
+		h.handler.OnAdd(obj, isInInitialList)
+	}
+}
+
+func (h *scopedHandler) OnUpdate(oldObj, newObj interface{}) {
+	if h.informer.objectMatches(newObj) {
+		h.handler.OnUpdate(oldObj, newObj)
+	}
+}
+
+func (h *scopedHandler) OnDelete(obj interface{}) {
+	if forced, ok := obj.(forcedObject); ok {
+		h.handler.OnDelete(forced.obj)
+		return
+	}
+	if h.informer.objectMatches(obj) {
+		h.handler.OnDelete(obj)
+	}
+}
+
+// forcedObject wraps the objects of the synthetic notifications sent when a
+// cluster joins or leaves the scope. They are delivered without checking the
+// scope, which a leaving cluster's objects no longer match.
+type forcedObject struct {
+	obj interface{}
+}
+
+// clusterPathNotifier is implemented by cluster path lookups that report
+// changes, like kcpcache.ClusterPathCache.
+type clusterPathNotifier interface {
+	AddChangeHandler(fn func(name logicalcluster.Name, path logicalcluster.Path)) (remove func())
+}
+
+// subscribeClusterPaths subscribes a subtree informer to the changes of the
+// cluster paths, if its lookup reports them, so that the handlers get adds
+// for the objects already in the cache of every cluster that joins the
+// subtree, and deletes for those of every cluster that leaves it. Must be
+// called with handlerRegistrationsLock held.
+func (s *scopedSharedIndexInformer) subscribeClusterPaths() {
+	if s.unsubscribe != nil {
+		return
 	}
-	return cluster == s.clusterName
+	notifier, ok := s.clusterPaths.(clusterPathNotifier)
+	if !ok {
+		return
+	}
+	s.subtreeClusters = sets.New[logicalcluster.Name]()
+	if resolver, ok := s.clusterPaths.(kcpcache.ClusterSubtreeResolver); ok {
+		s.subtreeClusters.Insert(resolver.ClustersInSubtree(s.subtree)...)
+	}
+	s.unsubscribe = notifier.AddChangeHandler(s.onClusterPathChanged)
+}
+
+// onClusterPathChanged sends adds for the objects of clusterName to the
+// handlers if the cluster joined the subtree, and deletes if it left it.
+func (s *scopedSharedIndexInformer) onClusterPathChanged(clusterName logicalcluster.Name, path logicalcluster.Path) {
+	s.blockDeltas.Lock()
+	defer s.blockDeltas.Unlock()
+	s.handlerRegistrationsLock.Lock()
+	defer s.handlerRegistrationsLock.Unlock()
+
+	if s.unsubscribe == nil {
+		return
+	}
+	joined := path.HasPrefix(s.subtree)
+	if joined == s.subtreeClusters.Has(clusterName) {
+		return
+	}
+	if joined {
+		s.subtreeClusters.Insert(clusterName)
+	} else {
+		s.subtreeClusters.Delete(clusterName)
+	}
+
+	registrations := make([]cache.ResourceEventHandlerRegistration, 0, len(s.handlerRegistrations))
+	for registration := range s.handlerRegistrations {
+		registrations = append(registrations, registration)
+	}
+	utilruntime.HandleError(kcpcache.ListAllByCluster(s.indexer, clusterName, nil, func(obj interface{}) {
+		// Objects whose path annotation keeps them in or out are unaffected.
+		if s.objectMatches(obj) != joined {
+			return
+		}
+		if joined {
+			s.processor.distributeTo(registrations, addNotification{newObj: forcedObject{obj: obj}})
+		} else {
+			s.processor.distributeTo(registrations, deleteNotification{oldObj: forcedObject{obj: obj}})
+		}
+	}))
+}
+
+// subscribeClusterSet subscribes a cluster set informer to the changes of
+// its members. Must be called with handlerRegistrationsLock held.
+func (s *scopedSharedIndexInformer) subscribeClusterSet() {
+	if s.unsubscribe != nil {
+		return
+	}
+	s.unsubscribe = s.clusterSet.AddChangeHandler(s.onClusterSetChanged)
+}
+
+// onClusterSetChanged sends adds for the objects of the added clusters and
+// deletes for the objects of the removed clusters to the handlers.
+func (s *scopedSharedIndexInformer) onClusterSetChanged(added, removed []logicalcluster.Name) {
+	s.blockDeltas.Lock()
+	defer s.blockDeltas.Unlock()
+	s.handlerRegistrationsLock.Lock()
+	defer s.handlerRegistrationsLock.Unlock()
+
+	if s.unsubscribe == nil {
+		return
+	}
+
+	registrations := make([]cache.ResourceEventHandlerRegistration, 0, len(s.handlerRegistrations))
+	for registration := range s.handlerRegistrations {
+		registrations = append(registrations, registration)
+	}
+	for _, clusterName := range added {
+		utilruntime.HandleError(kcpcache.ListAllByCluster(s.indexer, clusterName, nil, func(obj interface{}) {
+			s.processor.distributeTo(registrations, addNotification{newObj: forcedObject{obj: obj}})
+		}))
+	}
+	for _, clusterName := range removed {
+		utilruntime.HandleError(kcpcache.ListAllByCluster(s.indexer, clusterName, nil, func(obj interface{}) {
+			s.processor.distributeTo(registrations, deleteNotification{oldObj: forcedObject{obj: obj}})
+		}))
+	}
+}
+
+// Add adds clusters to the scope of a cluster set informer.
+func (s *scopedSharedIndexInformer) Add(clusterNames ...logicalcluster.Name) {
+	s.clusterSet.Add(clusterNames...)
+}
+
+// Remove removes clusters from the scope of a cluster set informer.
+func (s *scopedSharedIndexInformer) Remove(clusterNames ...logicalcluster.Name) {
+	s.clusterSet.Remove(clusterNames...)
+}
+
+// Clusters returns the members of a cluster set informer.
+func (s *scopedSharedIndexInformer) Clusters() *kcpcache.ClusterSet {
+	return s.clusterSet
 }
@@ -0,0 +1,210 @@
+/*
+Copyright 2026 The kcp Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package informers
+
+import (
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/cache"
+	fcache "k8s.io/client-go/tools/cache/testing"
+
+	kcpcache "github.com/kcp-dev/apimachinery/v2/pkg/cache"
+	"github.com/kcp-dev/logicalcluster/v3"
+)
+
+func newPodInNamespace(cluster, namespace, name string) *corev1.Pod {
+	pod := newPod(cluster, name, "")
+	pod.Namespace = namespace
+	return pod
+}
+
+func TestClusterNamespace(t *testing.T) {
+	source := fcache.NewFakeControllerSource()
+	source.Add(newPodInNamespace("c1", "ns", "a"))
+	source.Add(newPodInNamespace("c1", "other", "b"))
+	source.Add(newPodInNamespace("c2", "ns", "c"))
+	informer, _, _ := startInformer(t, source)
+
+	scoper, ok := informer.Cluster("c1").(kcpcache.NamespaceScoper)
+	require.True(t, ok, "cluster informers can be scoped to a namespace")
+	namespaced, err := scoper.Namespace("ns")
+	require.NoError(t, err)
+
+	handler := newRecordingHandler(false)
+	_, err = namespaced.AddEventHandler(handler)
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		return len(handler.recorded()) == 1
+	}, wait.ForeverTestTimeout, 10*time.Millisecond)
+	require.Equal(t, []string{"add a@1"}, handler.recorded())
+	require.Equal(t, []string{"ns/a"}, namespaced.GetStore().ListKeys())
+}
+
+func TestMultiClusterNamespace(t *testing.T) {
+	source := fcache.NewFakeControllerSource()
+	informer, _, _ := startInformer(t, source)
+	scopeable := kcpcache.ScopeableSharedIndexInformer(informer)
+
+	subtree := scopeable.(kcpcache.SubtreeScoper).Subtree(logicalcluster.NewPath("root:org"))
+	set := scopeable.(kcpcache.ClusterSetScoper).ClusterSet("c1")
+	for name, scoped := range map[string]cache.SharedIndexInformer{"subtree": subtree, "cluster set": set} {
+		scoper, ok := scoped.(kcpcache.NamespaceScoper)
+		require.True(t, ok, name)
+		namespaced, err := scoper.Namespace("ns")
+		require.Error(t, err, name)
+		require.Nil(t, namespaced, name)
+	}
+}
+
+func TestClusterSetMembershipChanges(t *testing.T) {
+	source := fcache.NewFakeControllerSource()
+	source.Add(newPod("c1", "a", ""))
+	source.Add(newPod("c2", "b", ""))
+	informer, _, _ := startInformer(t, source)
+
+	scoper, ok := kcpcache.ScopeableSharedIndexInformer(informer).(kcpcache.ClusterSetScoper)
+	require.True(t, ok, "informers can be scoped to a cluster set")
+	set := scoper.ClusterSet("c1")
+
+	handler := newRecordingHandler(false)
+	registration, err := set.AddEventHandler(handler)
+	require.NoError(t, err)
+	require.True(t, cache.WaitForCacheSync(wait.NeverStop, registration.HasSynced))
+	require.Equal(t, []string{"add a@1"}, handler.recorded())
+
+	// Objects already in the cache are added and deleted with their cluster.
+	set.Add("c2")
+	set.Remove("c1")
+	require.Eventually(t, func() bool {
+		return len(handler.recorded()) == 3
+	}, wait.ForeverTestTimeout, 10*time.Millisecond)
+
+	// Only the current members are delivered.
+	source.Add(newPod("c1", "c", ""))
+	source.Add(newPod("c2", "d", ""))
+	require.Eventually(t, func() bool {
+		return len(handler.recorded()) == 4
+	}, wait.ForeverTestTimeout, 10*time.Millisecond)
+	require.Never(t, func() bool {
+		return len(handler.recorded()) > 4
+	}, 100*time.Millisecond, 10*time.Millisecond)
+	require.Equal(t, []string{"add a@1", "add b@2", "delete a@1", "add d@4"}, handler.recorded())
+}
+
+func newClusterPath(cluster, path string) *corev1.Pod {
+	pod := newPod(cluster, "path", "")
+	pod.Annotations[kcpcache.ClusterPathAnnotationKey] = path
+	return pod
+}
+
+func TestSubtreeClusterPathChanges(t *testing.T) {
+	paths := kcpcache.NewClusterPathCache()
+	paths.OnAdd(newClusterPath("c1", "root:a"), false)
+	paths.OnAdd(newClusterPath("c2", "root:b"), false)
+
+	source := fcache.NewFakeControllerSource()
+	source.Add(newPod("c1", "a", ""))
+	source.Add(newPod("c2", "b", ""))
+	informer, _, _ := startInformerWithOptions(t, source, SharedIndexInformerOptions{ClusterPaths: paths})
+
+	scoper, ok := kcpcache.ScopeableSharedIndexInformer(informer).(kcpcache.SubtreeScoper)
+	require.True(t, ok, "informers can be scoped to a subtree")
+	subtree := scoper.Subtree(logicalcluster.NewPath("root:a"))
+
+	handler := newRecordingHandler(false)
+	registration, err := subtree.AddEventHandler(handler)
+	require.NoError(t, err)
+	// The scope is checked on delivery, wait for the initial list to be
+	// delivered before changing it.
+	require.True(t, cache.WaitForCacheSync(wait.NeverStop, registration.HasSynced))
+	require.Equal(t, []string{"add a@1"}, handler.recorded())
+
+	// c2 moves into the subtree, c1 out of it, and c2 is deleted.
+	paths.OnUpdate(nil, newClusterPath("c2", "root:a:b"))
+	paths.OnUpdate(nil, newClusterPath("c1", "root:c"))
+	paths.OnDelete(newClusterPath("c2", "root:a:b"))
+	require.Eventually(t, func() bool {
+		return len(handler.recorded()) == 4
+	}, wait.ForeverTestTimeout, 10*time.Millisecond)
+	require.Equal(t, []string{"add a@1", "add b@2", "delete a@1", "delete b@2"}, handler.recorded())
+
+	// Neither cluster is in the subtree anymore.
+	source.Add(newPod("c1", "c", ""))
+	source.Add(newPod("c2", "d", ""))
+	require.Never(t, func() bool {
+		return len(handler.recorded()) > 4
+	}, 100*time.Millisecond, 10*time.Millisecond)
+}
+
+func TestAllDoneChecker(t *testing.T) {
+	// require.Eventually runs the condition in a goroutine of its own, so
+	// poll by hand.
+	goroutines := runtime.NumGoroutine()
+	settled := func() bool {
+		for deadline := time.Now().Add(wait.ForeverTestTimeout); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+			if runtime.NumGoroutine() <= goroutines {
+				return true
+			}
+		}
+		return false
+	}
+
+	done, pending := make(doneChecker), make(doneChecker)
+	close(done)
+	require.True(t, isDone(newAllDoneChecker("done", nil, done, done)), "checkers already done do not need a goroutine")
+	require.True(t, settled())
+
+	// Nothing is waited for until Done is called.
+	stop := make(chan struct{})
+	checker := newAllDoneChecker("pending", stop, done, pending)
+	require.True(t, settled())
+	require.False(t, isDone(checker))
+	close(pending)
+	require.Eventually(t, func() bool {
+		return isDone(checker)
+	}, wait.ForeverTestTimeout, 10*time.Millisecond)
+	require.True(t, settled())
+
+	// Stopping gives up waiting, the checker is never done.
+	checker = newAllDoneChecker("stopped", stop, make(doneChecker))
+	require.False(t, isDone(checker))
+	close(stop)
+	require.True(t, settled())
+	require.False(t, isDone(checker))
+}
+
+func TestScopedHasSyncedCheckerDoesNotLeak(t *testing.T) {
+	source := fcache.NewFakeControllerSource()
+	source.Add(newPod("c1", "a", ""))
+	informer, _, _ := startInformer(t, source)
+	scoped := informer.Cluster("c1")
+	_, err := scoped.AddEventHandler(newRecordingHandler(false))
+	require.NoError(t, err)
+	require.True(t, cache.WaitForCacheSync(wait.NeverStop, scoped.HasSynced))
+
+	goroutines := runtime.NumGoroutine()
+	for range 100 {
+		require.True(t, isDone(scoped.HasSyncedChecker()))
+	}
+	require.LessOrEqual(t, runtime.NumGoroutine(), goroutines)
+}
@@ -0,0 +1,285 @@
+/*
+Copyright 2026 The kcp Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package informers
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+
+	kcpcache "github.com/kcp-dev/apimachinery/v2/pkg/cache"
+)
+
+// NewMultiShardInformer creates an informer that watches the same resource
+// on every shard in shards, keyed by shard name, and feeds all of them into
+// one cache. Each shard has its own reflector, so lists, relists and
+// resourceVersions are tracked per shard, and a relist of one shard never
+// deletes the objects of another.
+//
+// Objects are annotated with the shard they were read from under
+// kcpcache.ShardAnnotationKey, replacing any shard annotation they carry,
+// and are keyed with kcpcache.MetaShardClusterNamespaceKeyFunc as
+// shard|cluster|namespace/name. The same object read from two shards, e.g.
+// while its logical cluster moves between them, is therefore cached twice
+// and handlers see both copies. The indexer is a kcpcache.NewShardIndexer,
+// so listers, scoped informers and work queues, which look objects up by
+// their cluster-aware key, work unchanged. The kcpcache.ShardIndexName and
+// kcpcache.ClusterKeyIndexName indexes are always registered.
+//
+// The informer has synced once every shard has synced. Resyncs are done for
+// all shards at once. options.ClusterListerWatcher is not supported and is
+// ignored.
+func NewMultiShardInformer(shards map[string]cache.ListerWatcher, exampleObject runtime.Object, options SharedIndexInformerOptions) kcpcache.MultiShardInformer {
+	options.ClusterListerWatcher = nil
+	indexers := cache.Indexers{}
+	maps.Copy(indexers, options.Indexers)
+	maps.Copy(indexers, shardIndexers)
+	options.Indexers = indexers
+
+	informer := newSharedIndexInformer(nil, exampleObject, options, kcpcache.MetaShardClusterNamespaceKeyFunc)
+	informer.indexer = kcpcache.NewShardIndexer(informer.indexer)
+	informer.shardListerWatchers = maps.Clone(shards)
+	if informer.shardListerWatchers == nil {
+		informer.shardListerWatchers = map[string]cache.ListerWatcher{}
+	}
+	return &multiShardInformer{sharedIndexInformer: informer}
+}
+
+// shardIndexers are the indexers every multi-shard informer registers.
+var shardIndexers = cache.Indexers{
+	kcpcache.ShardIndexName:      kcpcache.ShardIndexFunc,
+	kcpcache.ClusterKeyIndexName: kcpcache.ClusterKeyIndexFunc,
+}
+
+// multiShardInformer exposes the per-shard state of a sharedIndexInformer
+// created by NewMultiShardInformer.
+type multiShardInformer struct {
+	*sharedIndexInformer
+}
+
+func (m *multiShardInformer) Shards() []string {
+	return slices.Sorted(maps.Keys(m.shardListerWatchers))
+}
+
+func (m *multiShardInformer) ShardHasSynced(shard string) bool {
+	m.startedLock.Lock()
+	defer m.startedLock.Unlock()
+
+	r, ok := m.shardReflectors[shard]
+	if !ok {
+		return false
+	}
+	return r.controller.HasSynced()
+}
+
+func (m *multiShardInformer) ShardLastSyncResourceVersion(shard string) string {
+	m.startedLock.Lock()
+	defer m.startedLock.Unlock()
+
+	r, ok := m.shardReflectors[shard]
+	if !ok {
+		return ""
+	}
+	return r.controller.LastSyncResourceVersion()
+}
+
+// shardReflector is the controller of one shard of a multi-shard informer.
+type shardReflector struct {
+	controller cache.Controller
+	queue      cache.Queue
+}
+
+// newShardReflectors creates the controllers for all shards. Must be called
+// with startedLock held.
+func (s *sharedIndexInformer) newShardReflectors(logger klog.Logger) map[string]*shardReflector {
+	reflectors := make(map[string]*shardReflector, len(s.shardListerWatchers))
+	for shard, lw := range s.shardListerWatchers {
+		controller, queue := s.newController(logger.WithValues("shard", shard), controllerConfig{
+			listerWatcher: lw,
+			clientState:   &shardStore{indexer: s.indexer, shard: shard},
+			keyFunc:       kcpcache.DeletionHandlingMetaShardClusterNamespaceKeyFunc,
+			transform:     shardTransform(shard, s.transform),
+		})
+		reflectors[shard] = &shardReflector{controller: controller, queue: queue}
+	}
+	return reflectors
+}
+
+// runShardReflectors runs the controllers of all shards until ctx is done,
+// and closes s.synced once all of them have synced.
+func (s *sharedIndexInformer) runShardReflectors(ctx context.Context, wg *wait.Group) {
+	checkers := make([]cache.DoneChecker, 0, len(s.shardReflectors))
+	for _, r := range s.shardReflectors {
+		checkers = append(checkers, r.controller.HasSyncedChecker())
+	}
+	allSynced := newAllDoneChecker(fmt.Sprintf("SharedIndexInformer %T on %d shards", s.objectType, len(checkers)), ctx.Done(), checkers...)
+	wg.Start(func() {
+		select {
+		case <-ctx.Done():
+			// We were stopped without completing the sync.
+		case <-allSynced.Done():
+			close(s.synced)
+		}
+	})
+
+	var shardsWg wait.Group
+	defer shardsWg.Wait()
+	for shard, r := range s.shardReflectors {
+		shardsWg.StartWithContext(klog.NewContext(ctx, klog.FromContext(ctx).WithValues("shard", shard)), r.controller.RunWithContext)
+	}
+
+	s.driveResyncs(ctx, func() {
+		for shard, r := range s.shardReflectors {
+			if err := r.queue.Resync(); err != nil {
+				utilruntime.HandleError(fmt.Errorf("failed to resync shard %q: %w", shard, err))
+			}
+		}
+	})
+}
+
+// shardTransform returns a transform that applies transform, if any, and
+// then annotates the object with shard. The annotation is part of the key of
+// the object, so a shard annotation the object already carries is replaced.
+func shardTransform(shard string, transform cache.TransformFunc) cache.TransformFunc {
+	return func(obj interface{}) (interface{}, error) {
+		if transform != nil {
+			var err error
+			if obj, err = transform(obj); err != nil {
+				return nil, err
+			}
+		}
+		m, err := meta.Accessor(obj)
+		if err != nil {
+			return obj, nil
+		}
+		// Objects already in the cache may be passed in again, only write
+		// when needed.
+		annotations := m.GetAnnotations()
+		if annotations[kcpcache.ShardAnnotationKey] == shard {
+			return obj, nil
+		}
+		if annotations == nil {
+			annotations = map[string]string{}
+		}
+		annotations[kcpcache.ShardAnnotationKey] = shard
+		m.SetAnnotations(annotations)
+		return obj, nil
+	}
+}
+
+// shardStore is the view of the informer's indexer that the controller of
+// one shard syncs. Listing only sees the objects of the shard, found through
+// kcpcache.ShardIndexName, so that a relist of one shard never deletes the
+// objects of another.
+type shardStore struct {
+	indexer cache.Indexer
+	shard   string
+}
+
+func (s *shardStore) Add(obj interface{}) error {
+	return s.indexer.Add(obj)
+}
+
+func (s *shardStore) Update(obj interface{}) error {
+	return s.indexer.Update(obj)
+}
+
+func (s *shardStore) Delete(obj interface{}) error {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		// The indexer cannot key tombstones, delete the cached object.
+		cached, exists, err := s.indexer.GetByKey(tombstone.Key)
+		if err != nil || !exists {
+			return err
+		}
+		obj = cached
+	}
+	return s.indexer.Delete(obj)
+}
+
+func (s *shardStore) List() []interface{} {
+	items, err := s.indexer.ByIndex(kcpcache.ShardIndexName, s.shard)
+	if err != nil {
+		// The index is always registered.
+		utilruntime.HandleError(fmt.Errorf("failed to list shard %q: %w", s.shard, err))
+		return nil
+	}
+	return items
+}
+
+func (s *shardStore) ListKeys() []string {
+	keys, err := s.indexer.IndexKeys(kcpcache.ShardIndexName, s.shard)
+	if err != nil {
+		utilruntime.HandleError(fmt.Errorf("failed to list shard %q: %w", s.shard, err))
+		return nil
+	}
+	return keys
+}
+
+func (s *shardStore) Get(obj interface{}) (item interface{}, exists bool, err error) {
+	return s.indexer.Get(obj)
+}
+
+func (s *shardStore) GetByKey(key string) (item interface{}, exists bool, err error) {
+	return s.indexer.GetByKey(key)
+}
+
+// Replace replaces the objects of the shard with list. The resource version
+// is dropped, as it is only meaningful per shard.
+func (s *shardStore) Replace(list []interface{}, _ string) error {
+	newKeys := sets.New[string]()
+	for _, obj := range list {
+		key, err := kcpcache.DeletionHandlingMetaShardClusterNamespaceKeyFunc(obj)
+		if err != nil {
+			return cache.KeyError{Obj: obj, Err: err}
+		}
+		newKeys.Insert(key)
+		if err := s.indexer.Update(obj); err != nil {
+			return err
+		}
+	}
+	for _, obj := range s.List() {
+		key, err := kcpcache.MetaShardClusterNamespaceKeyFunc(obj)
+		if err != nil || newKeys.Has(key) {
+			continue
+		}
+		if err := s.indexer.Delete(obj); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LastStoreSyncResourceVersion returns an empty string, as resource versions
+// of the shared indexer are not meaningful for a single shard.
+func (s *shardStore) LastStoreSyncResourceVersion() string {
+	return ""
+}
+
+func (s *shardStore) Bookmark(string) {}
+
+func (s *shardStore) Resync() error {
+	return nil
+}
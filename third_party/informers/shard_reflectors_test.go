@@ -0,0 +1,162 @@
+/*
+Copyright 2026 The kcp Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package informers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/cache"
+	fcache "k8s.io/client-go/tools/cache/testing"
+
+	kcpcache "github.com/kcp-dev/apimachinery/v2/pkg/cache"
+	kcpworkqueue "github.com/kcp-dev/apimachinery/v2/pkg/workqueue"
+)
+
+// startMultiShardInformer runs a multi-shard informer of sources until the
+// test ends. handler, if not nil, is registered before the informer starts.
+func startMultiShardInformer(t *testing.T, sources map[string]*fcache.FakeControllerSource, handler cache.ResourceEventHandler) kcpcache.MultiShardInformer {
+	t.Helper()
+
+	shards := make(map[string]cache.ListerWatcher, len(sources))
+	for shard, source := range sources {
+		shards[shard] = source
+	}
+	informer := NewMultiShardInformer(shards, &corev1.Pod{}, SharedIndexInformerOptions{})
+	if handler != nil {
+		_, err := informer.AddEventHandler(handler)
+		require.NoError(t, err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		informer.RunWithContext(ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-stopped
+	})
+	require.True(t, cache.WaitForCacheSync(ctx.Done(), informer.HasSynced))
+	return informer
+}
+
+func shardOf(t *testing.T, informer cache.SharedIndexInformer, key string) string {
+	t.Helper()
+	obj, exists, err := informer.GetIndexer().GetByKey(key)
+	require.NoError(t, err)
+	require.True(t, exists, "%s is missing", key)
+	return obj.(*corev1.Pod).Annotations[kcpcache.ShardAnnotationKey]
+}
+
+func TestMultiShardInformerKeys(t *testing.T) {
+	a, b := fcache.NewFakeControllerSource(), fcache.NewFakeControllerSource()
+	a.Add(newPod("c1", "a", ""))
+	b.Add(newPod("c2", "b", ""))
+	informer := startMultiShardInformer(t, map[string]*fcache.FakeControllerSource{"shard-a": a, "shard-b": b}, nil)
+
+	require.ElementsMatch(t, []string{"shard-a|c1|ns/a", "shard-b|c2|ns/b"}, informer.GetIndexer().ListKeys())
+	require.Equal(t, "shard-a", shardOf(t, informer, "shard-a|c1|ns/a"))
+
+	// Lookups that only know the cluster of an object find it.
+	require.Equal(t, "shard-a", shardOf(t, informer, kcpcache.ToClusterAwareKey("c1", "ns", "a")))
+	require.Equal(t, "shard-b", shardOf(t, informer.Cluster("c2"), kcpcache.ToClusterAwareKey("c2", "ns", "b")))
+	_, exists, err := kcpcache.NewScopedIndexer(informer.GetIndexer(), "c2", "ns").GetByKey("ns/b")
+	require.NoError(t, err)
+	require.True(t, exists)
+	lister := kcpcache.NewGenericClusterLister(informer.GetIndexer(), corev1.Resource("pods"))
+	_, err = lister.ByCluster("c2").ByNamespace("ns").Get("b")
+	require.NoError(t, err)
+
+	items, err := informer.GetIndexer().ByIndex(kcpcache.ShardIndexName, "shard-b")
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+
+	// Work queue keys of tombstones drop the shard, and resolve to the
+	// cached object.
+	tombstone := cache.DeletedFinalStateUnknown{Key: "shard-a|c1|ns/a"}
+	key, err := kcpcache.DeletionHandlingMetaClusterNamespaceKeyFunc(tombstone)
+	require.NoError(t, err)
+	require.Equal(t, "c1|ns/a", key)
+	_, exists, err = informer.GetIndexer().GetByKey(key)
+	require.NoError(t, err)
+	require.True(t, exists)
+	queueKey, err := kcpworkqueue.ClusterQueueKeyFor(tombstone)
+	require.NoError(t, err)
+	require.Equal(t, key, queueKey.String())
+}
+
+func TestMultiShardInformerReplacesShardAnnotation(t *testing.T) {
+	a := fcache.NewFakeControllerSource()
+	pod := newPod("c1", "a", "")
+	pod.Annotations[kcpcache.ShardAnnotationKey] = "origin"
+	a.Add(pod)
+	informer := startMultiShardInformer(t, map[string]*fcache.FakeControllerSource{"shard-a": a}, nil)
+
+	require.Equal(t, []string{"shard-a|c1|ns/a"}, informer.GetIndexer().ListKeys())
+	require.Equal(t, "shard-a", shardOf(t, informer, kcpcache.ToClusterAwareKey("c1", "ns", "a")))
+}
+
+func TestMultiShardInformerCollision(t *testing.T) {
+	a, b := fcache.NewFakeControllerSource(), fcache.NewFakeControllerSource()
+	a.Add(newPod("c1", "moving", ""))
+	a.Add(newPod("c1", "other", ""))
+	handler := newRecordingHandler(false)
+	informer := startMultiShardInformer(t, map[string]*fcache.FakeControllerSource{"shard-a": a, "shard-b": b}, handler)
+	key := kcpcache.ToClusterAwareKey("c1", "ns", "moving")
+	waitForEvents := func(n int) {
+		t.Helper()
+		require.Eventually(t, func() bool {
+			return len(handler.recorded()) == n
+		}, wait.ForeverTestTimeout, 10*time.Millisecond)
+	}
+	waitForEvents(2)
+
+	// The cluster moves to shard-b. Both copies are kept, and the key
+	// without a shard resolves to the one of the first shard.
+	b.Add(newPod("c1", "moving", ""))
+	waitForEvents(3)
+	copies, err := informer.GetIndexer().ByIndex(kcpcache.ClusterKeyIndexName, key)
+	require.NoError(t, err)
+	require.Len(t, copies, 2)
+	require.Equal(t, "shard-a", shardOf(t, informer, key))
+
+	// Neither a deletion on shard-a nor a relist of shard-a without the
+	// object removes the copy of shard-b.
+	a.Delete(newPod("c1", "moving", ""))
+	waitForEvents(4)
+	a.DeleteDropWatch(newPod("c1", "other", ""))
+	a.ResetWatch()
+	waitForEvents(5)
+	require.Equal(t, []string{"shard-b|c1|ns/moving"}, informer.GetIndexer().ListKeys())
+	require.Equal(t, "shard-b", shardOf(t, informer, key))
+
+	b.Delete(newPod("c1", "moving", ""))
+	waitForEvents(6)
+	_, exists, err := informer.GetIndexer().GetByKey(key)
+	require.NoError(t, err)
+	require.False(t, exists)
+
+	events := handler.recorded()
+	require.ElementsMatch(t, []string{"add moving@1", "add other@2"}, events[:2])
+	require.Equal(t, []string{"add moving@1", "delete moving@3", "delete other@2", "delete moving@2"}, events[2:])
+}
@@ -21,6 +21,8 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"maps"
+	"reflect"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -40,6 +42,7 @@ import (
 	utiltrace "k8s.io/utils/trace"
 
 	kcpcache "github.com/kcp-dev/apimachinery/v2/pkg/cache"
+	kcpclient "github.com/kcp-dev/apimachinery/v2/pkg/client"
 	kcpreflector "github.com/kcp-dev/apimachinery/v2/third_party/reflector"
 	"github.com/kcp-dev/logicalcluster/v3"
 )
@@ -57,6 +60,59 @@ type HandlerOptions struct {
 	//
 	// If nil, the default resync period of the shared informer is used.
 	ResyncPeriod *time.Duration
+
+	// kcp modification: bounded notification buffers.
+
+	// MaxPendingNotifications bounds the number of notifications queued for
+	// the handler, not counting the one it is handling. Zero means the
+	// buffer is unbounded, like in client-go. Apart from OverflowBlock, the
+	// bound only applies once the handler has synced: the initial list is
+	// always delivered in full, and the notifications queued meanwhile are
+	// trimmed at the next overflow.
+	MaxPendingNotifications int
+
+	// OverflowPolicy decides what happens when MaxPendingNotifications is
+	// reached. It defaults to OverflowBlock.
+	OverflowPolicy OverflowPolicy
+
+	// OnDisconnect is called when the handler has been removed by the
+	// OverflowDisconnect policy. err wraps ErrHandlerOverflow.
+	OnDisconnect func(err error)
+
+	// CoalesceNotifications merges the notifications queued for the handler
+	// by the cluster-aware key of their object, see
+	// kcpcache.MetaClusterNamespaceKeyFunc: add+update becomes an add,
+	// update+update becomes one update from the oldest to the newest object,
+	// and add+delete is dropped. The handler sees fewer notifications while
+	// it lags behind, but reaches the same final state. The notification
+	// being handled and the next one to be handled are not merged. A merged
+	// notification takes no room towards MaxPendingNotifications.
+	CoalesceNotifications bool
+
+	// RecoverPanics recovers panics of the handler instead of crashing the
+	// process, which is what utilruntime.HandleCrash does by default. The
+	// notification is skipped and, like with a non-crashing
+	// utilruntime.ReallyCrash, the handler gets the next one after a second.
+	// The other handlers of the informer are not affected.
+	RecoverPanics bool
+
+	// OnPanic is called with a *HandlerPanicError for every panic recovered
+	// because of RecoverPanics.
+	OnPanic func(err error)
+
+	// SlowHandlerThreshold is the time after which the handling of a
+	// notification is logged as slow at verbosity level 2, together with
+	// the key of its object, and counted by the slow notifications metric
+	// of the HandlerMetricsProvider. This is reported once, while the
+	// handler is still running, so that blocked handlers are noticed. Zero
+	// disables it.
+	SlowHandlerThreshold time.Duration
+}
+
+// EventHandlerAdder is implemented by the informers of this package,
+// including the scoped ones, to add handlers with kcp's HandlerOptions.
+type EventHandlerAdder interface {
+	AddEventHandlerWithHandlerOptions(handler cache.ResourceEventHandler, options HandlerOptions) (cache.ResourceEventHandlerRegistration, error)
 }
 
 // NewSharedInformer creates a new instance for the ListerWatcher. See NewSharedIndexInformerWithOptions for full details.
@@ -77,6 +133,61 @@ func NewSharedIndexInformer(lw cache.ListerWatcher, exampleObject runtime.Object
 	)
 }
 
+// SharedIndexInformerOptions extends [cache.SharedIndexInformerOptions] with
+// logical-cluster-aware configuration. May be left empty apart from the
+// embedded options.
+type SharedIndexInformerOptions struct {
+	cache.SharedIndexInformerOptions
+
+	// SubscribeToClusterDeletion subscribes the informer to
+	// [kcpclient.EvictCluster]. When a logical cluster is evicted, every
+	// handler registered through Cluster or ClusterWithContext for that
+	// cluster is removed. The subscription is weak and does not keep the
+	// informer alive.
+	SubscribeToClusterDeletion bool
+
+	// PurgeOnClusterDeletion subscribes the informer to
+	// [kcpclient.EvictCluster] and calls PurgeCluster for every evicted
+	// logical cluster. If SubscribeToClusterDeletion is also set, the purge
+	// runs first, so scoped handlers are sent their deletions before they are
+	// removed; delivery of those deletions is best-effort.
+	PurgeOnClusterDeletion bool
+
+	// ClusterListerWatcher switches the informer into lazy mode. Instead of
+	// one wildcard watch through the ListerWatcher passed to the
+	// constructor, which may then be nil, Cluster and ClusterWithContext
+	// start a dedicated reflector for their logical cluster on demand, using
+	// the ListerWatcher returned for the cluster's path. All reflectors feed
+	// the shared cluster-aware indexer.
+	//
+	// A reflector keeps running as long as it has references. Every
+	// ClusterWithContext holds one until its context is cancelled. Cluster
+	// pins the reflector of its cluster for the lifetime of the informer.
+	// Once the last reference is gone, the reflector is stopped and the
+	// cluster is purged from the cache.
+	//
+	// In lazy mode HasSynced of the informer itself only reports that it has
+	// started; use the per-cluster HasSynced of the scoped informers, or
+	// WaitForClusterSync.
+	ClusterListerWatcher func(clusterPath logicalcluster.Path) cache.ListerWatcher
+
+	// ClusterPartitionedStore keeps the informer's objects in a
+	// [kcpcache.ClusterIndexer] instead of the client-go indexer. Listing the
+	// objects of one logical cluster then needs no registered index, and
+	// PurgeCluster drops a cluster at once. Store metrics of the embedded
+	// options are not reported for it.
+	ClusterPartitionedStore bool
+
+	// ClusterPaths looks up the paths of logical clusters for Subtree, e.g.
+	// a [kcpcache.ClusterPathCache] fed by a LogicalCluster informer.
+	ClusterPaths kcpcache.ClusterPathLookup
+
+	// HandlerMetricsProvider reports the pending notifications, delivery
+	// latency, execution time and removals of every event handler, labelled
+	// with the handler's name and the informer's Identifier.
+	HandlerMetricsProvider kcpcache.HandlerMetricsProvider
+}
+
 // NewSharedIndexInformerWithOptions creates a new instance for the ListerWatcher.
 // The created informer will not do resyncs if options.ResyncPeriod is zero.  Otherwise: for each
 // handler that with a non-zero requested resync period, whether added
@@ -88,17 +199,45 @@ func NewSharedIndexInformer(lw cache.ListerWatcher, exampleObject runtime.Object
 // requested before the informer starts and the
 // options.ResyncPeriod given here and (b) the constant
 // `minimumResyncPeriod` defined in this file.
+//
+// To opt into logical-cluster-aware options like ClusterPartitionedStore, use
+// NewClusterAwareSharedIndexInformer.
 func NewSharedIndexInformerWithOptions(lw cache.ListerWatcher, exampleObject runtime.Object, options cache.SharedIndexInformerOptions) kcpcache.ScopeableSharedIndexInformer {
+	return NewClusterAwareSharedIndexInformer(lw, exampleObject, SharedIndexInformerOptions{SharedIndexInformerOptions: options})
+}
+
+// NewClusterAwareSharedIndexInformer is like NewSharedIndexInformerWithOptions, but additionally
+// accepts the logical-cluster-aware options in SharedIndexInformerOptions.
+func NewClusterAwareSharedIndexInformer(lw cache.ListerWatcher, exampleObject runtime.Object, options SharedIndexInformerOptions) kcpcache.ScopeableSharedIndexInformer {
+	return newSharedIndexInformer(lw, exampleObject, options, kcpcache.MetaClusterNamespaceKeyFunc)
+}
+
+// newSharedIndexInformer creates a sharedIndexInformer whose indexer keys
+// objects with keyFunc.
+func newSharedIndexInformer(lw cache.ListerWatcher, exampleObject runtime.Object, options SharedIndexInformerOptions, keyFunc cache.KeyFunc) *sharedIndexInformer {
 	realClock := &clock.RealClock{}
 
-	processor := &sharedProcessor{clock: realClock}
+	processor := &sharedProcessor{clock: realClock, stopping: make(chan struct{})}
 	processor.listenersRCond = sync.NewCond(processor.listenersLock.RLocker())
 
-	return &sharedIndexInformer{
-		// kcp modification: We changed the keyfunction passed to NewIndexer
-		indexer:                         cache.NewIndexer(kcpcache.MetaClusterNamespaceKeyFunc, options.Indexers, cache.WithStoreMetrics(options.Identifier, options.InformerMetricsProvider)),
+	// kcp modification: the cluster indexes are always registered, so that
+	// listing by cluster never falls back to scanning all objects.
+	indexers := maps.Clone(builtinIndexers)
+	maps.Copy(indexers, options.Indexers)
+
+	// kcp modification: We changed the keyfunction passed to NewIndexer
+	var indexer cache.Indexer
+	if options.ClusterPartitionedStore {
+		indexer = kcpcache.NewClusterIndexer(keyFunc, indexers)
+	} else {
+		indexer = cache.NewIndexer(keyFunc, indexers, cache.WithStoreMetrics(options.Identifier, options.InformerMetricsProvider))
+	}
+
+	informer := &sharedIndexInformer{
+		indexer:                         indexer,
 		processor:                       processor,
 		synced:                          make(chan struct{}),
+		stopCh:                          make(chan struct{}),
 		listerWatcher:                   lw,
 		objectType:                      exampleObject,
 		objectDescription:               options.ObjectDescription,
@@ -108,8 +247,20 @@ func NewSharedIndexInformerWithOptions(lw cache.ListerWatcher, exampleObject run
 		cacheMutationDetector:           cache.NewCacheMutationDetector(fmt.Sprintf("%T", exampleObject)),
 		identifier:                      options.Identifier,
 		informerMetricsProvider:         options.InformerMetricsProvider,
-		keyFunc:                         kcpcache.MetaClusterNamespaceKeyFunc,
+		keyFunc:                         keyFunc,
+		scopedInformers:                 map[logicalcluster.Name]sets.Set[*scopedSharedIndexInformer]{},
+		clusterListerWatcher:            options.ClusterListerWatcher,
+		clusterPaths:                    options.ClusterPaths,
+		handlerMetrics:                  newHandlerMetrics(options.HandlerMetricsProvider, options.Identifier),
+		clusterReflectors:               map[logicalcluster.Name]*clusterReflector{},
+		purgingClusters:                 map[logicalcluster.Name]chan struct{}{},
 	}
+	informer.unregisterOnClusterDeletion = options.SubscribeToClusterDeletion
+	informer.purgeOnClusterDeletion = options.PurgeOnClusterDeletion
+	if options.SubscribeToClusterDeletion || options.PurgeOnClusterDeletion {
+		informer.unsubscribeClusterDeleted = kcpclient.OnClusterDeletedWeak(informer.onClusterDeleted)
+	}
+	return informer
 }
 
 // InformerSynced is a function that can be used to determine if an informer has synced.  This is useful for determining if caches have synced.
@@ -161,6 +312,36 @@ func WaitForNamedCacheSyncWithContext(ctx context.Context, cacheSyncs ...Informe
 	return true
 }
 
+// WaitForClusterSync waits until informer has synced for each of the given
+// logical clusters, i.e. the initial list has been processed and every
+// handler registered through informer.Cluster or informer.ClusterWithContext
+// for one of these clusters has been delivered its part of it. In lazy mode,
+// WaitForClusterSync does not start reflectors itself; the clusters must be
+// held through Cluster or ClusterWithContext. Like
+// WaitForNamedCacheSyncWithContext, it logs before and after waiting.
+func WaitForClusterSync(ctx context.Context, informer kcpcache.ScopeableSharedIndexInformer, clusters ...logicalcluster.Name) bool {
+	logger := klog.FromContext(ctx)
+	logger.Info("Waiting for clusters to sync", "clusters", clusters)
+
+	cacheSyncs := make([]InformerSynced, 0, len(clusters))
+	for _, clusterName := range clusters {
+		if s, ok := informer.(*sharedIndexInformer); ok {
+			// Don't go through Cluster, which would start a reflector in
+			// lazy mode that is never released again.
+			cacheSyncs = append(cacheSyncs, newScopedSharedIndexInformer(s, clusterName).HasSynced)
+			continue
+		}
+		cacheSyncs = append(cacheSyncs, informer.Cluster(clusterName).HasSynced)
+	}
+	if !WaitForCacheSync(ctx.Done(), cacheSyncs...) {
+		utilruntime.HandleErrorWithContext(ctx, nil, "Unable to sync clusters", "clusters", clusters)
+		return false
+	}
+
+	logger.Info("Clusters are synced", "clusters", clusters)
+	return true
+}
+
 // WaitForCacheSync waits for caches to populate.  It returns true if it was successful, false
 // if the controller should shutdown
 // callers should prefer WaitForNamedCacheSync()
@@ -197,6 +378,9 @@ type sharedIndexInformer struct {
 	// synced gets created when creating the sharedIndexInformer.
 	// It gets closed when Run detects that the processor created
 	synced chan struct{}
+	// kcp modification: stopCh is closed once the informer has stopped, so
+	// that the DoneCheckers of scoped informers stop waiting.
+	stopCh chan struct{}
 
 	processor             *sharedProcessor
 	cacheMutationDetector cache.MutationDetector
@@ -240,14 +424,232 @@ type sharedIndexInformer struct {
 
 	// keyFunc is called when processing deltas by the underlying process function.
 	keyFunc cache.KeyFunc
-}
 
+	// scopedInformersLock guards scopedInformers.
+	scopedInformersLock sync.Mutex
+	// scopedInformers tracks the scoped informers that currently have handlers
+	// registered, so that they can be torn down when their cluster is deleted.
+	scopedInformers map[logicalcluster.Name]sets.Set[*scopedSharedIndexInformer]
+
+	// unsubscribeClusterDeleted anchors the weak subscription to
+	// kcpclient.EvictCluster, if SubscribeToClusterDeletion or
+	// PurgeOnClusterDeletion was requested.
+	unsubscribeClusterDeleted func()
+	// unregisterOnClusterDeletion and purgeOnClusterDeletion select what
+	// onClusterDeleted does.
+	unregisterOnClusterDeletion bool
+	purgeOnClusterDeletion      bool
+
+	// clusterLifecycle notifies cluster lifecycle handlers when a cluster
+	// gets its first object or loses its last one, which it reads from the
+	// ClusterIndexName index of the indexer. It is nil until the first such
+	// handler is registered, and is guarded by blockDeltas.
+	clusterLifecycle *clusterLifecycleTracker
+
+	// clusterListerWatcher is set in lazy mode, see
+	// SharedIndexInformerOptions.ClusterListerWatcher.
+	clusterListerWatcher func(clusterPath logicalcluster.Path) cache.ListerWatcher
+	// clusterPaths looks up the paths of logical clusters for Subtree.
+	clusterPaths kcpcache.ClusterPathLookup
+	// clusterReflectorsLock guards clusterReflectors, purgingClusters and
+	// clusterReflectorsCtx.
+	clusterReflectorsLock sync.Mutex
+	// clusterReflectors holds the per-cluster reflectors in lazy mode.
+	clusterReflectors map[logicalcluster.Name]*clusterReflector
+	// purgingClusters holds the purged channels of the released reflectors
+	// whose cluster is being purged.
+	purgingClusters map[logicalcluster.Name]chan struct{}
+	// clusterReflectorsCtx is the context of the running informer in lazy
+	// mode, or nil if it is not running.
+	clusterReflectorsCtx context.Context
+
+	// shardListerWatchers is set for multi-shard informers, see
+	// NewMultiShardInformer.
+	shardListerWatchers map[string]cache.ListerWatcher
+	// shardReflectors holds the per-shard controllers of a multi-shard
+	// informer once it has started. Guarded by startedLock.
+	shardReflectors map[string]*shardReflector
+
+	// source feeds an informer created by NewSharedIndexInformerFromPlain
+	// instead of a reflector.
+	source cache.SharedIndexInformer
+
+	// handlerMetrics generates the metrics of the listeners, if set.
+	handlerMetrics *handlerMetrics
+}
+
+// Cluster returns an informer whose handlers only see the objects of
+// cluster.
+//
+// In lazy mode Cluster pins the reflector of cluster: it is never stopped
+// and the cluster is never purged, however often Cluster is called for it.
+// Use ClusterWithContext for clusters that come and go.
 func (s *sharedIndexInformer) Cluster(cluster logicalcluster.Name) cache.SharedIndexInformer {
+	if s.clusterListerWatcher != nil {
+		s.acquireClusterReflector(cluster, true)
+	}
 	return newScopedSharedIndexInformer(s, cluster)
 }
 
+var _ kcpcache.SubtreeScoper = &sharedIndexInformer{}
+
+// Subtree returns an informer whose handlers only see the objects of the
+// logical clusters at path and below it. The path of an object's cluster is
+// read from its kcpcache.ClusterPathAnnotationKey annotation, or else looked
+// up in SharedIndexInformerOptions.ClusterPaths when the event is delivered,
+// so the scope follows the workspaces as they are added. If ClusterPaths
+// reports changes, like kcpcache.ClusterPathCache, handlers get adds for the
+// objects already in the cache of a cluster that joins the subtree, and
+// deletes for those of a cluster that leaves it or is deleted.
+//
+// In lazy mode Subtree starts no reflectors; it only sees the clusters
+// acquired through Cluster or ClusterWithContext.
+func (s *sharedIndexInformer) Subtree(path logicalcluster.Path) cache.SharedIndexInformer {
+	return newSubtreeSharedIndexInformer(s, path)
+}
+
+var _ kcpcache.ClusterSetScoper = &sharedIndexInformer{}
+
+// ClusterSet returns an informer whose handlers only see the objects of the
+// given logical clusters. Clusters can be added to and removed from the scope
+// at any time. Handlers then get adds for the objects already in the cache of
+// an added cluster, and deletes for those of a removed cluster. Objects that
+// are in flight while the scope changes can be seen twice.
+//
+// In lazy mode ClusterSet starts no reflectors; it only sees the clusters
+// acquired through Cluster or ClusterWithContext.
+func (s *sharedIndexInformer) ClusterSet(clusterNames ...logicalcluster.Name) kcpcache.ClusterSetInformer {
+	return newClusterSetSharedIndexInformer(s, kcpcache.NewClusterSet(clusterNames...))
+}
+
 func (s *sharedIndexInformer) ClusterWithContext(ctx context.Context, cluster logicalcluster.Name) cache.SharedIndexInformer {
-	return newScopedSharedIndexInformerWithContext(ctx, s, cluster)
+	if s.clusterListerWatcher == nil {
+		return newScopedSharedIndexInformerWithContext(ctx, s, cluster)
+	}
+
+	s.acquireClusterReflector(cluster, false)
+	informer := newScopedSharedIndexInformer(s, cluster)
+	informer.ctx = ctx
+	go func() {
+		<-ctx.Done()
+		informer.unregisterAllHandlers()
+		s.releaseClusterReflector(cluster)
+	}()
+	return informer
+}
+
+// trackScopedInformer records that scoped has handlers registered.
+func (s *sharedIndexInformer) trackScopedInformer(scoped *scopedSharedIndexInformer) {
+	s.scopedInformersLock.Lock()
+	defer s.scopedInformersLock.Unlock()
+
+	if s.scopedInformers[scoped.clusterName] == nil {
+		s.scopedInformers[scoped.clusterName] = sets.New[*scopedSharedIndexInformer]()
+	}
+	s.scopedInformers[scoped.clusterName].Insert(scoped)
+}
+
+// untrackScopedInformer forgets scoped once its last handler is removed.
+func (s *sharedIndexInformer) untrackScopedInformer(scoped *scopedSharedIndexInformer) {
+	s.scopedInformersLock.Lock()
+	defer s.scopedInformersLock.Unlock()
+
+	scopedInformers := s.scopedInformers[scoped.clusterName]
+	scopedInformers.Delete(scoped)
+	if scopedInformers.Len() == 0 {
+		delete(s.scopedInformers, scoped.clusterName)
+	}
+}
+
+// clusterHandlerRegistrations returns the registrations of all handlers added
+// through scoped informers for clusterName.
+func (s *sharedIndexInformer) clusterHandlerRegistrations(clusterName logicalcluster.Name) []cache.ResourceEventHandlerRegistration {
+	// Scoped informers take their own lock before scopedInformersLock, so
+	// only copy the set here and query them after releasing it.
+	s.scopedInformersLock.Lock()
+	scopedInformers := s.scopedInformers[clusterName].UnsortedList()
+	s.scopedInformersLock.Unlock()
+
+	var registrations []cache.ResourceEventHandlerRegistration
+	for _, scoped := range scopedInformers {
+		registrations = append(registrations, scoped.registrations()...)
+	}
+	return registrations
+}
+
+// onClusterDeleted purges the deleted cluster's objects and removes every
+// handler registered through a scoped informer for it, as configured. Paths
+// that are not a single logical cluster name cannot match any object or
+// scoped informer and are ignored.
+func (s *sharedIndexInformer) onClusterDeleted(clusterPath logicalcluster.Path) {
+	clusterName, ok := clusterPath.Name()
+	if !ok {
+		return
+	}
+
+	if s.purgeOnClusterDeletion {
+		if err := s.PurgeCluster(clusterName); err != nil {
+			utilruntime.HandleError(fmt.Errorf("failed to purge cluster %q from informer cache: %w", clusterName, err))
+		}
+	}
+	if !s.unregisterOnClusterDeletion {
+		return
+	}
+
+	s.scopedInformersLock.Lock()
+	scopedInformers := s.scopedInformers[clusterName].UnsortedList()
+	s.scopedInformersLock.Unlock()
+
+	for _, scoped := range scopedInformers {
+		scoped.unregisterAllHandlers()
+	}
+}
+
+// PurgeCluster removes every object of the given logical cluster from the
+// informer's cache in one operation, with event distribution blocked.
+// Handlers are sent a cache.DeletedFinalStateUnknown for each removed
+// object, since the deletion was not observed from the server. Use this once
+// a logical cluster is gone, so that objects whose delete events were missed
+// or are still in flight do not linger in the indexer. Delete events that
+// arrive for purged objects afterwards are not sent to handlers again.
+func (s *sharedIndexInformer) PurgeCluster(clusterName logicalcluster.Name) error {
+	s.blockDeltas.Lock()
+	defer s.blockDeltas.Unlock()
+
+	if clusterIndexer, ok := s.indexer.(kcpcache.ClusterIndexer); ok {
+		var errs []error
+		for _, obj := range clusterIndexer.DropCluster(clusterName) {
+			key, err := s.keyFunc(obj)
+			if err != nil {
+				errs = append(errs, cache.KeyError{Obj: obj, Err: err})
+				continue
+			}
+			s.OnDelete(cache.DeletedFinalStateUnknown{Key: key, Obj: obj})
+		}
+		return errors.Join(errs...)
+	}
+
+	var objs []interface{}
+	if err := kcpcache.ListAllByCluster(s.indexer, clusterName, nil, func(obj interface{}) {
+		objs = append(objs, obj)
+	}); err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, obj := range objs {
+		key, err := s.keyFunc(obj)
+		if err != nil {
+			errs = append(errs, cache.KeyError{Obj: obj, Err: err})
+			continue
+		}
+		if err := s.indexer.Delete(obj); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		s.OnDelete(cache.DeletedFinalStateUnknown{Key: key, Obj: obj})
+	}
+	return errors.Join(errs...)
 }
 
 // dummyController hides the fact that a SharedInformer is different from a dedicated one
@@ -274,6 +676,10 @@ func (v *dummyController) HasSyncedChecker() cache.DoneChecker {
 }
 
 func (v *dummyController) LastSyncResourceVersion() string {
+	// kcp modification: there is no wildcard controller in lazy mode.
+	if v.informer.controller == nil {
+		return ""
+	}
 	if clientgofeaturegate.FeatureGates().Enabled(clientgofeaturegate.InformerResourceVersion) {
 		return v.informer.controller.LastSyncResourceVersion()
 	}
@@ -342,53 +748,24 @@ func (s *sharedIndexInformer) RunWithContext(ctx context.Context) {
 		s.startedLock.Lock()
 		defer s.startedLock.Unlock()
 
-		// kcp: This is almost verbatim the content of newQueueFIFO in controller.go
-		var fifo cache.Queue
-		if clientgofeaturegate.FeatureGates().Enabled(clientgofeaturegate.InOrderInformers) {
-			fifo = cache.NewRealFIFOWithOptions(cache.RealFIFOOptions{
-				Logger: &logger,
-				Name:   fmt.Sprintf("RealFIFO %T", s.objectType),
-				// KCP modification: We changed the keyfunction passed to NewDeltaFIFOWithOptions
-				KeyFunction:     kcpcache.MetaClusterNamespaceKeyFunc,
-				KnownObjects:    s.indexer,
-				Transformer:     s.transform,
-				Identifier:      s.identifier,
-				MetricsProvider: s.informerMetricsProvider,
-			})
-		} else {
-			fifo = cache.NewDeltaFIFOWithOptions(cache.DeltaFIFOOptions{
-				Logger:                &logger,
-				Name:                  fmt.Sprintf("RealFIFO %T", s.objectType),
-				KnownObjects:          s.indexer,
-				EmitDeltaTypeReplaced: true,
-				Transformer:           s.transform,
-				// kcp modification: We changed the keyfunction passed to NewDeltaFIFOWithOptions
-				KeyFunction: kcpcache.MetaClusterNamespaceKeyFunc,
+		// kcp modification: in lazy mode there is no wildcard controller, the
+		// per-cluster controllers are created by runClusterReflectors. A
+		// multi-shard informer has one controller per shard instead, and an
+		// informer fed by a plain informer has none.
+		switch {
+		case s.source != nil:
+		case s.shardListerWatchers != nil:
+			s.shardReflectors = s.newShardReflectors(logger)
+		case s.clusterListerWatcher == nil:
+			s.controller, _ = s.newController(logger, controllerConfig{
+				listerWatcher: s.listerWatcher,
+				clientState:   s.indexer,
+				keyFunc:       kcpcache.DeletionHandlingMetaClusterNamespaceKeyFunc,
+				transform:     s.transform,
+				resyncPeriod:  s.resyncCheckPeriod,
+				shouldResync:  s.processor.shouldResync,
 			})
 		}
-
-		// kcp modification: use our forked controller that passes the KeyFunction to the reflector
-		// This is critical for WatchList (client-go 1.34+) where the reflector creates a temporaryStore
-		// that needs cluster-aware keys to avoid objects from different clusters overwriting each other.
-		cfg := &kcpreflector.Config{
-			Queue:             fifo,
-			ListerWatcher:     s.listerWatcher,
-			ObjectType:        s.objectType,
-			ObjectDescription: s.objectDescription,
-			FullResyncPeriod:  s.resyncCheckPeriod,
-			ShouldResync:      s.processor.shouldResync,
-
-			Process: func(obj interface{}, isInInitialList bool) error {
-				return s.handleDeltas(logger, obj, isInInitialList)
-			},
-			ProcessBatch: func(deltas []cache.Delta, isInInitialList bool) error {
-				return s.handleBatchDeltas(logger, deltas, isInInitialList)
-			},
-			WatchErrorHandlerWithContext: s.watchErrorHandler,
-			KeyFunction:                  kcpcache.DeletionHandlingMetaClusterNamespaceKeyFunc,
-		}
-
-		s.controller = kcpreflector.New(cfg)
 		// kcp modification: we removed setting the s.controller.clock here as it's an unexported field we can't access
 		s.started = true
 	}()
@@ -402,6 +779,34 @@ func (s *sharedIndexInformer) RunWithContext(ctx context.Context) {
 	defer stopProcessor(errors.New("informer is stopping")) // Tell Processor to stop
 	wg.StartWithChannel(processorStopCtx.Done(), s.cacheMutationDetector.Run)
 	wg.StartWithContext(processorStopCtx, s.processor.run)
+	// kcp modification: the controller waits for deliveries blocked by a
+	// listener, see OverflowBlock, so they are interrupted already when the
+	// informer stops.
+	context.AfterFunc(ctx, s.processor.interrupt)
+
+	defer func() {
+		s.startedLock.Lock()
+		defer s.startedLock.Unlock()
+		s.stopped = true // Don't want any new listeners
+		close(s.stopCh)
+	}()
+
+	if s.source != nil {
+		s.runSource(ctx, &wg)
+		return
+	}
+	if s.shardReflectors != nil {
+		s.runShardReflectors(ctx, &wg)
+		return
+	}
+	if s.clusterListerWatcher != nil {
+		// kcp modification: there is no wildcard list to wait for in lazy mode,
+		// sync is tracked per cluster instead.
+		close(s.synced)
+		s.runClusterReflectors(ctx)
+		return
+	}
+
 	wg.Start(func() {
 		select {
 		case <-ctx.Done():
@@ -411,15 +816,80 @@ func (s *sharedIndexInformer) RunWithContext(ctx context.Context) {
 			close(s.synced)
 		}
 	})
-
-	defer func() {
-		s.startedLock.Lock()
-		defer s.startedLock.Unlock()
-		s.stopped = true // Don't want any new listeners
-	}()
 	s.controller.RunWithContext(ctx)
 }
 
+// controllerConfig holds what differs between the controllers of an informer.
+// There is one controller for a wildcard informer, and one per logical cluster
+// or per shard otherwise.
+type controllerConfig struct {
+	listerWatcher cache.ListerWatcher
+	// clientState is the informer's indexer, or a view of the part of it that
+	// the controller is responsible for.
+	clientState cache.Store
+	// keyFunc keys objects in the queue and the reflector before they are
+	// transformed. It must handle cache.DeletedFinalStateUnknown, and agree
+	// with the indexer's key function on transformed objects.
+	keyFunc      cache.KeyFunc
+	transform    cache.TransformFunc
+	resyncPeriod time.Duration
+	shouldResync cache.ShouldResyncFunc
+}
+
+// newController creates the queue and the reflector-backed controller that
+// feed config.clientState from config.listerWatcher and distribute the
+// resulting notifications.
+func (s *sharedIndexInformer) newController(logger klog.Logger, config controllerConfig) (cache.Controller, cache.Queue) {
+	clientState := config.clientState
+	// kcp: This is almost verbatim the content of newQueueFIFO in controller.go
+	var fifo cache.Queue
+	if clientgofeaturegate.FeatureGates().Enabled(clientgofeaturegate.InOrderInformers) {
+		fifo = cache.NewRealFIFOWithOptions(cache.RealFIFOOptions{
+			Logger: &logger,
+			Name:   fmt.Sprintf("RealFIFO %T", s.objectType),
+			// KCP modification: We changed the keyfunction passed to NewDeltaFIFOWithOptions
+			KeyFunction:     config.keyFunc,
+			KnownObjects:    clientState,
+			Transformer:     config.transform,
+			Identifier:      s.identifier,
+			MetricsProvider: s.informerMetricsProvider,
+		})
+	} else {
+		fifo = cache.NewDeltaFIFOWithOptions(cache.DeltaFIFOOptions{
+			Logger:                &logger,
+			Name:                  fmt.Sprintf("RealFIFO %T", s.objectType),
+			KnownObjects:          clientState,
+			EmitDeltaTypeReplaced: true,
+			Transformer:           config.transform,
+			// kcp modification: We changed the keyfunction passed to NewDeltaFIFOWithOptions
+			KeyFunction: config.keyFunc,
+		})
+	}
+
+	// kcp modification: use our forked controller that passes the KeyFunction to the reflector
+	// This is critical for WatchList (client-go 1.34+) where the reflector creates a temporaryStore
+	// that needs cluster-aware keys to avoid objects from different clusters overwriting each other.
+	cfg := &kcpreflector.Config{
+		Queue:             fifo,
+		ListerWatcher:     config.listerWatcher,
+		ObjectType:        s.objectType,
+		ObjectDescription: s.objectDescription,
+		FullResyncPeriod:  config.resyncPeriod,
+		ShouldResync:      config.shouldResync,
+
+		Process: func(obj interface{}, isInInitialList bool) error {
+			return s.handleDeltas(logger, clientState, obj, isInInitialList)
+		},
+		ProcessBatch: func(deltas []cache.Delta, isInInitialList bool) error {
+			return s.handleBatchDeltas(logger, clientState, deltas, isInInitialList)
+		},
+		WatchErrorHandlerWithContext: s.watchErrorHandler,
+		KeyFunction:                  config.keyFunc,
+	}
+
+	return kcpreflector.New(cfg), fifo
+}
+
 func (s *sharedIndexInformer) HasStarted() bool {
 	s.startedLock.Lock()
 	defer s.startedLock.Unlock()
@@ -480,9 +950,30 @@ func (s *sharedIndexInformer) AddIndexers(indexers cache.Indexers) error {
 		return fmt.Errorf("indexer was not added because it has stopped already")
 	}
 
+	// kcp modification: the cluster indexes are always registered. Skip
+	// them, so that callers adding them themselves do not conflict, unless
+	// they come with index funcs of their own.
+	indexers = maps.Clone(indexers)
+	for name, builtin := range builtinIndexers {
+		indexFunc, ok := indexers[name]
+		if !ok {
+			continue
+		}
+		if reflect.ValueOf(indexFunc).Pointer() != reflect.ValueOf(builtin).Pointer() {
+			return fmt.Errorf("indexer conflict: %s is always registered, with a different index func", name)
+		}
+		delete(indexers, name)
+	}
+
 	return s.indexer.AddIndexers(indexers)
 }
 
+// builtinIndexers are the indexers every informer registers.
+var builtinIndexers = cache.Indexers{
+	kcpcache.ClusterIndexName:             kcpcache.ClusterIndexFunc,
+	kcpcache.ClusterAndNamespaceIndexName: kcpcache.ClusterAndNamespaceIndexFunc,
+}
+
 func (s *sharedIndexInformer) GetController() cache.Controller {
 	return &dummyController{informer: s}
 }
@@ -513,6 +1004,25 @@ func (s *sharedIndexInformer) AddEventHandlerWithResyncPeriod(handler cache.Reso
 }
 
 func (s *sharedIndexInformer) AddEventHandlerWithOptions(handler cache.ResourceEventHandler, options cache.HandlerOptions) (cache.ResourceEventHandlerRegistration, error) {
+	return s.AddEventHandlerWithHandlerOptions(handler, HandlerOptions{Logger: options.Logger, ResyncPeriod: options.ResyncPeriod})
+}
+
+// AddEventHandlerWithHandlerOptions is AddEventHandlerWithOptions with the
+// additional options of kcp, like a bound on the notifications buffered for
+// the handler.
+func (s *sharedIndexInformer) AddEventHandlerWithHandlerOptions(handler cache.ResourceEventHandler, options HandlerOptions) (cache.ResourceEventHandlerRegistration, error) {
+	return s.addEventHandler(handler, options, s.RemoveEventHandler, nil)
+}
+
+// addEventHandler adds handler. remove is used by the OverflowDisconnect
+// policy to remove the handler again. added, if not nil, is called with the
+// registration while no notifications are distributed, before the handler
+// can receive any.
+func (s *sharedIndexInformer) addEventHandler(handler cache.ResourceEventHandler, options HandlerOptions, remove func(cache.ResourceEventHandlerRegistration) error, added func(cache.ResourceEventHandlerRegistration)) (cache.ResourceEventHandlerRegistration, error) {
+	if options.MaxPendingNotifications < 0 {
+		return nil, fmt.Errorf("handler %v was not added to shared informer because MaxPendingNotifications is negative", handler)
+	}
+
 	s.startedLock.Lock()
 	defer s.startedLock.Unlock()
 
@@ -543,9 +1053,26 @@ func (s *sharedIndexInformer) AddEventHandlerWithOptions(handler cache.ResourceE
 	}
 
 	listener := newProcessListener(logger, handler, resyncPeriod, determineResyncPeriod(logger, resyncPeriod, s.resyncCheckPeriod), s.clock.Now(), initialBufferSize, s.HasSyncedChecker())
+	if s.handlerMetrics != nil {
+		listener.metrics = s.handlerMetrics.newListenerMetrics(listener.handlerName)
+	}
+	if options.CoalesceNotifications {
+		listener.coalesced = map[string]*coalescedNotification{}
+	}
+	listener.recoverPanics = options.RecoverPanics
+	listener.onPanic = options.OnPanic
+	listener.slowHandlerThreshold = options.SlowHandlerThreshold
+	listener.setBufferLimit(options.MaxPendingNotifications, options.OverflowPolicy, options.OnDisconnect, func() error {
+		return remove(listener)
+	})
 
 	if !s.started {
+		s.blockDeltas.Lock()
+		defer s.blockDeltas.Unlock()
 		handle, _ := s.processor.addListener(listener)
+		if added != nil {
+			added(handle)
+		}
 		return handle, nil
 	}
 
@@ -558,6 +1085,9 @@ func (s *sharedIndexInformer) AddEventHandlerWithOptions(handler cache.ResourceE
 	defer s.blockDeltas.Unlock()
 
 	handle, started := s.processor.addListener(listener)
+	if added != nil {
+		added(handle)
+	}
 	for _, item := range s.indexer.List() {
 		// Note that we enqueue these notifications with the lock held
 		// and before returning the handle. That means there is never a
@@ -578,20 +1108,20 @@ func (s *sharedIndexInformer) AddEventHandlerWithOptions(handler cache.ResourceE
 	return handle, nil
 }
 
-func (s *sharedIndexInformer) handleDeltas(logger klog.Logger, obj interface{}, isInInitialList bool) error {
+func (s *sharedIndexInformer) handleDeltas(logger klog.Logger, clientState cache.Store, obj interface{}, isInInitialList bool) error {
 	s.blockDeltas.Lock()
 	defer s.blockDeltas.Unlock()
 
 	if deltas, ok := obj.(cache.Deltas); ok {
-		return processDeltas(logger, s, s.indexer, deltas, isInInitialList, s.keyFunc)
+		return processDeltas(logger, s, clientState, deltas, isInInitialList, s.keyFunc)
 	}
 	return errors.New("object given as Process argument is not Deltas")
 }
 
-func (s *sharedIndexInformer) handleBatchDeltas(logger klog.Logger, deltas []cache.Delta, isInInitialList bool) error {
+func (s *sharedIndexInformer) handleBatchDeltas(logger klog.Logger, clientState cache.Store, deltas []cache.Delta, isInInitialList bool) error {
 	s.blockDeltas.Lock()
 	defer s.blockDeltas.Unlock()
-	return processDeltasInBatch(logger, s, s.indexer, deltas, isInInitialList, s.keyFunc)
+	return processDeltasInBatch(logger, s, clientState, deltas, isInInitialList, s.keyFunc)
 }
 
 // Conforms to cache.ResourceEventHandler
@@ -599,6 +1129,9 @@ func (s *sharedIndexInformer) OnAdd(obj interface{}, isInInitialList bool) {
 	// Invocation of this function is locked under s.blockDeltas, so it is
 	// safe to distribute the notification
 	s.cacheMutationDetector.AddObject(obj)
+	if s.clusterLifecycle != nil {
+		s.clusterLifecycle.observeAdd(obj)
+	}
 	s.processor.distribute(addNotification{newObj: obj, isInInitialList: isInInitialList}, false)
 }
 
@@ -628,6 +1161,9 @@ func (s *sharedIndexInformer) OnUpdate(old, new interface{}) {
 func (s *sharedIndexInformer) OnDelete(old interface{}) {
 	// Invocation of this function is locked under s.blockDeltas, so it is
 	// safe to distribute the notification
+	if s.clusterLifecycle != nil {
+		s.clusterLifecycle.observeDelete(old)
+	}
 	s.processor.distribute(deleteNotification{oldObj: old}, false)
 }
 
@@ -639,6 +1175,10 @@ func (s *sharedIndexInformer) IsStopped() bool {
 }
 
 func (s *sharedIndexInformer) RemoveEventHandler(handle cache.ResourceEventHandlerRegistration) error {
+	// kcp modification: a listener blocking the delivery of notifications
+	// holds the locks below, see OverflowBlock.
+	s.processor.interruptListener(handle)
+
 	s.startedLock.Lock()
 	defer s.startedLock.Unlock()
 
@@ -665,6 +1205,11 @@ type sharedProcessor struct {
 	listeners map[*processorListener]bool
 	clock     clock.Clock
 	wg        wait.Group
+	// kcp modification: stopping is closed when the informer stops. It
+	// interrupts listeners blocked in add, which hold listenersLock, see
+	// OverflowBlock.
+	stopping     chan struct{}
+	stoppingOnce sync.Once
 }
 
 func (p *sharedProcessor) getListener(registration cache.ResourceEventHandlerRegistration) *processorListener {
@@ -693,6 +1238,7 @@ func (p *sharedProcessor) addListener(listener *processorListener) (cache.Resour
 	}
 
 	p.listeners[listener] = true
+	listener.processorStopping = p.stopping
 
 	if p.listenersStarted {
 		// Not starting listener.watchSynced!
@@ -720,6 +1266,10 @@ func (p *sharedProcessor) removeListener(handle cache.ResourceEventHandlerRegist
 	}
 
 	delete(p.listeners, listener)
+	listener.interrupt()
+	if listener.metrics != nil {
+		listener.metrics.removed.Inc()
+	}
 
 	if p.listenersStarted {
 		close(listener.addCh)
@@ -728,6 +1278,42 @@ func (p *sharedProcessor) removeListener(handle cache.ResourceEventHandlerRegist
 	return nil
 }
 
+// kcp modification: interrupt stops all listeners from blocking in add.
+func (p *sharedProcessor) interrupt() {
+	p.stoppingOnce.Do(func() {
+		close(p.stopping)
+	})
+}
+
+// kcp modification: interruptListener stops the listener of handle from
+// blocking in add, so that the locks held by the blocked delivery can be
+// taken to remove it. It must be called before removeListener, and before
+// the locks it is called under.
+func (p *sharedProcessor) interruptListener(handle cache.ResourceEventHandlerRegistration) {
+	if listener, ok := handle.(*processorListener); ok && listener.processorStopping == p.stopping {
+		listener.interrupt()
+	}
+}
+
+// kcp modification: distributeTo delivers obj to the listeners of
+// registrations only. Nothing is delivered before the listeners are started,
+// since they receive the initial list then.
+func (p *sharedProcessor) distributeTo(registrations []cache.ResourceEventHandlerRegistration, obj interface{}) {
+	p.listenersLock.RLock()
+	defer p.listenersLock.RUnlock()
+
+	if !p.listenersStarted {
+		return
+	}
+	for _, registration := range registrations {
+		if listener, ok := registration.(*processorListener); ok {
+			if _, exists := p.listeners[listener]; exists {
+				listener.add(obj)
+			}
+		}
+	}
+}
+
 func (p *sharedProcessor) distribute(obj interface{}, sync bool) {
 	p.listenersLock.RLock()
 	defer p.listenersLock.RUnlock()
@@ -777,6 +1363,10 @@ func (p *sharedProcessor) run(ctx context.Context) {
 	}()
 	<-ctx.Done()
 
+	// kcp modification: unblock the listeners blocked in add under
+	// listenersLock.
+	p.interrupt()
+
 	p.listenersLock.Lock()
 	defer p.listenersLock.Unlock()
 	for listener := range p.listeners {
@@ -851,6 +1441,13 @@ type processorListener struct {
 	addCh  chan interface{}
 	done   chan struct{}
 
+	// kcp modification: removed is closed once the listener is being
+	// removed, and processorStopping when its processor is stopping. They
+	// interrupt add while it blocks, see OverflowBlock.
+	removed           chan struct{}
+	removeOnce        sync.Once
+	processorStopping <-chan struct{}
+
 	handler     cache.ResourceEventHandler
 	handlerName string
 
@@ -867,6 +1464,25 @@ type processorListener struct {
 	// run() reads this to decide when to enable expensive time tracing.
 	pendingNotificationsLength atomic.Int64
 
+	// bufferLimit bounds the notifications queued for the handler, if
+	// MaxPendingNotifications is set.
+	bufferLimit *bufferLimit
+	// coalesced holds the latest pending notification by object key, if
+	// CoalesceNotifications is set. pendingNotifications then holds
+	// *coalescedNotifications. Only used by pop().
+	coalesced map[string]*coalescedNotification
+
+	// recoverPanics, onPanic and slowHandlerThreshold are the
+	// RecoverPanics, OnPanic and SlowHandlerThreshold handler options.
+	recoverPanics        bool
+	onPanic              func(err error)
+	slowHandlerThreshold time.Duration
+
+	// metrics are the metrics of the handler, if the informer has a
+	// HandlerMetricsProvider. Notifications are then queued as
+	// timedNotifications.
+	metrics *listenerMetrics
+
 	// requestedResyncPeriod is how frequently the listener wants a
 	// full resync from the shared informer, but modified by two
 	// adjustments.  One is imposing a lower bound,
@@ -909,6 +1525,7 @@ func newProcessListener(logger klog.Logger, handler cache.ResourceEventHandler,
 		nextCh:                make(chan interface{}),
 		addCh:                 make(chan interface{}),
 		done:                  make(chan struct{}),
+		removed:               make(chan struct{}),
 		upstreamHasSynced:     hasSynced,
 		handler:               handler,
 		handlerName:           handlerName,
@@ -923,10 +1540,32 @@ func newProcessListener(logger klog.Logger, handler cache.ResourceEventHandler,
 	return ret
 }
 
+// interrupt stops add from blocking, as p is being removed.
+func (p *processorListener) interrupt() {
+	p.removeOnce.Do(func() {
+		close(p.removed)
+	})
+}
+
 func (p *processorListener) add(notification interface{}) {
 	if a, ok := notification.(addNotification); ok && a.isInInitialList {
 		p.syncTracker.Start()
 	}
+	if p.metrics != nil {
+		notification = timedNotification{notification: notification, queued: time.Now()}
+	}
+	if p.bufferLimit != nil && p.bufferLimit.slots != nil {
+		// Blocks while the buffer is full, see OverflowBlock. The caller
+		// holds the processor's listenersLock, so the wait is interrupted
+		// when p is being removed or the processor is stopping.
+		select {
+		case p.bufferLimit.slots <- struct{}{}:
+		case <-p.removed:
+			return
+		case <-p.processorStopping:
+			return
+		}
+	}
 	p.addCh <- notification
 }
 
@@ -934,6 +1573,8 @@ func (p *processorListener) pop() {
 	defer utilruntime.HandleCrashWithLogger(p.logger)
 	defer close(p.nextCh) // Tell .run() to stop
 	defer close(p.done)   // Tell .watchSynced() to stop
+	// kcp modification: the notifications left are never delivered.
+	defer p.setReportedPending(0)
 
 	var nextCh chan<- interface{}
 	var notification interface{}
@@ -942,24 +1583,35 @@ func (p *processorListener) pop() {
 		case nextCh <- notification:
 			// Notification dispatched
 			var ok bool
-			notification, ok = p.pendingNotifications.ReadOne()
-			if ok {
-				p.pendingNotificationsLength.Add(-1)
-			} else { // Nothing to pop
+			notification, ok = p.readPending()
+			p.bufferDrained()
+			if !ok { // Nothing to pop
 				nextCh = nil // Disable this select case
 			}
+			p.reportPending(notification != nil)
 		case notificationToAdd, ok := <-p.addCh:
 			if !ok {
 				return
 			}
-			if notification == nil { // No notification to pop (and pendingNotifications is empty)
+			// kcp modification: a notification merged into a pending one
+			// takes no room in the buffer.
+			if notification != nil && p.bufferFull() && !p.merges(notificationToAdd) {
+				notification = p.overflow(notification)
+				if notification == nil {
+					nextCh = nil
+				}
+			}
+			switch {
+			case p.bufferLimit != nil && p.bufferLimit.disconnected:
+				p.discard(notificationToAdd)
+			case notification == nil: // No notification to pop (and pendingNotifications is empty)
 				// Optimize the case - skip adding to pendingNotifications
 				notification = notificationToAdd
 				nextCh = p.nextCh
-			} else { // There is already a notification waiting to be dispatched
-				p.pendingNotifications.WriteOne(notificationToAdd)
-				p.pendingNotificationsLength.Add(1)
+			default: // There is already a notification waiting to be dispatched
+				p.writePending(notificationToAdd)
 			}
+			p.reportPending(notification != nil)
 		}
 	}
 }
@@ -970,9 +1622,13 @@ func (p *processorListener) run() {
 	// the next notification will be attempted. This is usually better than the alternative of never
 	// delivering again.
 	//
-	// This only applies if utilruntime is configured to not panic, which is not the default.
+	// This only applies if utilruntime is configured to not panic, which is not the default,
+	// or if the handler was added with the RecoverPanics option.
 	sleepAfterCrash := false
 	for next := range p.nextCh {
+		if p.bufferLimit != nil && p.bufferLimit.slots != nil {
+			<-p.bufferLimit.slots
+		}
 		if sleepAfterCrash {
 			// Sleep before processing the next item.
 			time.Sleep(time.Second)
@@ -981,6 +1637,9 @@ func (p *processorListener) run() {
 			// Gets reset below, but only if we get that far.
 			sleepAfterCrash = true
 			defer utilruntime.HandleCrashWithLogger(p.logger)
+			if p.recoverPanics {
+				defer p.recoverPanic(next)
+			}
 			pendingNotifications := p.pendingNotificationsLength.Load()
 			if pendingNotifications > initialBufferSize {
 				trace := utiltrace.New("processorListener handler",
@@ -990,6 +1649,18 @@ func (p *processorListener) run() {
 				defer trace.LogIfLong(100 * time.Millisecond)
 			}
 
+			if timed, ok := next.(timedNotification); ok {
+				next = timed.notification
+				start := time.Now()
+				p.metrics.deliveryLatency.Observe(start.Sub(timed.queued).Seconds())
+				defer func() {
+					p.metrics.handlerDuration.Observe(time.Since(start).Seconds())
+				}()
+			}
+			if p.slowHandlerThreshold > 0 {
+				defer p.watchSlow(next)()
+			}
+
 			switch notification := next.(type) {
 			case updateNotification:
 				p.handler.OnUpdate(notification.oldObj, notification.newObj)
@@ -1045,6 +1716,22 @@ func (p *processorListener) setResyncPeriod(resyncPeriod time.Duration) {
 	p.resyncPeriod = resyncPeriod
 }
 
+// storeHas reports whether clientState holds obj, which may be a
+// cache.DeletedFinalStateUnknown.
+func storeHas(clientState cache.Store, keyFunc cache.KeyFunc, obj interface{}) (bool, error) {
+	var key string
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		key = tombstone.Key
+	} else {
+		var err error
+		if key, err = keyFunc(obj); err != nil {
+			return false, cache.KeyError{Obj: obj, Err: err}
+		}
+	}
+	_, exists, err := clientState.GetByKey(key)
+	return exists, err
+}
+
 // Multiplexes updates in the form of a list of Deltas into a Store, and informs
 // a given handler of events OnUpdate, OnAdd, OnDelete
 // taken from k8s.io/client-go/tools/cache/controller.go
@@ -1094,6 +1781,14 @@ func processDeltas(
 				handler.OnAdd(obj, isInInitialList)
 			}
 		case cache.Deleted:
+			// kcp modification: the deletion of an object that is not in
+			// the store anymore, e.g. because PurgeCluster removed it, was
+			// already sent to the handlers.
+			if exists, err := storeHas(clientState, keyFunc, obj); err != nil {
+				return err
+			} else if !exists {
+				continue
+			}
 			if err := clientState.Delete(obj); err != nil {
 				return err
 			}
@@ -1168,6 +1863,12 @@ func processDeltasInBatch(
 				})
 			}
 		case cache.Deleted:
+			// kcp modification: see processDeltas.
+			if exists, err := storeHas(clientState, keyFunc, obj); err != nil {
+				return err
+			} else if !exists {
+				continue
+			}
 			txn := cache.Transaction{
 				Type:   cache.TransactionTypeDelete,
 				Object: obj,
@@ -0,0 +1,89 @@
+/*
+Copyright 2026 The kcp Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package informers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/cache"
+	fcache "k8s.io/client-go/tools/cache/testing"
+
+	kcpcache "github.com/kcp-dev/apimachinery/v2/pkg/cache"
+)
+
+func TestPurgeCluster(t *testing.T) {
+	for name, options := range map[string]SharedIndexInformerOptions{
+		"indexer":         {},
+		"cluster indexer": {ClusterPartitionedStore: true},
+	} {
+		t.Run(name, func(t *testing.T) {
+			source := fcache.NewFakeControllerSource()
+			source.Add(newPod("c1", "a", ""))
+			source.Add(newPod("c2", "b", ""))
+			informer, _, _ := startInformerWithOptions(t, source, options)
+			handler := newRecordingHandler(false)
+			_, err := informer.AddEventHandler(handler)
+			require.NoError(t, err)
+			require.Eventually(t, func() bool {
+				return len(handler.recorded()) == 2
+			}, wait.ForeverTestTimeout, 10*time.Millisecond)
+
+			require.NoError(t, informer.PurgeCluster("c1"))
+			require.Equal(t, 0, clusterKeys(informer, "c1"))
+			require.Equal(t, 1, clusterKeys(informer, "c2"))
+
+			// The delete event of the purged object is not sent again.
+			source.Delete(newPod("c1", "a", ""))
+			source.Add(newPod("c1", "c", ""))
+			require.Eventually(t, func() bool {
+				return len(handler.recorded()) == 4
+			}, wait.ForeverTestTimeout, 10*time.Millisecond)
+			require.ElementsMatch(t, []string{"add a@1", "add b@2"}, handler.recorded()[:2])
+			require.Equal(t, []string{"delete a@1", "add c@4"}, handler.recorded()[2:])
+			require.Never(t, func() bool {
+				return len(handler.recorded()) > 4
+			}, 100*time.Millisecond, 10*time.Millisecond)
+		})
+	}
+}
+
+func TestAddIndexers(t *testing.T) {
+	informer := NewSharedIndexInformer(fcache.NewFakeControllerSource(), &corev1.Pod{}, 0, cache.Indexers{})
+
+	// The built-in cluster indexes can be added again.
+	require.NoError(t, informer.AddIndexers(cache.Indexers{
+		kcpcache.ClusterIndexName:             kcpcache.ClusterIndexFunc,
+		kcpcache.ClusterAndNamespaceIndexName: kcpcache.ClusterAndNamespaceIndexFunc,
+		"name": func(obj interface{}) ([]string, error) {
+			return []string{obj.(*corev1.Pod).Name}, nil
+		},
+	}))
+	require.Contains(t, informer.GetIndexer().GetIndexers(), "name")
+
+	// Different index funcs under their names are rejected.
+	require.EqualError(t, informer.AddIndexers(cache.Indexers{
+		kcpcache.ClusterIndexName: cache.MetaNamespaceIndexFunc,
+	}), "indexer conflict: "+kcpcache.ClusterIndexName+" is always registered, with a different index func")
+	require.Error(t, informer.AddIndexers(cache.Indexers{
+		kcpcache.ClusterAndNamespaceIndexName: kcpcache.ClusterIndexFunc,
+	}))
+}